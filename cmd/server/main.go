@@ -7,8 +7,11 @@ import (
 
 	"github.com/amoylab/solo-api/internal/config"
 	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/executor"
 	"github.com/amoylab/solo-api/internal/handler"
 	"github.com/amoylab/solo-api/internal/service"
+	"github.com/amoylab/solo-api/internal/store"
 	"github.com/amoylab/solo-api/pkg/logger"
 	"github.com/gin-gonic/gin"
 	"github.com/spf13/cobra"
@@ -79,14 +82,14 @@ func initConfig() *config.Config {
 }
 
 func initDatabase(cfg *config.Config, logger *zap.Logger) *database.Database {
-	db, err := database.NewDatabase(cfg.Database.DSN, logger)
+	db, err := database.NewDatabase(&cfg.Database, logger)
 	if err != nil {
 		logger.Fatal("Failed to initialize database", zap.Error(err))
 	}
 	return db
 }
 
-func setupRouter(taskHandler *handler.TaskHandler, projectHandler *handler.ProjectHandler, agentHandler *handler.AgentHandler, systemHandler *handler.SystemHandler, filesystemHandler *handler.FilesystemHandler, logger *zap.Logger) *gin.Engine {
+func setupRouter(taskHandler *handler.TaskHandler, runHandler *handler.RunHandler, reviewHandler *handler.ReviewHandler, commentHandler *handler.CommentHandler, activityHandler *handler.ActivityHandler, projectHandler *handler.ProjectHandler, workflowHandler *handler.WorkflowHandler, agentHandler *handler.AgentHandler, tagHandler *handler.TagHandler, trashHandler *handler.TrashHandler, searchHandler *handler.SearchHandler, eventHandler *handler.EventHandler, systemHandler *handler.SystemHandler, filesystemHandler *handler.FilesystemHandler, logger *zap.Logger) *gin.Engine {
 	// Set gin mode
 	gin.SetMode(gin.ReleaseMode)
 
@@ -121,10 +124,42 @@ func setupRouter(taskHandler *handler.TaskHandler, projectHandler *handler.Proje
 		tasks := api.Group("/tasks")
 		{
 			tasks.POST("", taskHandler.CreateTask)
+			tasks.POST("/bulk", taskHandler.BulkUpdateTasks)
 			tasks.GET("", taskHandler.GetTasks)
 			tasks.GET("/:id", taskHandler.GetTask)
 			tasks.PUT("/:id", taskHandler.UpdateTask)
+			tasks.PATCH("/:id", taskHandler.PatchTask)
 			tasks.DELETE("/:id", taskHandler.DeleteTask)
+			tasks.POST("/:id/move", taskHandler.MoveTask)
+			tasks.POST("/:id/archive", taskHandler.ArchiveTask)
+			tasks.POST("/:id/unarchive", taskHandler.UnarchiveTask)
+			tasks.GET("/:id/subtasks", taskHandler.GetSubtasks)
+			tasks.POST("/:id/subtasks", taskHandler.CreateSubtask)
+			tasks.GET("/:id/dependencies", taskHandler.GetDependencies)
+			tasks.POST("/:id/dependencies", taskHandler.AddDependency)
+			tasks.DELETE("/:id/dependencies/:dependsOnId", taskHandler.RemoveDependency)
+
+			tasks.POST("/:id/runs", runHandler.StartRun)
+			tasks.GET("/:id/runs", runHandler.GetRuns)
+			tasks.GET("/:id/runs/:runId", runHandler.GetRun)
+			tasks.POST("/:id/runs/:runId/cancel", runHandler.CancelRun)
+			tasks.GET("/:id/runs/:runId/log", runHandler.GetRunLog)
+			tasks.GET("/:id/runs/:runId/stream", runHandler.StreamRun)
+
+			tasks.GET("/:id/diff", reviewHandler.GetTaskDiff)
+			tasks.GET("/:id/diff/file", reviewHandler.GetTaskFileDiff)
+			tasks.POST("/:id/merge", reviewHandler.MergeTask)
+			tasks.POST("/:id/rebase", reviewHandler.RebaseTask)
+			tasks.POST("/:id/discard", reviewHandler.DiscardTask)
+
+			tasks.GET("/:id/comments", commentHandler.GetComments)
+			tasks.POST("/:id/comments", commentHandler.CreateComment)
+			tasks.GET("/:id/comments/:commentId", commentHandler.GetComment)
+			tasks.PUT("/:id/comments/:commentId", commentHandler.UpdateComment)
+			tasks.DELETE("/:id/comments/:commentId", commentHandler.DeleteComment)
+			tasks.GET("/:id/comments/:commentId/revisions", commentHandler.GetCommentRevisions)
+
+			tasks.GET("/:id/activity", activityHandler.GetTaskActivity)
 		}
 
 		projects := api.Group("/projects")
@@ -133,7 +168,14 @@ func setupRouter(taskHandler *handler.TaskHandler, projectHandler *handler.Proje
 			projects.GET("", projectHandler.GetProjects)
 			projects.GET("/:id", projectHandler.GetProject)
 			projects.PUT("/:id", projectHandler.UpdateProject)
+			projects.PATCH("/:id", projectHandler.PatchProject)
 			projects.DELETE("/:id", projectHandler.DeleteProject)
+			projects.POST("/:id/archive", projectHandler.ArchiveProject)
+			projects.POST("/:id/unarchive", projectHandler.UnarchiveProject)
+
+			projects.GET("/:id/workflow", workflowHandler.GetWorkflow)
+			projects.PUT("/:id/workflow", workflowHandler.UpdateWorkflow)
+			projects.DELETE("/:id/workflow", workflowHandler.ResetWorkflow)
 		}
 
 		agents := api.Group("/agents")
@@ -142,9 +184,31 @@ func setupRouter(taskHandler *handler.TaskHandler, projectHandler *handler.Proje
 			agents.GET("", agentHandler.GetAgents)
 			agents.GET("/:id", agentHandler.GetAgent)
 			agents.PUT("/:id", agentHandler.UpdateAgent)
+			agents.PATCH("/:id", agentHandler.PatchAgent)
 			agents.DELETE("/:id", agentHandler.DeleteAgent)
 		}
 
+		tags := api.Group("/tags")
+		{
+			tags.GET("", tagHandler.GetTags)
+			tags.POST("", tagHandler.CreateTag)
+			tags.POST("/gc", tagHandler.CollectGarbage)
+			tags.GET("/:id", tagHandler.GetTag)
+			tags.PUT("/:id", tagHandler.UpdateTag)
+			tags.DELETE("/:id", tagHandler.DeleteTag)
+			tags.POST("/:id/merge", tagHandler.MergeTag)
+		}
+
+		trash := api.Group("/trash")
+		{
+			trash.GET("", trashHandler.GetTrash)
+			trash.POST("/:id/restore", trashHandler.Restore)
+		}
+
+		api.GET("/search", searchHandler.Search)
+		api.GET("/activity", activityHandler.GetActivity)
+		api.GET("/events", eventHandler.StreamEvents)
+
 		system := api.Group("/system")
 		{
 			system.GET("/user-dirs", systemHandler.GetUserDirectoryInfo)
@@ -165,8 +229,9 @@ func setupRouter(taskHandler *handler.TaskHandler, projectHandler *handler.Proje
 func corsMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With")
+		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE, OPTIONS")
+		c.Header("Access-Control-Allow-Headers", "Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization, accept, origin, Cache-Control, X-Requested-With, X-Actor, If-Match, If-None-Match")
+		c.Header("Access-Control-Expose-Headers", "ETag")
 		c.Header("Access-Control-Allow-Credentials", "true")
 
 		if c.Request.Method == "OPTIONS" {
@@ -193,19 +258,48 @@ func run() {
 	defer db.Close()
 
 	// Initialize services
-	taskService := service.NewTaskService(db, logger)
-	projectService := service.NewProjectService(db, logger)
-	agentService := service.NewAgentService(db, logger)
+	stores := store.NewGormStore(db.DB)
+	bus := events.NewBus(logger)
+	worktreeService := service.NewWorktreeService(stores, &cfg.Git, logger)
+	workflowService := service.NewWorkflowService(stores, bus, logger)
+	taskService := service.NewTaskService(stores, worktreeService, workflowService, bus, logger)
+	projectService := service.NewProjectService(stores, taskService, bus, logger)
+	agentService := service.NewAgentService(stores, taskService, projectService, bus, logger)
+	tagService := service.NewTagService(stores, taskService, bus, logger)
+	searchService := service.NewSearchService(db, logger)
+	commentService := service.NewCommentService(db, bus, logger)
+	activityService := service.NewActivityService(db, logger)
+	reviewService := service.NewReviewService(stores, taskService, worktreeService, logger)
+	runService := service.NewRunService(db, executor.NewExecutor(logger), taskService, worktreeService, logger)
+	trashService := service.NewTrashService(stores, &cfg.Trash, taskService, projectService, bus, logger)
+
+	if err := runService.RecoverInterruptedRuns(); err != nil {
+		logger.Fatal("Failed to recover interrupted runs", zap.Error(err))
+	}
+	if err := taskService.AssignMissingPositions(); err != nil {
+		logger.Fatal("Failed to assign task positions", zap.Error(err))
+	}
+	trashService.StartPurging()
+	taskService.StartArchiving(&cfg.Archive)
 
 	// Initialize handlers
 	taskHandler := handler.NewTaskHandler(taskService, logger)
+	runHandler := handler.NewRunHandler(runService, logger)
+	reviewHandler := handler.NewReviewHandler(reviewService, logger)
+	commentHandler := handler.NewCommentHandler(commentService, logger)
+	activityHandler := handler.NewActivityHandler(activityService, logger)
 	projectHandler := handler.NewProjectHandler(projectService, logger)
+	workflowHandler := handler.NewWorkflowHandler(workflowService, logger)
 	agentHandler := handler.NewAgentHandler(agentService, logger)
+	tagHandler := handler.NewTagHandler(tagService, logger)
+	trashHandler := handler.NewTrashHandler(trashService, logger)
+	searchHandler := handler.NewSearchHandler(searchService, logger)
+	eventHandler := handler.NewEventHandler(bus, logger)
 	systemHandler := handler.NewSystemHandler(logger)
 	filesystemHandler := handler.NewFilesystemHandler(logger)
 
 	// Setup router
-	router := setupRouter(taskHandler, projectHandler, agentHandler, systemHandler, filesystemHandler, logger)
+	router := setupRouter(taskHandler, runHandler, reviewHandler, commentHandler, activityHandler, projectHandler, workflowHandler, agentHandler, tagHandler, trashHandler, searchHandler, eventHandler, systemHandler, filesystemHandler, logger)
 
 	// Start server
 	address := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
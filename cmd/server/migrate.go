@@ -0,0 +1,112 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+)
+
+var (
+	migrateCmd = &cobra.Command{
+		Use:   "migrate",
+		Short: "Manage database schema migrations",
+		// Errors are reported by main, and are no usage mistakes
+		PersistentPreRun: func(cmd *cobra.Command, args []string) {
+			cmd.SilenceUsage = true
+			cmd.SilenceErrors = true
+		},
+	}
+
+	migrateUpCmd = &cobra.Command{
+		Use:   "up",
+		Short: "Apply all pending migrations",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrationDatabase(func(db *database.Database) error {
+				applied, err := db.MigrateUp()
+				for _, migration := range applied {
+					fmt.Printf("Applied %04d_%s\n", migration.Version, migration.Name)
+				}
+				if err == nil && len(applied) == 0 {
+					fmt.Println("Schema is up to date")
+				}
+				return err
+			})
+		},
+	}
+
+	migrateDownCmd = &cobra.Command{
+		Use:   "down",
+		Short: "Roll back the latest applied migration",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrationDatabase(func(db *database.Database) error {
+				migration, err := db.MigrateDown()
+				if err != nil {
+					return err
+				}
+				if migration == nil {
+					fmt.Println("No migration to roll back")
+					return nil
+				}
+				fmt.Printf("Rolled back %04d_%s\n", migration.Version, migration.Name)
+				return nil
+			})
+		},
+	}
+
+	migrateStatusCmd = &cobra.Command{
+		Use:   "status",
+		Short: "List migrations and whether they are applied",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return withMigrationDatabase(func(db *database.Database) error {
+				status, err := db.MigrationStatus()
+				if err != nil {
+					return err
+				}
+
+				w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+				fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED")
+				for _, migration := range status {
+					applied := "pending"
+					if migration.AppliedAt != nil {
+						applied = migration.AppliedAt.Local().Format("2006-01-02 15:04:05")
+					}
+					if migration.Unknown {
+						applied += " (unknown to this version)"
+					}
+					fmt.Fprintf(w, "%04d\t%s\t%s\n", migration.Version, migration.Name, applied)
+				}
+				return w.Flush()
+			})
+		},
+	}
+)
+
+func init() {
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+	rootCmd.AddCommand(migrateCmd)
+}
+
+// withMigrationDatabase opens the configured database without migrating it
+// on the way, unlike the server.
+func withMigrationDatabase(fn func(db *database.Database) error) error {
+	cfg := initConfig()
+	logger := initLogger(cfg)
+	defer logger.Sync()
+
+	db, err := database.Open(&cfg.Database, logger)
+	if err != nil {
+		logger.Error("Failed to open database", zap.Error(err))
+		return err
+	}
+	defer db.Close()
+
+	return fn(db)
+}
@@ -4,6 +4,7 @@ import (
 	"os"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
 	"gopkg.in/yaml.v3"
@@ -13,6 +14,9 @@ type Config struct {
 	Server   ServerConfig   `yaml:"server"`
 	Database DatabaseConfig `yaml:"database"`
 	Logger   LoggerConfig   `yaml:"logger"`
+	Git      GitConfig      `yaml:"git"`
+	Trash    TrashConfig    `yaml:"trash"`
+	Archive  ArchiveConfig  `yaml:"archive"`
 }
 
 type ServerConfig struct {
@@ -21,9 +25,26 @@ type ServerConfig struct {
 }
 
 type DatabaseConfig struct {
-	Type   string `yaml:"type"`
-	DSN    string `yaml:"dsn"`
-	DbName string `yaml:"db_name"`
+	Type            string        `yaml:"type"`              // sqlite, postgres or mysql
+	DSN             string        `yaml:"dsn"`               // File path for sqlite, connection string otherwise
+	DbName          string        `yaml:"db_name"`           // Used when a postgres or mysql DSN names no database
+	MaxOpenConns    int           `yaml:"max_open_conns"`    // 0 for no limit
+	MaxIdleConns    int           `yaml:"max_idle_conns"`    // 0 keeps the driver default
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime"` // 0 keeps connections open
+	ManualMigrate   bool          `yaml:"manual_migrate"`    // Leave pending migrations to "server migrate up"
+}
+
+type GitConfig struct {
+	WorktreeRoot string `yaml:"worktree_root"`
+	BranchPrefix string `yaml:"branch_prefix"`
+}
+
+type TrashConfig struct {
+	RetentionDays int `yaml:"retention_days"` // Days before trashed tasks and projects are purged; 0 keeps them
+}
+
+type ArchiveConfig struct {
+	AfterDays int `yaml:"after_days"` // Days after a task is finished before it is archived; 0 disables
 }
 
 type LoggerConfig struct {
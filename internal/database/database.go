@@ -3,9 +3,10 @@ package database
 import (
 	"time"
 
-	"github.com/glebarez/sqlite"
 	"go.uber.org/zap"
 	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/config"
 )
 
 type Database struct {
@@ -13,21 +14,46 @@ type Database struct {
 	logger *zap.Logger
 }
 
-func NewDatabase(dsn string, logger *zap.Logger) (*Database, error) {
-	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{})
+// NewDatabase connects to the configured database and brings its schema up
+// to date. Only SQLite has a full-text search index; see SearchService for
+// how the other databases are searched.
+func NewDatabase(cfg *config.DatabaseConfig, logger *zap.Logger) (*Database, error) {
+	d, err := Open(cfg, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := d.prepareSchema(cfg.ManualMigrate); err != nil {
+		d.Close()
+		return nil, err
+	}
+
+	logger.Info("Database ready", zap.String("type", d.Type()))
+	return d, nil
+}
+
+// Open connects to the configured database without touching its schema,
+// for running migrations.
+func Open(cfg *config.DatabaseConfig, logger *zap.Logger) (*Database, error) {
+	dialector, err := openDialector(cfg)
 	if err != nil {
 		return nil, err
 	}
 
-	// Auto-migrate the schema
-	if err := db.AutoMigrate(&Agent{}, &Task{}, &Project{}, &Tag{}, &TaskTag{}); err != nil {
+	db, err := gorm.Open(dialector, &gorm.Config{})
+	if err != nil {
 		return nil, err
 	}
 
-	return &Database{
+	d := &Database{
 		DB:     db,
 		logger: logger,
-	}, nil
+	}
+	if err := configurePool(db, cfg); err != nil {
+		d.Close()
+		return nil, err
+	}
+	return d, nil
 }
 
 func (d *Database) Close() error {
@@ -42,38 +68,65 @@ func (d *Database) GetDB() *gorm.DB {
 	return d.DB
 }
 
+// Type returns the kind of database in use: TypeSQLite, TypePostgres or
+// TypeMySQL.
+func (d *Database) Type() string {
+	return d.DB.Dialector.Name()
+}
+
 type Agent struct {
 	ID          string    `gorm:"primaryKey" json:"id"`
 	Name        string    `gorm:"not null;unique" json:"name"`
 	Type        string    `gorm:"not null" json:"type"`
 	Description string    `json:"description"`
+	Command     string    `json:"command"` // Optional CLI override, defaults are derived from Type
+	Version     int64     `gorm:"not null;default:1" json:"version"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
 
 type Task struct {
-	ID          string    `gorm:"primaryKey" json:"id"`
-	Title       string    `gorm:"not null" json:"title"`
-	Description string    `json:"description"`
-	Status      string    `gorm:"not null;default:'todo'" json:"status"`
-	Assignee    string    `json:"assignee"`
-	AgentID     *string   `json:"agent_id"` // Foreign key to agents table
-	Agent       *Agent    `gorm:"foreignKey:AgentID" json:"agent"`
-	ProjectID   string    `json:"project_id"` // Foreign key to projects table
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
-	TaskTags    []TaskTag `gorm:"foreignKey:TaskID" json:"-"`
+	ID           string         `gorm:"primaryKey" json:"id"`
+	Title        string         `gorm:"not null" json:"title"`
+	Description  string         `json:"description"`
+	Status       string         `gorm:"not null;default:'todo'" json:"status"`
+	Priority     string         `gorm:"not null;default:'medium'" json:"priority"`
+	DueDate      *time.Time     `json:"due_date"`
+	Assignee     string         `json:"assignee"`
+	AgentID      *string        `gorm:"index" json:"agent_id"` // Foreign key to agents table
+	Agent        *Agent         `gorm:"foreignKey:AgentID;constraint:OnDelete:SET NULL" json:"agent"`
+	ProjectID    string         `gorm:"index" json:"project_id"` // Projects table, empty for none; enforced by triggers
+	ParentID     *string        `gorm:"index" json:"parent_id"`  // Parent task when this is a subtask
+	Parent       *Task          `gorm:"foreignKey:ParentID;constraint:OnDelete:CASCADE" json:"-"`
+	Position     string         `gorm:"index" json:"position"`             // Rank within the task's board column, compared as text
+	Branch       string         `json:"branch"`                            // Task branch used for agent work
+	BaseBranch   string         `json:"base_branch"`                       // Branch the task branch was created from
+	WorktreePath string         `json:"worktree_path"`                     // Empty when no worktree is checked out
+	Version      int64          `gorm:"not null;default:1" json:"version"` // Incremented by every change
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+	CompletedAt  *time.Time     `gorm:"-:migration" json:"completed_at"` // When the task last entered a final status; added by migration 0004
+	ArchivedAt   *time.Time     `gorm:"index" json:"archived_at"`        // Set while the task is archived, which hides it from lists
+	DeletedAt    gorm.DeletedAt `gorm:"index" json:"deleted_at"`         // Set while the task is in the trash
+	TrashBatch   string         `gorm:"-:migration" json:"-"`            // Shared by everything trashed by one delete; added by migration 0005
+	TaskTags     []TaskTag      `gorm:"foreignKey:TaskID" json:"-"`
 }
 
 type Project struct {
-	ID          string    `gorm:"primaryKey" json:"id"`
-	Name        string    `gorm:"not null" json:"name"`
-	Description string    `json:"description"`
-	Directory   string    `gorm:"not null" json:"directory"`
-	AgentID     *string   `json:"agent_id"` // Foreign key to agents table
-	Agent       *Agent    `gorm:"foreignKey:AgentID" json:"agent"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string         `gorm:"primaryKey" json:"id"`
+	Name        string         `gorm:"not null" json:"name"`
+	Description string         `json:"description"`
+	Directory   string         `gorm:"not null" json:"directory"`
+	BaseBranch  string         `json:"base_branch"`               // Defaults to the branch checked out in Directory
+	Workflow    string         `gorm:"type:text" json:"workflow"` // JSON encoded model.Workflow, empty for the default
+	AgentID     *string        `gorm:"index" json:"agent_id"`     // Foreign key to agents table
+	Agent       *Agent         `gorm:"foreignKey:AgentID;constraint:OnDelete:SET NULL" json:"agent"`
+	Version     int64          `gorm:"not null;default:1" json:"version"`
+	CreatedAt   time.Time      `json:"created_at"`
+	UpdatedAt   time.Time      `json:"updated_at"`
+	ArchivedAt  *time.Time     `gorm:"index" json:"archived_at"` // Set while the project is archived, which hides it from lists
+	DeletedAt   gorm.DeletedAt `gorm:"index" json:"deleted_at"`  // Set while the project is in the trash
+	TrashBatch  string         `gorm:"-:migration" json:"-"`     // Shared by everything trashed by one delete; added by migration 0005
 }
 
 type Tag struct {
@@ -87,6 +140,66 @@ type Tag struct {
 type TaskTag struct {
 	TaskID string `gorm:"primaryKey" json:"task_id"`
 	TagID  string `gorm:"primaryKey" json:"tag_id"`
-	Task   Task   `gorm:"foreignKey:TaskID" json:"-"`
-	Tag    Tag    `gorm:"foreignKey:TagID" json:"-"`
+	Task   Task   `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE" json:"-"`
+	Tag    Tag    `gorm:"foreignKey:TagID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// TaskDependency records that a task cannot start before another task is finished.
+type TaskDependency struct {
+	TaskID      string    `gorm:"primaryKey" json:"task_id"`
+	DependsOnID string    `gorm:"primaryKey;index" json:"depends_on_id"`
+	CreatedAt   time.Time `json:"created_at"`
+	Task        *Task     `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE" json:"-"`
+	DependsOn   *Task     `gorm:"foreignKey:DependsOnID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Comment is a markdown note on a task, written by a person or an agent.
+type Comment struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	TaskID     string     `gorm:"not null;index" json:"task_id"`
+	AuthorType string     `gorm:"not null;default:'user'" json:"author_type"` // "user" or "agent"
+	AuthorID   string     `json:"author_id"`                                  // User name, or the agent ID for agent comments
+	Body       string     `gorm:"type:text;not null" json:"body"`
+	EditedAt   *time.Time `json:"edited_at"` // Set when the body was last changed
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Task       *Task      `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// CommentRevision keeps a previous body of an edited comment.
+type CommentRevision struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	CommentID string    `gorm:"not null;index" json:"comment_id"`
+	Body      string    `gorm:"type:text;not null" json:"body"`
+	CreatedAt time.Time `json:"created_at"` // When the body was replaced
+	Comment   *Comment  `gorm:"foreignKey:CommentID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+type Run struct {
+	ID         string     `gorm:"primaryKey" json:"id"`
+	TaskID     string     `gorm:"not null;index" json:"task_id"`
+	AgentID    string     `gorm:"not null" json:"agent_id"`
+	Status     string     `gorm:"not null;default:'running'" json:"status"`
+	Command    string     `json:"command"`
+	WorkingDir string     `json:"working_dir"`
+	ExitCode   *int       `json:"exit_code"`
+	Error      string     `json:"error"`
+	Log        string     `gorm:"type:text" json:"log"` // JSON-encoded output lines, written when the run ends
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+	Task       *Task      `gorm:"foreignKey:TaskID;constraint:OnDelete:CASCADE" json:"-"`
+}
+
+// Activity is an append-only record of a change to a task, project or agent.
+type Activity struct {
+	ID         int64     `gorm:"primaryKey;autoIncrement" json:"id"`
+	EntityType string    `gorm:"not null;index:idx_activity_entity" json:"entity_type"`
+	EntityID   string    `gorm:"not null;index:idx_activity_entity" json:"entity_id"`
+	ProjectID  string    `gorm:"index" json:"project_id"`
+	Action     string    `gorm:"not null" json:"action"` // created, updated or deleted
+	Actor      string    `gorm:"not null" json:"actor"`
+	Changes    string    `gorm:"type:text" json:"changes"` // JSON encoded []model.FieldChange
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
 }
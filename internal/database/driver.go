@@ -0,0 +1,95 @@
+package database
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+
+	"github.com/glebarez/sqlite"
+	mysqldriver "github.com/go-sql-driver/mysql"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/config"
+)
+
+// Database types, as set by database.type and reported by Database.Type.
+const (
+	TypeSQLite   = "sqlite"
+	TypePostgres = "postgres"
+	TypeMySQL    = "mysql"
+)
+
+// openDialector picks the driver for the configured database type. SQLite
+// is the default.
+func openDialector(cfg *config.DatabaseConfig) (gorm.Dialector, error) {
+	switch cfg.Type {
+	case "", TypeSQLite:
+		return sqlite.Open(withForeignKeys(cfg.DSN)), nil
+	case TypePostgres:
+		return postgres.Open(postgresDSN(cfg.DSN, cfg.DbName)), nil
+	case TypeMySQL:
+		dsn, err := mysqlDSN(cfg.DSN, cfg.DbName)
+		if err != nil {
+			return nil, err
+		}
+		return mysql.Open(dsn), nil
+	}
+	return nil, fmt.Errorf("unsupported database type %q, use %s, %s or %s", cfg.Type, TypeSQLite, TypePostgres, TypeMySQL)
+}
+
+// postgresDSN names the database in a URL or key/value DSN that names none.
+func postgresDSN(dsn, name string) string {
+	if name == "" {
+		return dsn
+	}
+
+	if strings.HasPrefix(dsn, "postgres://") || strings.HasPrefix(dsn, "postgresql://") {
+		u, err := url.Parse(dsn)
+		if err != nil || strings.Trim(u.Path, "/") != "" {
+			return dsn
+		}
+		u.Path = "/" + name
+		return u.String()
+	}
+
+	if strings.Contains(dsn, "dbname=") {
+		return dsn
+	}
+	return strings.TrimSpace(dsn + " dbname=" + name)
+}
+
+// mysqlDSN names the database in a DSN that names none and makes the driver
+// scan timestamps into time.Time, which GORM relies on.
+func mysqlDSN(dsn, name string) (string, error) {
+	parsed, err := mysqldriver.ParseDSN(dsn)
+	if err != nil {
+		return "", err
+	}
+	if parsed.DBName == "" {
+		parsed.DBName = name
+	}
+	parsed.ParseTime = true
+	return parsed.FormatDSN(), nil
+}
+
+// configurePool applies the connection pool settings. Zero values keep the
+// defaults of database/sql.
+func configurePool(db *gorm.DB, cfg *config.DatabaseConfig) error {
+	sqlDB, err := db.DB()
+	if err != nil {
+		return err
+	}
+
+	if cfg.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(cfg.MaxOpenConns)
+	}
+	if cfg.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(cfg.MaxIdleConns)
+	}
+	if cfg.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(cfg.ConnMaxLifetime)
+	}
+	return nil
+}
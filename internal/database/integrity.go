@@ -0,0 +1,121 @@
+package database
+
+import (
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Rows left dangling by versions of Solo that did not enforce references.
+// References that may be empty are cleared, rows that cannot exist on their
+// own are deleted.
+var repairStatements = []string{
+	`UPDATE tasks SET agent_id = NULL WHERE agent_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM agents WHERE agents.id = tasks.agent_id)`,
+	`UPDATE projects SET agent_id = NULL WHERE agent_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM agents WHERE agents.id = projects.agent_id)`,
+	`UPDATE tasks SET parent_id = NULL WHERE parent_id IS NOT NULL AND NOT EXISTS (SELECT 1 FROM tasks parents WHERE parents.id = tasks.parent_id)`,
+	`UPDATE tasks SET project_id = '' WHERE project_id <> '' AND NOT EXISTS (SELECT 1 FROM projects WHERE projects.id = tasks.project_id)`,
+	`DELETE FROM task_tags WHERE NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.id = task_tags.task_id)
+		OR NOT EXISTS (SELECT 1 FROM tags WHERE tags.id = task_tags.tag_id)`,
+	`DELETE FROM task_dependencies WHERE NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.id = task_dependencies.task_id)
+		OR NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.id = task_dependencies.depends_on_id)`,
+	`DELETE FROM comments WHERE NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.id = comments.task_id)`,
+	`DELETE FROM comment_revisions WHERE NOT EXISTS (SELECT 1 FROM comments WHERE comments.id = comment_revisions.comment_id)`,
+	`DELETE FROM runs WHERE NOT EXISTS (SELECT 1 FROM tasks WHERE tasks.id = runs.task_id)`,
+}
+
+// models lists every table of the schema before migrations were versioned.
+var models = []interface{}{&Agent{}, &Task{}, &Project{}, &Tag{}, &TaskTag{}, &TaskDependency{}, &Comment{}, &CommentRevision{}, &Run{}, &Activity{}}
+
+// withForeignKeys makes every connection of the pool enforce foreign keys,
+// which SQLite leaves off by default.
+func withForeignKeys(dsn string) string {
+	separator := "?"
+	if strings.Contains(dsn, "?") {
+		separator = "&"
+	}
+	return dsn + separator + "_pragma=foreign_keys(1)"
+}
+
+// checkLegacySchema checks a database created before migrations were
+// versioned has every table, column and foreign key of the first migration,
+// the schema AutoMigrate left it with on every startup until then. The
+// schema is not changed: AutoMigrate would alter it to the current models,
+// so an older database is refused instead.
+func checkLegacySchema(db *gorm.DB) error {
+	migrator := db.Migrator()
+	for _, model := range models {
+		stmt := &gorm.Statement{DB: db}
+		if err := stmt.Parse(model); err != nil {
+			return err
+		}
+		table := stmt.Schema.Table
+		if !migrator.HasTable(table) {
+			return fmt.Errorf("%w: table %s is missing", ErrLegacySchema, table)
+		}
+		for _, field := range stmt.Schema.Fields {
+			if field.DBName != "" && !field.IgnoreMigration && !migrator.HasColumn(model, field.DBName) {
+				return fmt.Errorf("%w: column %s.%s is missing", ErrLegacySchema, table, field.DBName)
+			}
+		}
+		for _, rel := range stmt.Schema.Relationships.Relations {
+			constraint := rel.ParseConstraint()
+			if constraint != nil && constraint.Schema == stmt.Schema && !migrator.HasConstraint(model, constraint.Name) {
+				return fmt.Errorf("%w: foreign key %s is missing", ErrLegacySchema, constraint.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// repairLegacyReferences clears or deletes the rows a database created
+// before migrations were versioned may have left dangling, and reports any
+// violation left. Only SQLite ran without enforcing references, so there is
+// nothing to repair on the other databases. It runs on a single connection
+// with foreign keys off, as the repairs must not cascade.
+func repairLegacyReferences(db *gorm.DB, logger *zap.Logger) error {
+	if db.Dialector.Name() != TypeSQLite {
+		return nil
+	}
+
+	return db.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		var repaired int64
+		if err := conn.Transaction(func(tx *gorm.DB) error {
+			for _, statement := range repairStatements {
+				result := tx.Exec(statement)
+				if result.Error != nil {
+					return result.Error
+				}
+				repaired += result.RowsAffected
+			}
+			return nil
+		}); err != nil {
+			return err
+		}
+		if repaired > 0 {
+			logger.Warn("Repaired dangling references", zap.Int64("rows", repaired))
+		}
+
+		var violations []struct {
+			Table  string
+			Rowid  int64
+			Parent string
+		}
+		if err := conn.Raw("PRAGMA foreign_key_check").Scan(&violations).Error; err != nil {
+			return err
+		}
+		for _, violation := range violations {
+			logger.Warn("Foreign key violation",
+				zap.String("table", violation.Table),
+				zap.Int64("rowid", violation.Rowid),
+				zap.String("parent", violation.Parent))
+		}
+		return nil
+	})
+}
@@ -0,0 +1,398 @@
+package database
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+)
+
+// Migrations live in migrations/<database type>/ as pairs of
+// NNNN_name.up.sql and NNNN_name.down.sql files. Every database type has
+// the same versions. Statements end with a semicolon at the end of a line;
+// statements with semicolons of their own, like triggers, are enclosed in
+// "-- +begin" and "-- +end" lines instead.
+//
+//go:embed migrations
+var migrationFiles embed.FS
+
+var (
+	migrationFilePattern = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+	statementEnd         = regexp.MustCompile(`;[ \t]*\r?\n`)
+)
+
+const (
+	blockBegin = "-- +begin"
+	blockEnd   = "-- +end"
+)
+
+var (
+	ErrSchemaTooNew      = errors.New("database schema is newer than this version of Solo")
+	ErrPendingMigrations = errors.New("database schema is out of date")
+	ErrLegacySchema      = errors.New("database predates the first migration")
+)
+
+// Migration is a numbered change to the schema together with its rollback.
+type Migration struct {
+	Version int
+	Name    string
+	up      []string
+	down    []string
+}
+
+// SchemaMigration records a migration applied to the database.
+type SchemaMigration struct {
+	Version   int       `gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `gorm:"not null"`
+	AppliedAt time.Time `gorm:"not null"`
+}
+
+// MigrationStatus tells whether a migration has been applied. Migrations
+// applied by a newer version of Solo are listed as unknown.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+	Unknown   bool
+}
+
+// loadMigrations reads the migrations of the database type, oldest first.
+func loadMigrations(dbType string) ([]Migration, error) {
+	dir := path.Join("migrations", dbType)
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("no migrations for database type %s: %w", dbType, err)
+	}
+
+	byVersion := map[int]*Migration{}
+	for _, entry := range entries {
+		match := migrationFilePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("unexpected migration file %s", path.Join(dir, entry.Name()))
+		}
+		version, _ := strconv.Atoi(match[1])
+		content, err := migrationFiles.ReadFile(path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration := byVersion[version]
+		if migration == nil {
+			migration = &Migration{Version: version, Name: match[2]}
+			byVersion[version] = migration
+		}
+		if migration.Name != match[2] {
+			return nil, fmt.Errorf("migration %d has two names, %s and %s", version, migration.Name, match[2])
+		}
+		if match[3] == "up" {
+			migration.up = splitStatements(string(content))
+		} else {
+			migration.down = splitStatements(string(content))
+		}
+	}
+
+	migrations := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.up == nil || migration.down == nil {
+			return nil, fmt.Errorf("migration %d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		migrations = append(migrations, *migration)
+	}
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].Version < migrations[j].Version
+	})
+	return migrations, nil
+}
+
+// splitStatements splits a migration file into its statements, leaving out
+// comment lines. A block is a single statement; its final semicolon is
+// dropped.
+func splitStatements(content string) []string {
+	statements := []string{}
+	var lines []string
+	inBlock := false
+	flush := func() {
+		text := strings.Join(lines, "\n") + "\n"
+		lines = nil
+		if inBlock {
+			if statement := strings.TrimSuffix(strings.TrimSpace(text), ";"); statement != "" {
+				statements = append(statements, statement)
+			}
+			return
+		}
+		for _, statement := range statementEnd.Split(text, -1) {
+			if statement = strings.TrimSpace(statement); statement != "" {
+				statements = append(statements, statement)
+			}
+		}
+	}
+
+	for _, line := range strings.Split(content, "\n") {
+		switch trimmed := strings.TrimSpace(line); {
+		case trimmed == blockBegin || trimmed == blockEnd:
+			flush()
+			inBlock = trimmed == blockBegin
+		case strings.HasPrefix(trimmed, "--"):
+		default:
+			lines = append(lines, line)
+		}
+	}
+	flush()
+	return statements
+}
+
+// prepareSchema applies the pending migrations, or with manual set refuses
+// to go on while there are any. It always refuses a schema migrated by a
+// newer version of Solo.
+func (d *Database) prepareSchema(manual bool) error {
+	if err := d.adoptLegacySchema(); err != nil {
+		return err
+	}
+
+	pending, err := d.pendingMigrations()
+	if err != nil {
+		return err
+	}
+	if len(pending) == 0 {
+		return nil
+	}
+	if manual {
+		return fmt.Errorf("%w: %d migrations pending, run \"server migrate up\"", ErrPendingMigrations, len(pending))
+	}
+	_, err = d.MigrateUp()
+	return err
+}
+
+// MigrateUp applies the pending migrations, oldest first, and returns them.
+func (d *Database) MigrateUp() ([]Migration, error) {
+	if err := d.adoptLegacySchema(); err != nil {
+		return nil, err
+	}
+
+	pending, err := d.pendingMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	for i, migration := range pending {
+		if err := d.runMigration(migration.up, func(tx *gorm.DB) error {
+			return tx.Create(&SchemaMigration{Version: migration.Version, Name: migration.Name, AppliedAt: time.Now()}).Error
+		}); err != nil {
+			return pending[:i], fmt.Errorf("migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		d.logger.Info("Applied migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+	}
+	return pending, nil
+}
+
+// MigrateDown rolls back the latest applied migration and returns it, or
+// nil when no migration is applied.
+func (d *Database) MigrateDown() (*Migration, error) {
+	if err := d.adoptLegacySchema(); err != nil {
+		return nil, err
+	}
+
+	migrations, applied, err := d.migrationState()
+	if err != nil {
+		return nil, err
+	}
+	if len(applied) == 0 {
+		return nil, nil
+	}
+
+	latest := applied[len(applied)-1].Version
+	for _, migration := range migrations {
+		if migration.Version != latest {
+			continue
+		}
+		if err := d.runMigration(migration.down, func(tx *gorm.DB) error {
+			return tx.Delete(&SchemaMigration{}, "version = ?", migration.Version).Error
+		}); err != nil {
+			return nil, fmt.Errorf("rolling back migration %d_%s failed: %w", migration.Version, migration.Name, err)
+		}
+		d.logger.Info("Rolled back migration", zap.Int("version", migration.Version), zap.String("name", migration.Name))
+		return &migration, nil
+	}
+	return nil, fmt.Errorf("%w: migration %d is unknown", ErrSchemaTooNew, latest)
+}
+
+// MigrationStatus lists the known migrations, followed by applied ones this
+// version of Solo does not know.
+func (d *Database) MigrationStatus() ([]MigrationStatus, error) {
+	migrations, err := loadMigrations(d.Type())
+	if err != nil {
+		return nil, err
+	}
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	appliedAt := map[int]time.Time{}
+	for _, record := range applied {
+		appliedAt[record.Version] = record.AppliedAt
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	known := map[int]bool{}
+	for _, migration := range migrations {
+		known[migration.Version] = true
+		entry := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if at, ok := appliedAt[migration.Version]; ok {
+			entry.AppliedAt = &at
+		}
+		status = append(status, entry)
+	}
+	for _, record := range applied {
+		if !known[record.Version] {
+			at := record.AppliedAt
+			status = append(status, MigrationStatus{Version: record.Version, Name: record.Name, AppliedAt: &at, Unknown: true})
+		}
+	}
+	return status, nil
+}
+
+// pendingMigrations returns the migrations not applied yet, oldest first.
+func (d *Database) pendingMigrations() ([]Migration, error) {
+	migrations, applied, err := d.migrationState()
+	if err != nil {
+		return nil, err
+	}
+
+	done := map[int]bool{}
+	for _, record := range applied {
+		done[record.Version] = true
+	}
+	var pending []Migration
+	for _, migration := range migrations {
+		if !done[migration.Version] {
+			pending = append(pending, migration)
+		}
+	}
+	return pending, nil
+}
+
+// migrationState returns the known and the applied migrations, failing
+// when the database was migrated beyond what this version of Solo knows.
+func (d *Database) migrationState() ([]Migration, []SchemaMigration, error) {
+	migrations, err := loadMigrations(d.Type())
+	if err != nil {
+		return nil, nil, err
+	}
+	applied, err := d.appliedMigrations()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	latest := 0
+	if len(migrations) > 0 {
+		latest = migrations[len(migrations)-1].Version
+	}
+	if len(applied) > 0 && applied[len(applied)-1].Version > latest {
+		return nil, nil, fmt.Errorf("%w: it is at version %d, this version knows up to %d",
+			ErrSchemaTooNew, applied[len(applied)-1].Version, latest)
+	}
+	return migrations, applied, nil
+}
+
+// appliedMigrations returns the applied migrations, oldest first.
+func (d *Database) appliedMigrations() ([]SchemaMigration, error) {
+	if !d.DB.Migrator().HasTable(&SchemaMigration{}) {
+		return nil, nil
+	}
+	var applied []SchemaMigration
+	if err := d.DB.Order("version").Find(&applied).Error; err != nil {
+		return nil, err
+	}
+	return applied, nil
+}
+
+// adoptLegacySchema creates the table recording applied migrations. A
+// database created before migrations were versioned is recorded as having
+// the first migration applied once its schema is checked to match it, with
+// only dangling references repaired.
+func (d *Database) adoptLegacySchema() error {
+	migrator := d.DB.Migrator()
+	if migrator.HasTable(&SchemaMigration{}) {
+		return nil
+	}
+
+	legacy := migrator.HasTable(&Task{})
+	if legacy {
+		d.logger.Info("Adopting database created before versioned migrations")
+		if err := checkLegacySchema(d.DB); err != nil {
+			return err
+		}
+		if err := repairLegacyReferences(d.DB, d.logger); err != nil {
+			return err
+		}
+	}
+
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().CreateTable(&SchemaMigration{}); err != nil {
+			return err
+		}
+		if !legacy {
+			return nil
+		}
+		migrations, err := loadMigrations(d.Type())
+		if err != nil || len(migrations) == 0 {
+			return err
+		}
+		return tx.Create(&SchemaMigration{Version: migrations[0].Version, Name: migrations[0].Name, AppliedAt: time.Now()}).Error
+	})
+}
+
+// runMigration runs the statements of a migration and records it in one
+// transaction. MySQL commits schema changes right away, so a migration
+// failing there may be left half applied.
+//
+// SQLite changes most columns by rebuilding the table, which must happen
+// with foreign keys off so dropping the old table does not cascade. They
+// are turned off on a single connection for the migration, and the
+// references are checked before it commits.
+func (d *Database) runMigration(statements []string, record func(tx *gorm.DB) error) error {
+	apply := func(tx *gorm.DB) error {
+		for _, statement := range statements {
+			if err := tx.Exec(statement).Error; err != nil {
+				return err
+			}
+		}
+		return record(tx)
+	}
+
+	if d.Type() != TypeSQLite {
+		return d.DB.Transaction(apply)
+	}
+
+	return d.DB.Connection(func(conn *gorm.DB) error {
+		if err := conn.Exec("PRAGMA foreign_keys = OFF").Error; err != nil {
+			return err
+		}
+		defer conn.Exec("PRAGMA foreign_keys = ON")
+
+		return conn.Transaction(func(tx *gorm.DB) error {
+			if err := apply(tx); err != nil {
+				return err
+			}
+			var violations []struct {
+				Table string
+			}
+			if err := tx.Raw("PRAGMA foreign_key_check").Scan(&violations).Error; err != nil {
+				return err
+			}
+			if len(violations) > 0 {
+				return fmt.Errorf("migration leaves %d rows of %s with dangling references", len(violations), violations[0].Table)
+			}
+			return nil
+		})
+	})
+}
@@ -0,0 +1,137 @@
+package database
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/config"
+)
+
+func TestSplitStatements(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    []string
+	}{
+		{
+			name:    "statements end at a semicolon ending a line",
+			content: "-- comment\nCREATE TABLE a (\n\tid text\n);\nDROP TABLE b;  \n",
+			want:    []string{"CREATE TABLE a (\n\tid text\n)", "DROP TABLE b"},
+		},
+		{
+			name:    "blocks are single statements",
+			content: "DROP TRIGGER IF EXISTS t;\n\n-- +begin\nCREATE TRIGGER t BEGIN\n\tSELECT 1;\n\tSELECT 2;\nEND;\n-- +end\n\nDROP TABLE c;\n",
+			want:    []string{"DROP TRIGGER IF EXISTS t", "CREATE TRIGGER t BEGIN\n\tSELECT 1;\n\tSELECT 2;\nEND", "DROP TABLE c"},
+		},
+		{
+			name:    "comments only",
+			content: "-- Nothing to do for this database.\n",
+			want:    []string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := splitStatements(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("splitStatements() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMigrationsMatchAcrossDatabases(t *testing.T) {
+	var want []Migration
+	for _, dbType := range []string{TypeSQLite, TypePostgres, TypeMySQL} {
+		migrations, err := loadMigrations(dbType)
+		if err != nil {
+			t.Fatalf("loadMigrations(%s): %v", dbType, err)
+		}
+		if len(migrations) == 0 {
+			t.Fatalf("no migrations for %s", dbType)
+		}
+		if want == nil {
+			want = migrations
+			continue
+		}
+		if len(migrations) != len(want) {
+			t.Fatalf("%s has %d migrations, %s has %d", dbType, len(migrations), TypeSQLite, len(want))
+		}
+		for i := range migrations {
+			if migrations[i].Version != want[i].Version || migrations[i].Name != want[i].Name {
+				t.Errorf("%s migration %d is %04d_%s, %s has %04d_%s", dbType, i,
+					migrations[i].Version, migrations[i].Name, TypeSQLite, want[i].Version, want[i].Name)
+			}
+		}
+	}
+}
+
+// legacyDatabase creates a database as the versions before migrations left
+// it: the schema of the first migration, changed by the given statements,
+// and no record of any migration.
+func legacyDatabase(t *testing.T, statements ...string) *config.DatabaseConfig {
+	t.Helper()
+	migrations, err := loadMigrations(TypeSQLite)
+	if err != nil {
+		t.Fatalf("loadMigrations: %v", err)
+	}
+	cfg := &config.DatabaseConfig{Type: TypeSQLite, DSN: filepath.Join(t.TempDir(), "solo.db")}
+	d, err := Open(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer d.Close()
+	for _, statement := range append(migrations[0].up, statements...) {
+		if err := d.DB.Exec(statement).Error; err != nil {
+			t.Fatalf("%s: %v", statement, err)
+		}
+	}
+	return cfg
+}
+
+func TestAdoptLegacySchema(t *testing.T) {
+	cfg := legacyDatabase(t,
+		"INSERT INTO projects (id, name, directory, version) VALUES ('p', 'project', '/tmp', 1)",
+		"INSERT INTO tasks (id, title, project_id, version) VALUES ('t', 'task', 'p', 1)")
+
+	d, err := NewDatabase(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("NewDatabase: %v", err)
+	}
+	defer d.Close()
+
+	status, err := d.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	for _, migration := range status {
+		if migration.AppliedAt == nil {
+			t.Errorf("migration %04d_%s not applied", migration.Version, migration.Name)
+		}
+	}
+	for _, column := range []string{"completed_at", "trash_batch"} {
+		if !d.DB.Migrator().HasColumn(&Task{}, column) {
+			t.Errorf("tasks has no %s column", column)
+		}
+	}
+	var task Task
+	if err := d.DB.First(&task, "id = ?", "t").Error; err != nil {
+		t.Fatalf("task after the upgrade: %v", err)
+	}
+	if task.Title != "task" || task.ProjectID != "p" {
+		t.Errorf("task after the upgrade = %+v", task)
+	}
+}
+
+func TestAdoptOlderLegacySchema(t *testing.T) {
+	cfg := legacyDatabase(t,
+		"DROP INDEX idx_projects_archived_at",
+		"ALTER TABLE projects DROP COLUMN archived_at")
+
+	if _, err := NewDatabase(cfg, zap.NewNop()); !errors.Is(err, ErrLegacySchema) {
+		t.Errorf("NewDatabase: err = %v, want %v", err, ErrLegacySchema)
+	}
+}
@@ -0,0 +1,151 @@
+-- The schema as GORM AutoMigrate created it before migrations were versioned.
+
+CREATE TABLE `agents` (
+	`id` varchar(191),
+	`name` varchar(191) NOT NULL,
+	`type` longtext NOT NULL,
+	`description` longtext,
+	`command` longtext,
+	`version` bigint NOT NULL DEFAULT 1,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	CONSTRAINT `uni_agents_name` UNIQUE (`name`)
+);
+
+CREATE TABLE `tasks` (
+	`id` varchar(191),
+	`title` longtext NOT NULL,
+	`description` longtext,
+	`status` varchar(191) NOT NULL DEFAULT 'todo',
+	`priority` varchar(191) NOT NULL DEFAULT 'medium',
+	`due_date` datetime(3) NULL,
+	`assignee` longtext,
+	`agent_id` varchar(191),
+	`project_id` varchar(191),
+	`parent_id` varchar(191),
+	`position` varchar(191),
+	`branch` longtext,
+	`base_branch` longtext,
+	`worktree_path` longtext,
+	`version` bigint NOT NULL DEFAULT 1,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	`archived_at` datetime(3) NULL,
+	`deleted_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_tasks_parent_id` (`parent_id`),
+	INDEX `idx_tasks_position` (`position`),
+	INDEX `idx_tasks_archived_at` (`archived_at`),
+	INDEX `idx_tasks_deleted_at` (`deleted_at`),
+	INDEX `idx_tasks_agent_id` (`agent_id`),
+	INDEX `idx_tasks_project_id` (`project_id`),
+	CONSTRAINT `fk_tasks_agent` FOREIGN KEY (`agent_id`) REFERENCES `agents`(`id`) ON DELETE SET NULL,
+	CONSTRAINT `fk_tasks_parent` FOREIGN KEY (`parent_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE `projects` (
+	`id` varchar(191),
+	`name` longtext NOT NULL,
+	`description` longtext,
+	`directory` longtext NOT NULL,
+	`base_branch` longtext,
+	`workflow` text,
+	`agent_id` varchar(191),
+	`version` bigint NOT NULL DEFAULT 1,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	`archived_at` datetime(3) NULL,
+	`deleted_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_projects_archived_at` (`archived_at`),
+	INDEX `idx_projects_deleted_at` (`deleted_at`),
+	INDEX `idx_projects_agent_id` (`agent_id`),
+	CONSTRAINT `fk_projects_agent` FOREIGN KEY (`agent_id`) REFERENCES `agents`(`id`) ON DELETE SET NULL
+);
+
+CREATE TABLE `tags` (
+	`id` varchar(191),
+	`name` varchar(191) NOT NULL,
+	`color` longtext,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	CONSTRAINT `uni_tags_name` UNIQUE (`name`)
+);
+
+CREATE TABLE `task_tags` (
+	`task_id` varchar(191),
+	`tag_id` varchar(191),
+	PRIMARY KEY (`task_id`,`tag_id`),
+	CONSTRAINT `fk_task_tags_tag` FOREIGN KEY (`tag_id`) REFERENCES `tags`(`id`) ON DELETE CASCADE,
+	CONSTRAINT `fk_tasks_task_tags` FOREIGN KEY (`task_id`) REFERENCES `tasks`(`id`)
+);
+
+CREATE TABLE `task_dependencies` (
+	`task_id` varchar(191),
+	`depends_on_id` varchar(191),
+	`created_at` datetime(3) NULL,
+	PRIMARY KEY (`task_id`,`depends_on_id`),
+	INDEX `idx_task_dependencies_depends_on_id` (`depends_on_id`),
+	CONSTRAINT `fk_task_dependencies_task` FOREIGN KEY (`task_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE,
+	CONSTRAINT `fk_task_dependencies_depends_on` FOREIGN KEY (`depends_on_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE `comments` (
+	`id` varchar(191),
+	`task_id` varchar(191) NOT NULL,
+	`author_type` varchar(191) NOT NULL DEFAULT 'user',
+	`author_id` longtext,
+	`body` text NOT NULL,
+	`edited_at` datetime(3) NULL,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_comments_task_id` (`task_id`),
+	CONSTRAINT `fk_comments_task` FOREIGN KEY (`task_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE `comment_revisions` (
+	`id` varchar(191),
+	`comment_id` varchar(191) NOT NULL,
+	`body` text NOT NULL,
+	`created_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_comment_revisions_comment_id` (`comment_id`),
+	CONSTRAINT `fk_comment_revisions_comment` FOREIGN KEY (`comment_id`) REFERENCES `comments`(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE `runs` (
+	`id` varchar(191),
+	`task_id` varchar(191) NOT NULL,
+	`agent_id` longtext NOT NULL,
+	`status` varchar(191) NOT NULL DEFAULT 'running',
+	`command` longtext,
+	`working_dir` longtext,
+	`exit_code` bigint,
+	`error` longtext,
+	`log` text,
+	`started_at` datetime(3) NULL,
+	`finished_at` datetime(3) NULL,
+	`created_at` datetime(3) NULL,
+	`updated_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_runs_task_id` (`task_id`),
+	CONSTRAINT `fk_runs_task` FOREIGN KEY (`task_id`) REFERENCES `tasks`(`id`) ON DELETE CASCADE
+);
+
+CREATE TABLE `activities` (
+	`id` bigint AUTO_INCREMENT,
+	`entity_type` varchar(191) NOT NULL,
+	`entity_id` varchar(191) NOT NULL,
+	`project_id` varchar(191),
+	`action` longtext NOT NULL,
+	`actor` longtext NOT NULL,
+	`changes` text,
+	`created_at` datetime(3) NULL,
+	PRIMARY KEY (`id`),
+	INDEX `idx_activity_entity` (`entity_type`,`entity_id`),
+	INDEX `idx_activities_project_id` (`project_id`),
+	INDEX `idx_activities_created_at` (`created_at`)
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS projects_restrict_delete;
+DROP TRIGGER IF EXISTS tasks_project_update;
+DROP TRIGGER IF EXISTS tasks_project_insert;
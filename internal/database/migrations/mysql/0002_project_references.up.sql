@@ -0,0 +1,38 @@
+-- Tasks without a project carry an empty project_id, which a foreign key
+-- cannot express. Triggers enforce the reference instead: a task must point
+-- to an existing project or none, and projects with tasks cannot be deleted.
+-- Databases set up before this migration already have them.
+
+DROP TRIGGER IF EXISTS tasks_project_insert;
+
+-- +begin
+CREATE TRIGGER tasks_project_insert BEFORE INSERT ON tasks FOR EACH ROW
+BEGIN
+	IF NEW.project_id <> '' AND NOT EXISTS (SELECT 1 FROM projects WHERE id = NEW.project_id) THEN
+		SIGNAL SQLSTATE '23000' SET MESSAGE_TEXT = 'FOREIGN KEY constraint failed';
+	END IF;
+END;
+-- +end
+
+DROP TRIGGER IF EXISTS tasks_project_update;
+
+-- +begin
+CREATE TRIGGER tasks_project_update BEFORE UPDATE ON tasks FOR EACH ROW
+BEGIN
+	IF NEW.project_id <> OLD.project_id AND NEW.project_id <> ''
+		AND NOT EXISTS (SELECT 1 FROM projects WHERE id = NEW.project_id) THEN
+		SIGNAL SQLSTATE '23000' SET MESSAGE_TEXT = 'FOREIGN KEY constraint failed';
+	END IF;
+END;
+-- +end
+
+DROP TRIGGER IF EXISTS projects_restrict_delete;
+
+-- +begin
+CREATE TRIGGER projects_restrict_delete BEFORE DELETE ON projects FOR EACH ROW
+BEGIN
+	IF EXISTS (SELECT 1 FROM tasks WHERE project_id = OLD.id) THEN
+		SIGNAL SQLSTATE '23000' SET MESSAGE_TEXT = 'FOREIGN KEY constraint failed';
+	END IF;
+END;
+-- +end
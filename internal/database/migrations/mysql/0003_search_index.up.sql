@@ -0,0 +1,2 @@
+-- Only SQLite has a full-text search index; see SearchService for how this
+-- database is searched.
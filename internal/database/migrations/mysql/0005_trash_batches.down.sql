@@ -0,0 +1,3 @@
+ALTER TABLE `projects` DROP COLUMN `trash_batch`;
+
+ALTER TABLE `tasks` DROP COLUMN `trash_batch`;
@@ -0,0 +1,10 @@
+DROP TABLE IF EXISTS "activities";
+DROP TABLE IF EXISTS "runs";
+DROP TABLE IF EXISTS "comment_revisions";
+DROP TABLE IF EXISTS "comments";
+DROP TABLE IF EXISTS "task_dependencies";
+DROP TABLE IF EXISTS "task_tags";
+DROP TABLE IF EXISTS "tags";
+DROP TABLE IF EXISTS "tasks";
+DROP TABLE IF EXISTS "projects";
+DROP TABLE IF EXISTS "agents";
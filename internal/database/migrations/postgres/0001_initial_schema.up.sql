@@ -0,0 +1,151 @@
+-- The schema as GORM AutoMigrate created it before migrations were versioned.
+
+CREATE TABLE "agents" (
+	"id" text,
+	"name" text NOT NULL,
+	"type" text NOT NULL,
+	"description" text,
+	"command" text,
+	"version" bigint NOT NULL DEFAULT 1,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "uni_agents_name" UNIQUE ("name")
+);
+
+CREATE TABLE "tasks" (
+	"id" text,
+	"title" text NOT NULL,
+	"description" text,
+	"status" text NOT NULL DEFAULT 'todo',
+	"priority" text NOT NULL DEFAULT 'medium',
+	"due_date" timestamptz,
+	"assignee" text,
+	"agent_id" text,
+	"project_id" text,
+	"parent_id" text,
+	"position" text,
+	"branch" text,
+	"base_branch" text,
+	"worktree_path" text,
+	"version" bigint NOT NULL DEFAULT 1,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"archived_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_tasks_agent" FOREIGN KEY ("agent_id") REFERENCES "agents"("id") ON DELETE SET NULL,
+	CONSTRAINT "fk_tasks_parent" FOREIGN KEY ("parent_id") REFERENCES "tasks"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_tasks_deleted_at" ON "tasks" ("deleted_at");
+CREATE INDEX IF NOT EXISTS "idx_tasks_archived_at" ON "tasks" ("archived_at");
+CREATE INDEX IF NOT EXISTS "idx_tasks_position" ON "tasks" ("position");
+CREATE INDEX IF NOT EXISTS "idx_tasks_parent_id" ON "tasks" ("parent_id");
+CREATE INDEX IF NOT EXISTS "idx_tasks_project_id" ON "tasks" ("project_id");
+CREATE INDEX IF NOT EXISTS "idx_tasks_agent_id" ON "tasks" ("agent_id");
+
+CREATE TABLE "projects" (
+	"id" text,
+	"name" text NOT NULL,
+	"description" text,
+	"directory" text NOT NULL,
+	"base_branch" text,
+	"workflow" text,
+	"agent_id" text,
+	"version" bigint NOT NULL DEFAULT 1,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	"archived_at" timestamptz,
+	"deleted_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_projects_agent" FOREIGN KEY ("agent_id") REFERENCES "agents"("id") ON DELETE SET NULL
+);
+CREATE INDEX IF NOT EXISTS "idx_projects_archived_at" ON "projects" ("archived_at");
+CREATE INDEX IF NOT EXISTS "idx_projects_agent_id" ON "projects" ("agent_id");
+CREATE INDEX IF NOT EXISTS "idx_projects_deleted_at" ON "projects" ("deleted_at");
+
+CREATE TABLE "tags" (
+	"id" text,
+	"name" text NOT NULL,
+	"color" text,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "uni_tags_name" UNIQUE ("name")
+);
+
+CREATE TABLE "task_tags" (
+	"task_id" text,
+	"tag_id" text,
+	PRIMARY KEY ("task_id","tag_id"),
+	CONSTRAINT "fk_tasks_task_tags" FOREIGN KEY ("task_id") REFERENCES "tasks"("id"),
+	CONSTRAINT "fk_task_tags_tag" FOREIGN KEY ("tag_id") REFERENCES "tags"("id") ON DELETE CASCADE
+);
+
+CREATE TABLE "task_dependencies" (
+	"task_id" text,
+	"depends_on_id" text,
+	"created_at" timestamptz,
+	PRIMARY KEY ("task_id","depends_on_id"),
+	CONSTRAINT "fk_task_dependencies_task" FOREIGN KEY ("task_id") REFERENCES "tasks"("id") ON DELETE CASCADE,
+	CONSTRAINT "fk_task_dependencies_depends_on" FOREIGN KEY ("depends_on_id") REFERENCES "tasks"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_task_dependencies_depends_on_id" ON "task_dependencies" ("depends_on_id");
+
+CREATE TABLE "comments" (
+	"id" text,
+	"task_id" text NOT NULL,
+	"author_type" text NOT NULL DEFAULT 'user',
+	"author_id" text,
+	"body" text NOT NULL,
+	"edited_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_comments_task" FOREIGN KEY ("task_id") REFERENCES "tasks"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_comments_task_id" ON "comments" ("task_id");
+
+CREATE TABLE "comment_revisions" (
+	"id" text,
+	"comment_id" text NOT NULL,
+	"body" text NOT NULL,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_comment_revisions_comment" FOREIGN KEY ("comment_id") REFERENCES "comments"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_comment_revisions_comment_id" ON "comment_revisions" ("comment_id");
+
+CREATE TABLE "runs" (
+	"id" text,
+	"task_id" text NOT NULL,
+	"agent_id" text NOT NULL,
+	"status" text NOT NULL DEFAULT 'running',
+	"command" text,
+	"working_dir" text,
+	"exit_code" bigint,
+	"error" text,
+	"log" text,
+	"started_at" timestamptz,
+	"finished_at" timestamptz,
+	"created_at" timestamptz,
+	"updated_at" timestamptz,
+	PRIMARY KEY ("id"),
+	CONSTRAINT "fk_runs_task" FOREIGN KEY ("task_id") REFERENCES "tasks"("id") ON DELETE CASCADE
+);
+CREATE INDEX IF NOT EXISTS "idx_runs_task_id" ON "runs" ("task_id");
+
+CREATE TABLE "activities" (
+	"id" bigserial,
+	"entity_type" text NOT NULL,
+	"entity_id" text NOT NULL,
+	"project_id" text,
+	"action" text NOT NULL,
+	"actor" text NOT NULL,
+	"changes" text,
+	"created_at" timestamptz,
+	PRIMARY KEY ("id")
+);
+CREATE INDEX IF NOT EXISTS "idx_activities_created_at" ON "activities" ("created_at");
+CREATE INDEX IF NOT EXISTS "idx_activities_project_id" ON "activities" ("project_id");
+CREATE INDEX IF NOT EXISTS "idx_activity_entity" ON "activities" ("entity_type","entity_id");
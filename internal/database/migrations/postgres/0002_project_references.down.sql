@@ -0,0 +1,4 @@
+DROP TRIGGER IF EXISTS projects_restrict_delete ON projects;
+DROP TRIGGER IF EXISTS tasks_project_check ON tasks;
+DROP FUNCTION IF EXISTS projects_restrict_delete();
+DROP FUNCTION IF EXISTS tasks_project_check();
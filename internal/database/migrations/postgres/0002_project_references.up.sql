@@ -0,0 +1,34 @@
+-- Tasks without a project carry an empty project_id, which a foreign key
+-- cannot express. Triggers enforce the reference instead: a task must point
+-- to an existing project or none, and projects with tasks cannot be deleted.
+-- Databases set up before this migration already have them.
+
+-- +begin
+CREATE OR REPLACE FUNCTION tasks_project_check() RETURNS trigger AS $$
+BEGIN
+	IF NEW.project_id <> '' AND NOT EXISTS (SELECT 1 FROM projects WHERE id = NEW.project_id) THEN
+		RAISE foreign_key_violation USING MESSAGE = 'FOREIGN KEY constraint failed';
+	END IF;
+	RETURN NEW;
+END $$ LANGUAGE plpgsql;
+-- +end
+
+DROP TRIGGER IF EXISTS tasks_project_check ON tasks;
+
+CREATE TRIGGER tasks_project_check BEFORE INSERT OR UPDATE OF project_id ON tasks
+FOR EACH ROW EXECUTE FUNCTION tasks_project_check();
+
+-- +begin
+CREATE OR REPLACE FUNCTION projects_restrict_delete() RETURNS trigger AS $$
+BEGIN
+	IF EXISTS (SELECT 1 FROM tasks WHERE project_id = OLD.id) THEN
+		RAISE foreign_key_violation USING MESSAGE = 'FOREIGN KEY constraint failed';
+	END IF;
+	RETURN OLD;
+END $$ LANGUAGE plpgsql;
+-- +end
+
+DROP TRIGGER IF EXISTS projects_restrict_delete ON projects;
+
+CREATE TRIGGER projects_restrict_delete BEFORE DELETE ON projects
+FOR EACH ROW EXECUTE FUNCTION projects_restrict_delete();
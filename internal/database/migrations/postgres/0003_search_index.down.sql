@@ -0,0 +1 @@
+-- Only SQLite has a full-text search index.
@@ -0,0 +1,12 @@
+-- Tasks remember when they last entered a final status, which is what
+-- automatic archiving counts from. Finished tasks of projects on the
+-- default workflow start from their last update; tasks of projects with a
+-- custom workflow are left empty and fall back to it when archived.
+
+ALTER TABLE "tasks" ADD COLUMN "completed_at" timestamptz;
+
+CREATE INDEX "idx_tasks_completed_at" ON "tasks" ("completed_at");
+
+UPDATE "tasks" SET "completed_at" = "updated_at"
+WHERE "status" IN ('done', 'cancelled')
+	AND "project_id" NOT IN (SELECT "id" FROM "projects" WHERE COALESCE("workflow", '') <> '');
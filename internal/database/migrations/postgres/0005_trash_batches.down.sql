@@ -0,0 +1,3 @@
+ALTER TABLE "projects" DROP COLUMN IF EXISTS "trash_batch";
+
+ALTER TABLE "tasks" DROP COLUMN IF EXISTS "trash_batch";
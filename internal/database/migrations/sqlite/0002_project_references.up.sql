@@ -0,0 +1,25 @@
+-- Tasks without a project carry an empty project_id, which a foreign key
+-- cannot express. Triggers enforce the reference instead: a task must point
+-- to an existing project or none, and projects with tasks cannot be deleted.
+-- Databases set up before this migration already have them.
+
+-- +begin
+CREATE TRIGGER IF NOT EXISTS tasks_project_insert BEFORE INSERT ON tasks
+WHEN NEW.project_id <> '' AND NOT EXISTS (SELECT 1 FROM projects WHERE id = NEW.project_id) BEGIN
+	SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed');
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER IF NOT EXISTS tasks_project_update BEFORE UPDATE OF project_id ON tasks
+WHEN NEW.project_id <> '' AND NOT EXISTS (SELECT 1 FROM projects WHERE id = NEW.project_id) BEGIN
+	SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed');
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER IF NOT EXISTS projects_restrict_delete BEFORE DELETE ON projects
+WHEN EXISTS (SELECT 1 FROM tasks WHERE project_id = OLD.id) BEGIN
+	SELECT RAISE(ABORT, 'FOREIGN KEY constraint failed');
+END;
+-- +end
@@ -0,0 +1,12 @@
+DROP TRIGGER IF EXISTS search_index_project_trash;
+DROP TRIGGER IF EXISTS search_index_project_delete;
+DROP TRIGGER IF EXISTS search_index_project_update;
+DROP TRIGGER IF EXISTS search_index_project_insert;
+DROP TRIGGER IF EXISTS search_index_tag_update;
+DROP TRIGGER IF EXISTS search_index_task_tag_delete;
+DROP TRIGGER IF EXISTS search_index_task_tag_insert;
+DROP TRIGGER IF EXISTS search_index_task_trash;
+DROP TRIGGER IF EXISTS search_index_task_delete;
+DROP TRIGGER IF EXISTS search_index_task_update;
+DROP TRIGGER IF EXISTS search_index_task_insert;
+DROP TABLE IF EXISTS search_index;
@@ -0,0 +1,125 @@
+-- The full-text index searched by SearchService: an FTS5 table with a row
+-- per task and project, kept in sync by triggers. Trashed tasks and
+-- projects are left out. Databases set up before this migration already
+-- have the table; its triggers are recreated and its content rebuilt.
+
+CREATE VIRTUAL TABLE IF NOT EXISTS search_index USING fts5(
+	entity_type UNINDEXED,
+	entity_id UNINDEXED,
+	project_id UNINDEXED,
+	title,
+	body,
+	tags,
+	tokenize = 'unicode61 remove_diacritics 2'
+);
+
+DROP TRIGGER IF EXISTS search_index_task_insert;
+DROP TRIGGER IF EXISTS search_index_task_update;
+DROP TRIGGER IF EXISTS search_index_task_delete;
+DROP TRIGGER IF EXISTS search_index_task_trash;
+DROP TRIGGER IF EXISTS search_index_task_tag_insert;
+DROP TRIGGER IF EXISTS search_index_task_tag_delete;
+DROP TRIGGER IF EXISTS search_index_tag_update;
+DROP TRIGGER IF EXISTS search_index_project_insert;
+DROP TRIGGER IF EXISTS search_index_project_update;
+DROP TRIGGER IF EXISTS search_index_project_delete;
+DROP TRIGGER IF EXISTS search_index_project_trash;
+
+-- Tasks
+
+-- +begin
+CREATE TRIGGER search_index_task_insert AFTER INSERT ON tasks BEGIN
+	INSERT INTO search_index (entity_type, entity_id, project_id, title, body, tags)
+	VALUES ('task', NEW.id, NEW.project_id, NEW.title, NEW.description, COALESCE((SELECT group_concat(tags.name, ' ') FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = NEW.id), ''));
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_task_update AFTER UPDATE OF title, description, project_id ON tasks
+WHEN NEW.deleted_at IS NULL BEGIN
+	DELETE FROM search_index WHERE entity_type = 'task' AND entity_id = OLD.id;
+	INSERT INTO search_index (entity_type, entity_id, project_id, title, body, tags)
+	VALUES ('task', NEW.id, NEW.project_id, NEW.title, NEW.description, COALESCE((SELECT group_concat(tags.name, ' ') FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = NEW.id), ''));
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_task_delete AFTER DELETE ON tasks BEGIN
+	DELETE FROM search_index WHERE entity_type = 'task' AND entity_id = OLD.id;
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_task_trash AFTER UPDATE OF deleted_at ON tasks BEGIN
+	DELETE FROM search_index WHERE entity_type = 'task' AND entity_id = OLD.id;
+	INSERT INTO search_index (entity_type, entity_id, project_id, title, body, tags)
+	SELECT 'task', NEW.id, NEW.project_id, NEW.title, NEW.description, COALESCE((SELECT group_concat(tags.name, ' ') FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = NEW.id), '')
+	WHERE NEW.deleted_at IS NULL;
+END;
+-- +end
+
+-- Tag assignments and renames change the tags column of tasks
+
+-- +begin
+CREATE TRIGGER search_index_task_tag_insert AFTER INSERT ON task_tags BEGIN
+	UPDATE search_index SET tags = COALESCE((SELECT group_concat(tags.name, ' ') FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = NEW.task_id), '')
+	WHERE entity_type = 'task' AND entity_id = NEW.task_id;
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_task_tag_delete AFTER DELETE ON task_tags BEGIN
+	UPDATE search_index SET tags = COALESCE((SELECT group_concat(tags.name, ' ') FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = OLD.task_id), '')
+	WHERE entity_type = 'task' AND entity_id = OLD.task_id;
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_tag_update AFTER UPDATE OF name ON tags BEGIN
+	UPDATE search_index SET tags = COALESCE((SELECT group_concat(tags.name, ' ') FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = search_index.entity_id), '')
+	WHERE entity_type = 'task' AND entity_id IN (SELECT task_id FROM task_tags WHERE tag_id = NEW.id);
+END;
+-- +end
+
+-- Projects
+
+-- +begin
+CREATE TRIGGER search_index_project_insert AFTER INSERT ON projects BEGIN
+	INSERT INTO search_index (entity_type, entity_id, project_id, title, body, tags)
+	VALUES ('project', NEW.id, NEW.id, NEW.name, NEW.description, '');
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_project_update AFTER UPDATE OF name, description ON projects BEGIN
+	UPDATE search_index SET title = NEW.name, body = NEW.description
+	WHERE entity_type = 'project' AND entity_id = NEW.id;
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_project_delete AFTER DELETE ON projects BEGIN
+	DELETE FROM search_index WHERE entity_type = 'project' AND entity_id = OLD.id;
+END;
+-- +end
+
+-- +begin
+CREATE TRIGGER search_index_project_trash AFTER UPDATE OF deleted_at ON projects BEGIN
+	DELETE FROM search_index WHERE entity_type = 'project' AND entity_id = OLD.id;
+	INSERT INTO search_index (entity_type, entity_id, project_id, title, body, tags)
+	SELECT 'project', NEW.id, NEW.id, NEW.name, NEW.description, ''
+	WHERE NEW.deleted_at IS NULL;
+END;
+-- +end
+
+-- Index what is already there
+
+DELETE FROM search_index;
+
+INSERT INTO search_index (entity_type, entity_id, project_id, title, body, tags)
+SELECT 'task', id, project_id, title, description, COALESCE((SELECT group_concat(tags.name, ' ') FROM task_tags JOIN tags ON tags.id = task_tags.tag_id WHERE task_tags.task_id = tasks.id), '')
+FROM tasks WHERE deleted_at IS NULL;
+
+INSERT INTO search_index (entity_type, entity_id, project_id, title, body, tags)
+SELECT 'project', id, id, name, description, ''
+FROM projects WHERE deleted_at IS NULL;
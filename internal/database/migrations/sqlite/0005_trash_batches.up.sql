@@ -0,0 +1,12 @@
+-- Tasks and projects trashed by one delete share a batch, which is what a
+-- restore brings back and a purge removes. Deletion times alone do not
+-- tell deletes apart once the database rounds them. Items already in the
+-- trash keep being grouped by their deletion time.
+
+ALTER TABLE `tasks` ADD COLUMN `trash_batch` varchar(64) NOT NULL DEFAULT '';
+
+ALTER TABLE `projects` ADD COLUMN `trash_batch` varchar(64) NOT NULL DEFAULT '';
+
+UPDATE `tasks` SET `trash_batch` = CAST(`deleted_at` AS TEXT) WHERE `deleted_at` IS NOT NULL;
+
+UPDATE `projects` SET `trash_batch` = CAST(`deleted_at` AS TEXT) WHERE `deleted_at` IS NOT NULL;
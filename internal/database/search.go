@@ -0,0 +1,6 @@
+package database
+
+// SearchIndexTable is the FTS5 table indexing tasks and projects, created by
+// the search_index migration on SQLite. Rows carry the entity they index;
+// title, body and tags are the searchable columns.
+const SearchIndexTable = "search_index"
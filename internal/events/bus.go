@@ -0,0 +1,121 @@
+package events
+
+import (
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+const (
+	TaskCreated     = "task.created"
+	TaskUpdated     = "task.updated"
+	TaskDeleted     = "task.deleted"
+	TaskRestored    = "task.restored"
+	ProjectCreated  = "project.created"
+	ProjectUpdated  = "project.updated"
+	ProjectDeleted  = "project.deleted"
+	ProjectRestored = "project.restored"
+	WorkflowUpdated = "project.workflow_updated"
+	AgentCreated    = "agent.created"
+	AgentUpdated    = "agent.updated"
+	AgentDeleted    = "agent.deleted"
+	CommentCreated  = "comment.created"
+	CommentUpdated  = "comment.updated"
+	CommentDeleted  = "comment.deleted"
+	TagCreated      = "tag.created"
+	TagUpdated      = "tag.updated"
+	TagDeleted      = "tag.deleted"
+
+	// subscriberBuffer is how many events a subscriber may fall behind before
+	// it is dropped; it is expected to reconnect and reload.
+	subscriberBuffer = 256
+)
+
+// Event describes a change to a board entity.
+type Event struct {
+	ID                int64       `json:"id"`
+	Type              string      `json:"type"`
+	EntityID          string      `json:"entity_id"`
+	ProjectID         string      `json:"project_id,omitempty"`
+	PreviousProjectID string      `json:"previous_project_id,omitempty"` // Set when a task moved between projects
+	Data              interface{} `json:"data,omitempty"`
+	Timestamp         time.Time   `json:"timestamp"`
+}
+
+type subscription struct {
+	ch        chan Event
+	projectID string
+}
+
+// matches reports whether the subscription wants the event. Events that do
+// not belong to a project, such as agent changes, reach every subscriber.
+func (s *subscription) matches(event Event) bool {
+	if s.projectID == "" || event.ProjectID == "" {
+		return true
+	}
+	return event.ProjectID == s.projectID || event.PreviousProjectID == s.projectID
+}
+
+// Bus fans out entity change events to subscribers in process.
+type Bus struct {
+	logger      *zap.Logger
+	mu          sync.Mutex
+	seq         int64
+	subscribers map[*subscription]struct{}
+}
+
+func NewBus(logger *zap.Logger) *Bus {
+	return &Bus{
+		logger:      logger,
+		subscribers: make(map[*subscription]struct{}),
+	}
+}
+
+// Publish delivers an event to every matching subscriber without blocking.
+func (b *Bus) Publish(event Event) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.seq++
+	event.ID = b.seq
+	if event.Timestamp.IsZero() {
+		event.Timestamp = time.Now()
+	}
+
+	for sub := range b.subscribers {
+		if !sub.matches(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			b.logger.Warn("Dropping slow event subscriber", zap.String("project_id", sub.projectID))
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
+
+// Subscribe returns a channel of events, optionally limited to one project.
+// The channel is closed if the subscriber falls too far behind; call
+// unsubscribe when done listening.
+func (b *Bus) Subscribe(projectID string) (<-chan Event, func()) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub := &subscription{
+		ch:        make(chan Event, subscriberBuffer),
+		projectID: projectID,
+	}
+	b.subscribers[sub] = struct{}{}
+
+	return sub.ch, func() {
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subscribers[sub]; ok {
+			delete(b.subscribers, sub)
+			close(sub.ch)
+		}
+	}
+}
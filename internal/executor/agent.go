@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var ErrUnknownAgentType = errors.New("no command configured for agent type")
+
+// PromptPlaceholder may appear in a custom agent command to control where
+// the prompt is inserted. Without it the prompt is appended as the last argument.
+const PromptPlaceholder = "{prompt}"
+
+// defaultCommands maps well-known agent types to a non-interactive CLI invocation.
+var defaultCommands = map[string][]string{
+	"claude-code": {"claude", "-p", "--permission-mode", "acceptEdits"},
+	"claude":      {"claude", "-p", "--permission-mode", "acceptEdits"},
+	"codex":       {"codex", "exec", "--full-auto"},
+	"gemini":      {"gemini", "-p"},
+	"aider":       {"aider", "--yes-always", "--message"},
+}
+
+// AgentCommand builds the command used to run an agent of the given type.
+// A non-empty override takes precedence over the built-in defaults.
+func AgentCommand(agentType, override, prompt, dir string) (Command, error) {
+	var parts []string
+	if strings.TrimSpace(override) != "" {
+		parts = strings.Fields(override)
+	} else if defaults, ok := defaultCommands[strings.ToLower(agentType)]; ok {
+		parts = append(parts, defaults...)
+	} else {
+		return Command{}, fmt.Errorf("%w %q", ErrUnknownAgentType, agentType)
+	}
+
+	args := make([]string, 0, len(parts))
+	substituted := false
+	for _, part := range parts[1:] {
+		if strings.Contains(part, PromptPlaceholder) {
+			part = strings.ReplaceAll(part, PromptPlaceholder, prompt)
+			substituted = true
+		}
+		args = append(args, part)
+	}
+	if !substituted {
+		args = append(args, prompt)
+	}
+
+	return Command{
+		Name: parts[0],
+		Args: args,
+		Dir:  dir,
+	}, nil
+}
+
+// TaskPrompt builds the prompt handed to an agent from a task's title and description.
+func TaskPrompt(title, description string) string {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return title
+	}
+	return title + "\n\n" + description
+}
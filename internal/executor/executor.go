@@ -0,0 +1,194 @@
+package executor
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// outputWaitDelay bounds how long Wait keeps collecting output after the
+// process exits, e.g. while a background child still holds the pipe.
+const outputWaitDelay = 5 * time.Second
+
+var (
+	ErrAlreadyRunning = errors.New("process is already running")
+	ErrNotRunning     = errors.New("process is not running")
+)
+
+// Command describes a process to launch.
+type Command struct {
+	Name string
+	Args []string
+	Dir  string
+	Env  []string
+}
+
+func (c Command) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Result describes how a process finished.
+type Result struct {
+	ExitCode  int
+	Err       error
+	Cancelled bool
+	Output    []Line
+}
+
+type process struct {
+	cmd       *exec.Cmd
+	cancel    context.CancelFunc
+	output    *Output
+	stdout    *io.PipeWriter
+	stderr    *io.PipeWriter
+	readers   sync.WaitGroup
+	cancelled bool
+}
+
+// Executor launches agent processes and tracks them by ID until they exit.
+type Executor struct {
+	logger    *zap.Logger
+	mu        sync.Mutex
+	processes map[string]*process
+}
+
+func NewExecutor(logger *zap.Logger) *Executor {
+	return &Executor{
+		logger:    logger,
+		processes: make(map[string]*process),
+	}
+}
+
+// Start launches the command and returns once the process has started.
+// onExit is called from a separate goroutine when the process finishes.
+func (e *Executor) Start(id string, command Command, onExit func(Result)) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if _, exists := e.processes[id]; exists {
+		return ErrAlreadyRunning
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, command.Name, command.Args...)
+	cmd.Dir = command.Dir
+	cmd.Env = append(os.Environ(), command.Env...)
+
+	// Output goes through in-process pipes rather than StdoutPipe so that Wait,
+	// bounded by WaitDelay, does not hang on descendants holding the pipe open.
+	stdoutReader, stdoutWriter := io.Pipe()
+	stderrReader, stderrWriter := io.Pipe()
+	cmd.Stdout = stdoutWriter
+	cmd.Stderr = stderrWriter
+	cmd.WaitDelay = outputWaitDelay
+
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return err
+	}
+
+	proc := &process{cmd: cmd, cancel: cancel, output: newOutput(), stdout: stdoutWriter, stderr: stderrWriter}
+	proc.readers.Add(2)
+	go func() {
+		defer proc.readers.Done()
+		proc.output.capture(StreamStdout, stdoutReader)
+	}()
+	go func() {
+		defer proc.readers.Done()
+		proc.output.capture(StreamStderr, stderrReader)
+	}()
+	e.processes[id] = proc
+
+	e.logger.Info("Process started",
+		zap.String("id", id),
+		zap.String("command", command.Name),
+		zap.String("dir", command.Dir),
+		zap.Int("pid", cmd.Process.Pid))
+
+	go e.wait(id, proc, onExit)
+
+	return nil
+}
+
+func (e *Executor) wait(id string, proc *process, onExit func(Result)) {
+	err := proc.cmd.Wait()
+	proc.cancel()
+	proc.stdout.Close()
+	proc.stderr.Close()
+	proc.readers.Wait()
+
+	e.mu.Lock()
+	cancelled := proc.cancelled
+	e.mu.Unlock()
+
+	result := Result{ExitCode: 0, Cancelled: cancelled, Output: proc.output.Lines()}
+	if err != nil {
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			result.ExitCode = exitErr.ExitCode()
+		} else {
+			result.ExitCode = -1
+		}
+		result.Err = err
+	}
+
+	e.logger.Info("Process exited",
+		zap.String("id", id),
+		zap.Int("exit_code", result.ExitCode),
+		zap.Bool("cancelled", result.Cancelled))
+
+	if onExit != nil {
+		onExit(result)
+	}
+
+	// Subscribers are released only after onExit has recorded the outcome, so
+	// anyone looking up the run once its stream ends sees the final state.
+	proc.output.close()
+
+	e.mu.Lock()
+	delete(e.processes, id)
+	e.mu.Unlock()
+}
+
+// Cancel kills a running process. The onExit callback still fires.
+func (e *Executor) Cancel(id string) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	proc, exists := e.processes[id]
+	if !exists {
+		return ErrNotRunning
+	}
+
+	proc.cancelled = true
+	proc.cancel()
+	return nil
+}
+
+// Output returns the live output of a running process.
+func (e *Executor) Output(id string) (*Output, bool) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	proc, exists := e.processes[id]
+	if !exists {
+		return nil, false
+	}
+	return proc.output, true
+}
+
+// IsRunning reports whether a process with the given ID is still alive.
+func (e *Executor) IsRunning(id string) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	_, exists := e.processes[id]
+	return exists
+}
@@ -0,0 +1,121 @@
+package executor
+
+import (
+	"bufio"
+	"io"
+	"sync"
+	"time"
+)
+
+const (
+	StreamStdout = "stdout"
+	StreamStderr = "stderr"
+
+	// subscriberBuffer is how many lines a subscriber may fall behind before
+	// it is dropped, so a slow client can never stall the agent process.
+	subscriberBuffer = 1024
+	maxLineLength    = 1024 * 1024
+)
+
+// Line is a single line of process output.
+type Line struct {
+	Seq    int
+	Stream string
+	Text   string
+	Time   time.Time
+}
+
+// Output buffers everything a process writes and fans it out to subscribers.
+type Output struct {
+	mu          sync.Mutex
+	lines       []Line
+	subscribers map[chan Line]struct{}
+	closed      bool
+}
+
+func newOutput() *Output {
+	return &Output{
+		subscribers: make(map[chan Line]struct{}),
+	}
+}
+
+// Subscribe returns the lines written so far together with a channel that
+// receives every later line. The channel is closed when the process exits or
+// the subscriber falls too far behind; call unsubscribe when done listening.
+func (o *Output) Subscribe() (backlog []Line, lines <-chan Line, unsubscribe func()) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	backlog = make([]Line, len(o.lines))
+	copy(backlog, o.lines)
+
+	ch := make(chan Line, subscriberBuffer)
+	if o.closed {
+		close(ch)
+		return backlog, ch, func() {}
+	}
+
+	o.subscribers[ch] = struct{}{}
+	return backlog, ch, func() {
+		o.mu.Lock()
+		defer o.mu.Unlock()
+		if _, ok := o.subscribers[ch]; ok {
+			delete(o.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+// Lines returns a copy of everything written so far.
+func (o *Output) Lines() []Line {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	lines := make([]Line, len(o.lines))
+	copy(lines, o.lines)
+	return lines
+}
+
+func (o *Output) append(stream, text string) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	line := Line{
+		Seq:    len(o.lines) + 1,
+		Stream: stream,
+		Text:   text,
+		Time:   time.Now(),
+	}
+	o.lines = append(o.lines, line)
+
+	for ch := range o.subscribers {
+		select {
+		case ch <- line:
+		default:
+			delete(o.subscribers, ch)
+			close(ch)
+		}
+	}
+}
+
+func (o *Output) close() {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	o.closed = true
+	for ch := range o.subscribers {
+		delete(o.subscribers, ch)
+		close(ch)
+	}
+}
+
+// capture copies r into the output line by line until EOF.
+func (o *Output) capture(stream string, r io.Reader) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineLength)
+	for scanner.Scan() {
+		o.append(stream, scanner.Text())
+	}
+	// Drain the rest so the process never blocks on a full pipe
+	io.Copy(io.Discard, r)
+}
@@ -0,0 +1,46 @@
+package git
+
+import (
+	"strings"
+)
+
+const maxSlugLength = 40
+
+// TaskBranch builds the branch name used for a task, e.g. "solo/1a2b3c4d-fix-login".
+func TaskBranch(prefix, taskID, title string) string {
+	shortID := taskID
+	if len(shortID) > 8 {
+		shortID = shortID[:8]
+	}
+
+	name := shortID
+	if slug := Slugify(title); slug != "" {
+		name += "-" + slug
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return name
+	}
+	return prefix + "/" + name
+}
+
+// Slugify lowercases s and collapses everything but ASCII letters and digits
+// into single dashes, so the result is safe to use in a branch name.
+func Slugify(s string) string {
+	var b strings.Builder
+	dash := false
+	for _, r := range strings.ToLower(s) {
+		if (r >= 'a' && r <= 'z') || (r >= '0' && r <= '9') {
+			b.WriteRune(r)
+			dash = false
+		} else if !dash && b.Len() > 0 {
+			b.WriteByte('-')
+			dash = true
+		}
+		if b.Len() >= maxSlugLength {
+			break
+		}
+	}
+	return strings.Trim(b.String(), "-")
+}
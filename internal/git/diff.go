@@ -0,0 +1,246 @@
+package git
+
+import (
+	"bufio"
+	"strconv"
+	"strings"
+)
+
+const (
+	FileAdded    = "added"
+	FileModified = "modified"
+	FileDeleted  = "deleted"
+	FileRenamed  = "renamed"
+
+	LineContext = "context"
+	LineAdded   = "add"
+	LineDeleted = "delete"
+)
+
+// FileDiff is the change to one file between two revisions.
+type FileDiff struct {
+	Path      string
+	OldPath   string
+	Status    string
+	Binary    bool
+	Additions int
+	Deletions int
+	Hunks     []Hunk
+}
+
+// Hunk is one @@ section of a unified diff.
+type Hunk struct {
+	Header   string
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Lines    []DiffLine
+}
+
+// DiffLine is a single line of a hunk. Line numbers are 0 on the side the
+// line does not exist on.
+type DiffLine struct {
+	Type    string
+	Content string
+	OldLine int
+	NewLine int
+}
+
+// Diff returns the changes made on head since it diverged from base, i.e.
+// `git diff base...head`. Passing paths limits the diff to those files.
+func Diff(repoDir, base, head string, paths ...string) ([]FileDiff, error) {
+	args := []string{"-c", "core.quotePath=false", "diff", "--no-color", "--no-ext-diff", "-M", base + "..." + head}
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+
+	output, err := runRaw(repoDir, args...)
+	if err != nil {
+		return nil, err
+	}
+
+	return ParseDiff(output), nil
+}
+
+// ParseDiff parses the output of `git diff` into files and hunks.
+func ParseDiff(patch string) []FileDiff {
+	var files []FileDiff
+	var file *FileDiff
+	var hunk *Hunk
+	oldLine, newLine := 0, 0
+
+	flushHunk := func() {
+		if file != nil && hunk != nil {
+			file.Hunks = append(file.Hunks, *hunk)
+		}
+		hunk = nil
+	}
+	flushFile := func() {
+		flushHunk()
+		if file != nil {
+			if file.Path == "" {
+				file.Path = file.OldPath
+			}
+			if file.OldPath == file.Path && file.Status != FileRenamed {
+				file.OldPath = ""
+			}
+			files = append(files, *file)
+		}
+		file = nil
+	}
+
+	scanner := bufio.NewScanner(strings.NewReader(patch))
+	scanner.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+
+		if strings.HasPrefix(line, "diff --git ") {
+			flushFile()
+			file = &FileDiff{Status: FileModified}
+			file.OldPath, file.Path = parseDiffGitLine(line)
+			continue
+		}
+		if file == nil {
+			continue
+		}
+
+		if hunk != nil {
+			switch {
+			case strings.HasPrefix(line, "+"):
+				hunk.Lines = append(hunk.Lines, DiffLine{Type: LineAdded, Content: line[1:], NewLine: newLine})
+				file.Additions++
+				newLine++
+				continue
+			case strings.HasPrefix(line, "-"):
+				hunk.Lines = append(hunk.Lines, DiffLine{Type: LineDeleted, Content: line[1:], OldLine: oldLine})
+				file.Deletions++
+				oldLine++
+				continue
+			case strings.HasPrefix(line, " "), line == "":
+				content := line
+				if content != "" {
+					content = content[1:]
+				}
+				hunk.Lines = append(hunk.Lines, DiffLine{Type: LineContext, Content: content, OldLine: oldLine, NewLine: newLine})
+				oldLine++
+				newLine++
+				continue
+			case strings.HasPrefix(line, `\`):
+				// "\ No newline at end of file"
+				continue
+			}
+		}
+
+		switch {
+		case strings.HasPrefix(line, "@@"):
+			flushHunk()
+			hunk = parseHunkHeader(line)
+			oldLine, newLine = hunk.OldStart, hunk.NewStart
+		case strings.HasPrefix(line, "new file mode"):
+			file.Status = FileAdded
+		case strings.HasPrefix(line, "deleted file mode"):
+			file.Status = FileDeleted
+		case strings.HasPrefix(line, "rename from "):
+			file.Status = FileRenamed
+			file.OldPath = diffPath(strings.TrimPrefix(line, "rename from "), "")
+		case strings.HasPrefix(line, "rename to "):
+			file.Status = FileRenamed
+			file.Path = diffPath(strings.TrimPrefix(line, "rename to "), "")
+		case strings.HasPrefix(line, "--- "):
+			if path := diffPath(strings.TrimPrefix(line, "--- "), "a/"); path != "/dev/null" {
+				file.OldPath = path
+			} else {
+				file.OldPath = ""
+			}
+		case strings.HasPrefix(line, "+++ "):
+			if path := diffPath(strings.TrimPrefix(line, "+++ "), "b/"); path != "/dev/null" {
+				file.Path = path
+			} else {
+				file.Path = file.OldPath
+			}
+		case strings.HasPrefix(line, "Binary files "):
+			file.Binary = true
+		}
+	}
+	flushFile()
+
+	return files
+}
+
+// parseDiffGitLine extracts paths from "diff --git a/old b/new". It is only a
+// fallback for entries without ---/+++ lines, such as binary files.
+func parseDiffGitLine(line string) (string, string) {
+	rest := strings.TrimPrefix(line, "diff --git ")
+	if end := quotedEnd(rest); end > 0 {
+		return diffPath(rest[:end], "a/"), diffPath(strings.TrimPrefix(rest[end:], " "), "b/")
+	}
+	if i := strings.Index(rest, ` "b/`); i >= 0 && strings.HasPrefix(rest, "a/") {
+		return rest[2:i], diffPath(rest[i+1:], "b/")
+	}
+	if i := strings.Index(rest, " b/"); i >= 0 && strings.HasPrefix(rest, "a/") {
+		return rest[2:i], rest[i+3:]
+	}
+	return rest, rest
+}
+
+// diffPath reads a path as git prints it in a diff header and drops the
+// a/ or b/ prefix. Paths with unusual characters are quoted C-style, and
+// paths containing spaces are followed by a tab on ---/+++ lines.
+func diffPath(path, prefix string) string {
+	path = strings.TrimSuffix(path, "\t")
+	if quotedEnd(path) == len(path) {
+		if unquoted, err := strconv.Unquote(path); err == nil {
+			path = unquoted
+		}
+	}
+	return strings.TrimPrefix(path, prefix)
+}
+
+// quotedEnd returns the index just past the quoted string s starts with,
+// or 0 when it does not start with one.
+func quotedEnd(s string) int {
+	if !strings.HasPrefix(s, `"`) {
+		return 0
+	}
+	for i := 1; i < len(s); i++ {
+		switch s[i] {
+		case '\\':
+			i++
+		case '"':
+			return i + 1
+		}
+	}
+	return 0
+}
+
+// parseHunkHeader parses "@@ -oldStart,oldLines +newStart,newLines @@ section".
+func parseHunkHeader(line string) *Hunk {
+	hunk := &Hunk{Header: line, OldLines: 1, NewLines: 1}
+
+	fields := strings.Fields(line)
+	for _, field := range fields[1:] {
+		if field == "@@" {
+			break
+		}
+		start, count := parseRange(field[1:])
+		if strings.HasPrefix(field, "-") {
+			hunk.OldStart, hunk.OldLines = start, count
+		} else if strings.HasPrefix(field, "+") {
+			hunk.NewStart, hunk.NewLines = start, count
+		}
+	}
+
+	return hunk
+}
+
+func parseRange(r string) (int, int) {
+	start, count := r, "1"
+	if i := strings.Index(r, ","); i >= 0 {
+		start, count = r[:i], r[i+1:]
+	}
+	s, _ := strconv.Atoi(start)
+	c, _ := strconv.Atoi(count)
+	return s, c
+}
@@ -0,0 +1,56 @@
+package git
+
+import "testing"
+
+func TestParseDiffPaths(t *testing.T) {
+	patch := "diff --git a/my file.txt b/my file.txt\n" +
+		"new file mode 100644\n" +
+		"index 0000000..45b983b\n" +
+		"--- /dev/null\n" +
+		"+++ b/my file.txt\t\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+hi\n" +
+		"diff --git a/old name.txt b/new name.txt\n" +
+		"similarity index 100%\n" +
+		"rename from old name.txt\n" +
+		"rename to new name.txt\n" +
+		"diff --git \"a/tab\\tname\" \"b/tab\\tname\"\n" +
+		"new file mode 100644\n" +
+		"index 0000000..7898192\n" +
+		"--- /dev/null\n" +
+		"+++ \"b/tab\\tname\"\n" +
+		"@@ -0,0 +1 @@\n" +
+		"+a\n" +
+		"diff --git a/gone file.txt b/gone file.txt\n" +
+		"deleted file mode 100644\n" +
+		"index 587be6b..0000000\n" +
+		"--- a/gone file.txt\t\n" +
+		"+++ /dev/null\n" +
+		"@@ -1 +0,0 @@\n" +
+		"-x\n" +
+		"diff --git \"a/\\303\\274.bin\" \"b/\\303\\274.bin\"\n" +
+		"index 1b2c3d4..5e6f7a8 100644\n" +
+		"Binary files \"a/\\303\\274.bin\" and \"b/\\303\\274.bin\" differ\n"
+
+	tests := []struct {
+		path, oldPath, status string
+	}{
+		{"my file.txt", "", FileAdded},
+		{"new name.txt", "old name.txt", FileRenamed},
+		{"tab\tname", "", FileAdded},
+		{"gone file.txt", "", FileDeleted},
+		{"ü.bin", "", FileModified},
+	}
+
+	files := ParseDiff(patch)
+	if len(files) != len(tests) {
+		t.Fatalf("got %d files, want %d", len(files), len(tests))
+	}
+	for i, tt := range tests {
+		file := files[i]
+		if file.Path != tt.path || file.OldPath != tt.oldPath || file.Status != tt.status {
+			t.Errorf("file %d = %q (old %q, %s), want %q (old %q, %s)",
+				i, file.Path, file.OldPath, file.Status, tt.path, tt.oldPath, tt.status)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package git
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+)
+
+// defaultAuthorName and defaultAuthorEmail are used for commits made by the
+// server when the repository has no identity configured.
+const (
+	defaultAuthorName  = "Solo"
+	defaultAuthorEmail = "solo@localhost"
+)
+
+// IsRepository reports whether dir is the root of a git repository or worktree.
+func IsRepository(dir string) bool {
+	fileInfo, err := os.Stat(dir)
+	if err != nil || !fileInfo.IsDir() {
+		return false
+	}
+
+	_, err = os.Stat(filepath.Join(dir, ".git"))
+	return err == nil
+}
+
+// run executes git in dir and returns its trimmed stdout. Failures include stderr.
+func run(dir string, args ...string) (string, error) {
+	output, err := runRaw(dir, args...)
+	return strings.TrimSpace(output), err
+}
+
+// runRaw is like run but returns stdout untouched.
+func runRaw(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = err.Error()
+		}
+		return stdout.String(), fmt.Errorf("git %s: %s", gitSubcommand(args), message)
+	}
+
+	return stdout.String(), nil
+}
+
+// gitSubcommand returns the first argument that is not a global option.
+func gitSubcommand(args []string) string {
+	for i := 0; i < len(args); i++ {
+		if args[i] == "-c" {
+			i++
+			continue
+		}
+		return args[i]
+	}
+	return ""
+}
+
+// CurrentBranch returns the branch checked out in dir.
+func CurrentBranch(dir string) (string, error) {
+	return run(dir, "rev-parse", "--abbrev-ref", "HEAD")
+}
+
+// BranchExists reports whether a local branch exists in the repository at dir.
+func BranchExists(dir, branch string) bool {
+	_, err := run(dir, "rev-parse", "--verify", "--quiet", "refs/heads/"+branch)
+	return err == nil
+}
+
+// AddWorktree checks out branch into path, creating the branch from base if
+// it does not exist yet.
+func AddWorktree(repoDir, path, branch, base string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if BranchExists(repoDir, branch) {
+		_, err := run(repoDir, "worktree", "add", path, branch)
+		return err
+	}
+
+	_, err := run(repoDir, "worktree", "add", "-b", branch, path, base)
+	return err
+}
+
+// RemoveWorktree deletes the worktree at path, discarding uncommitted changes.
+func RemoveWorktree(repoDir, path string) error {
+	if _, err := os.Stat(path); err == nil {
+		if _, err := run(repoDir, "worktree", "remove", "--force", path); err != nil {
+			return err
+		}
+	}
+
+	_, err := run(repoDir, "worktree", "prune")
+	return err
+}
+
+// DeleteBranch force-deletes a local branch.
+func DeleteBranch(repoDir, branch string) error {
+	if !BranchExists(repoDir, branch) {
+		return nil
+	}
+
+	_, err := run(repoDir, "branch", "-D", branch)
+	return err
+}
+
+// CommitAll stages every change in dir and commits it. It reports whether a
+// commit was created; a clean tree is not an error.
+func CommitAll(dir, message string) (bool, error) {
+	if _, err := run(dir, "add", "-A"); err != nil {
+		return false, err
+	}
+
+	if _, err := run(dir, "diff", "--cached", "--quiet"); err == nil {
+		return false, nil
+	}
+
+	args := append(identityArgs(dir), "commit", "--no-verify", "-m", message)
+	if _, err := run(dir, args...); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// identityArgs supplies a fallback author when the repository has none configured.
+func identityArgs(dir string) []string {
+	var args []string
+	if name, _ := run(dir, "config", "user.name"); name == "" {
+		args = append(args, "-c", "user.name="+defaultAuthorName)
+	}
+	if email, _ := run(dir, "config", "user.email"); email == "" {
+		args = append(args, "-c", "user.email="+defaultAuthorEmail)
+	}
+	return args
+}
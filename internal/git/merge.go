@@ -0,0 +1,90 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictError reports that a merge or rebase stopped on conflicts. The
+// repository has already been restored to its previous state.
+type ConflictError struct {
+	Operation string
+	Files     []string
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%s conflict in %d file(s): %s", e.Operation, len(e.Files), strings.Join(e.Files, ", "))
+}
+
+// IsClean reports whether dir has no staged or unstaged changes to tracked files.
+func IsClean(dir string) (bool, error) {
+	output, err := run(dir, "status", "--porcelain", "--untracked-files=no")
+	if err != nil {
+		return false, err
+	}
+	return output == "", nil
+}
+
+// HeadCommit returns the commit checked out in dir.
+func HeadCommit(dir string) (string, error) {
+	return run(dir, "rev-parse", "HEAD")
+}
+
+// Merge merges branch into the branch checked out in dir. With squash the
+// changes are collapsed into a single commit. Conflicts abort the merge and
+// return a *ConflictError.
+func Merge(dir, branch, message string, squash bool) error {
+	if !squash {
+		args := append(identityArgs(dir), "merge", "--no-ff", "--no-edit", "-m", message, branch)
+		if _, err := run(dir, args...); err != nil {
+			files := conflictedFiles(dir)
+			run(dir, "merge", "--abort")
+			if len(files) > 0 {
+				return &ConflictError{Operation: "merge", Files: files}
+			}
+			return err
+		}
+		return nil
+	}
+
+	if _, err := run(dir, "merge", "--squash", branch); err != nil {
+		files := conflictedFiles(dir)
+		run(dir, "reset", "--merge")
+		if len(files) > 0 {
+			return &ConflictError{Operation: "merge", Files: files}
+		}
+		return err
+	}
+
+	// Nothing to commit when the branch holds no new changes
+	if _, err := run(dir, "diff", "--cached", "--quiet"); err == nil {
+		return nil
+	}
+
+	args := append(identityArgs(dir), "commit", "--no-verify", "-m", message)
+	_, err := run(dir, args...)
+	return err
+}
+
+// Rebase replays the branch checked out in dir onto upstream. Conflicts abort
+// the rebase and return a *ConflictError.
+func Rebase(dir, upstream string) error {
+	args := append(identityArgs(dir), "rebase", upstream)
+	if _, err := run(dir, args...); err != nil {
+		files := conflictedFiles(dir)
+		run(dir, "rebase", "--abort")
+		if len(files) > 0 {
+			return &ConflictError{Operation: "rebase", Files: files}
+		}
+		return err
+	}
+	return nil
+}
+
+func conflictedFiles(dir string) []string {
+	output, err := run(dir, "-c", "core.quotePath=false", "diff", "--name-only", "--diff-filter=U")
+	if err != nil || output == "" {
+		return nil
+	}
+	return strings.Split(output, "\n")
+}
@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+const (
+	// actorHeader names who makes a change, e.g. a user name or an agent ID.
+	actorHeader  = "X-Actor"
+	defaultActor = "user"
+)
+
+// requestActor returns the actor recorded in the activity log for changes
+// made by the request.
+func requestActor(c *gin.Context) string {
+	if actor := strings.TrimSpace(c.GetHeader(actorHeader)); actor != "" {
+		return actor
+	}
+	return defaultActor
+}
+
+type ActivityHandler struct {
+	activityService *service.ActivityService
+	logger          *zap.Logger
+}
+
+func NewActivityHandler(activityService *service.ActivityService, logger *zap.Logger) *ActivityHandler {
+	return &ActivityHandler{
+		activityService: activityService,
+		logger:          logger,
+	}
+}
+
+// GetActivity handles GET /api/activity
+// @Summary List activity
+// @Description Get the log of changes to tasks, projects and agents, newest first
+// @Tags activity
+// @Accept json
+// @Produce json
+// @Param entity_type query string false "task, project or agent"
+// @Param entity_id query string false "Entity ID"
+// @Param project_id query string false "Project ID"
+// @Param actor query string false "Actor"
+// @Param action query string false "created, updated or deleted"
+// @Param since query string false "RFC 3339 timestamp or YYYY-MM-DD"
+// @Param until query string false "RFC 3339 timestamp or YYYY-MM-DD"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} model.ActivityListResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /activity [get]
+func (h *ActivityHandler) GetActivity(c *gin.Context) {
+	var query model.ActivityQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	activity, err := h.activityService.GetActivity(&query)
+	h.respond(c, activity, err)
+}
+
+// GetTaskActivity handles GET /api/tasks/:id/activity
+// @Summary List task activity
+// @Description Get the log of changes to a task, newest first. It stays available after the task is deleted.
+// @Tags activity
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param actor query string false "Actor"
+// @Param action query string false "created, updated or deleted"
+// @Param since query string false "RFC 3339 timestamp or YYYY-MM-DD"
+// @Param until query string false "RFC 3339 timestamp or YYYY-MM-DD"
+// @Param limit query int false "Page size (default 50, max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
+// @Success 200 {object} model.ActivityListResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/activity [get]
+func (h *ActivityHandler) GetTaskActivity(c *gin.Context) {
+	var query model.ActivityQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	activity, err := h.activityService.GetTaskActivity(c.Param("id"), &query)
+	h.respond(c, activity, err)
+}
+
+func (h *ActivityHandler) respond(c *gin.Context, activity *model.ActivityListResponse, err error) {
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidActivityQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid query parameters",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		h.logger.Error("Failed to get activity", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get activity",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, activity)
+}
@@ -1,10 +1,12 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+	"gorm.io/gorm"
 
 	"github.com/amoylab/solo-api/internal/model"
 	"github.com/amoylab/solo-api/internal/service"
@@ -47,6 +49,9 @@ func (h *AgentHandler) GetAgent(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, agent.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, agent)
 }
 
@@ -58,13 +63,14 @@ func (h *AgentHandler) CreateAgent(c *gin.Context) {
 		return
 	}
 
-	agent, err := h.agentService.CreateAgent(&req)
+	agent, err := h.agentService.CreateAgent(&req, service.MutationOptions{Actor: requestActor(c)})
 	if err != nil {
 		h.logger.Error("Failed to create agent", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create agent"})
 		return
 	}
 
+	setETag(c, agent.Version)
 	c.JSON(http.StatusCreated, agent)
 }
 
@@ -82,13 +88,59 @@ func (h *AgentHandler) UpdateAgent(c *gin.Context) {
 		return
 	}
 
-	agent, err := h.agentService.UpdateAgent(id, &req)
+	agent, err := h.agentService.UpdateAgent(id, &req, service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+			return
+		}
+		if err == service.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to update agent", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agent"})
+		return
+	}
+
+	setETag(c, agent.Version)
+	c.JSON(http.StatusOK, agent)
+}
+
+func (h *AgentHandler) PatchAgent(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Agent ID is required"})
+		return
+	}
+
+	var req model.PatchAgentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request body"})
+		return
+	}
+
+	agent, err := h.agentService.PatchAgent(id, &req, service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+			return
+		}
+		if err == service.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPatch) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to update agent", zap.Error(err), zap.String("id", id))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update agent"})
 		return
 	}
 
+	setETag(c, agent.Version)
 	c.JSON(http.StatusOK, agent)
 }
 
@@ -99,8 +151,16 @@ func (h *AgentHandler) DeleteAgent(c *gin.Context) {
 		return
 	}
 
-	err := h.agentService.DeleteAgent(id)
+	err := h.agentService.DeleteAgent(id, service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
 	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Agent not found"})
+			return
+		}
+		if err == service.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to delete agent", zap.Error(err), zap.String("id", id))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete agent"})
 		return
@@ -0,0 +1,202 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+type CommentHandler struct {
+	commentService *service.CommentService
+	logger         *zap.Logger
+}
+
+func NewCommentHandler(commentService *service.CommentService, logger *zap.Logger) *CommentHandler {
+	return &CommentHandler{
+		commentService: commentService,
+		logger:         logger,
+	}
+}
+
+// GetComments handles GET /api/tasks/:id/comments
+// @Summary List task comments
+// @Description Get the comment thread of a task, oldest first
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} model.CommentListResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/comments [get]
+func (h *CommentHandler) GetComments(c *gin.Context) {
+	taskID := c.Param("id")
+
+	comments, err := h.commentService.GetComments(taskID)
+	if err != nil {
+		h.handleError(c, err, "Failed to get comments")
+		return
+	}
+
+	c.JSON(http.StatusOK, comments)
+}
+
+// CreateComment handles POST /api/tasks/:id/comments
+// @Summary Add a comment
+// @Description Add a markdown comment to a task, written by a user or an agent
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param comment body model.CreateCommentRequest true "Comment data"
+// @Success 201 {object} model.CommentResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/comments [post]
+func (h *CommentHandler) CreateComment(c *gin.Context) {
+	taskID := c.Param("id")
+
+	var req model.CreateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	comment, err := h.commentService.CreateComment(taskID, &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to create comment")
+		return
+	}
+
+	c.JSON(http.StatusCreated, comment)
+}
+
+// GetComment handles GET /api/tasks/:id/comments/:commentId
+// @Summary Get a comment
+// @Description Get a specific comment of a task
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} model.CommentResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/comments/{commentId} [get]
+func (h *CommentHandler) GetComment(c *gin.Context) {
+	comment, err := h.commentService.GetComment(c.Param("id"), c.Param("commentId"))
+	if err != nil {
+		h.handleError(c, err, "Failed to get comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// UpdateComment handles PUT /api/tasks/:id/comments/:commentId
+// @Summary Edit a comment
+// @Description Replace the body of a comment. The previous body is kept in the edit history.
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param commentId path string true "Comment ID"
+// @Param comment body model.UpdateCommentRequest true "New comment body"
+// @Success 200 {object} model.CommentResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/comments/{commentId} [put]
+func (h *CommentHandler) UpdateComment(c *gin.Context) {
+	var req model.UpdateCommentRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	comment, err := h.commentService.UpdateComment(c.Param("id"), c.Param("commentId"), &req)
+	if err != nil {
+		h.handleError(c, err, "Failed to update comment")
+		return
+	}
+
+	c.JSON(http.StatusOK, comment)
+}
+
+// DeleteComment handles DELETE /api/tasks/:id/comments/:commentId
+// @Summary Delete a comment
+// @Description Delete a comment together with its edit history
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param commentId path string true "Comment ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/comments/{commentId} [delete]
+func (h *CommentHandler) DeleteComment(c *gin.Context) {
+	if err := h.commentService.DeleteComment(c.Param("id"), c.Param("commentId")); err != nil {
+		h.handleError(c, err, "Failed to delete comment")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// GetCommentRevisions handles GET /api/tasks/:id/comments/:commentId/revisions
+// @Summary Get the edit history of a comment
+// @Description Get the earlier bodies of an edited comment, newest first
+// @Tags comments
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param commentId path string true "Comment ID"
+// @Success 200 {object} model.CommentRevisionListResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/comments/{commentId}/revisions [get]
+func (h *CommentHandler) GetCommentRevisions(c *gin.Context) {
+	revisions, err := h.commentService.GetCommentRevisions(c.Param("id"), c.Param("commentId"))
+	if err != nil {
+		h.handleError(c, err, "Failed to get comment revisions")
+		return
+	}
+
+	c.JSON(http.StatusOK, revisions)
+}
+
+func (h *CommentHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Task or comment with the specified ID does not exist",
+		})
+	case service.ErrInvalidCommentAuthor:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"message": err.Error(),
+		})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	}
+}
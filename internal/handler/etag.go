@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// etag formats the version of a task, project or agent as a strong entity
+// tag.
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+func setETag(c *gin.Context, version int64) {
+	c.Header("ETag", etag(version))
+}
+
+// ifMatch reads the versions the If-Match header allows a change to apply
+// to. It returns nil, allowing any version, without the header or for "*".
+// Tags that are not versions match nothing.
+func ifMatch(c *gin.Context) []int64 {
+	header := strings.TrimSpace(c.GetHeader("If-Match"))
+	if header == "" || header == "*" {
+		return nil
+	}
+
+	versions := []int64{}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if len(tag) < 2 || tag[0] != '"' || tag[len(tag)-1] != '"' {
+			continue
+		}
+		if version, err := strconv.ParseInt(tag[1:len(tag)-1], 10, 64); err == nil {
+			versions = append(versions, version)
+		}
+	}
+	return versions
+}
+
+// notModified sets the ETag of a response and answers 304 Not Modified
+// when If-None-Match names the current version.
+func notModified(c *gin.Context, version int64) bool {
+	setETag(c, version)
+
+	header := c.GetHeader("If-None-Match")
+	if header == "" {
+		return false
+	}
+	current := etag(version)
+	for _, tag := range strings.Split(header, ",") {
+		// If-None-Match compares weakly
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || tag == current {
+			c.Status(http.StatusNotModified)
+			return true
+		}
+	}
+	return false
+}
+
+// respondVersionMismatch answers a change made against an outdated version.
+func respondVersionMismatch(c *gin.Context, err error) {
+	c.JSON(http.StatusPreconditionFailed, gin.H{
+		"error":   "Precondition failed",
+		"message": err.Error(),
+	})
+}
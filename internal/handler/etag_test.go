@@ -0,0 +1,70 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/config"
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+func TestTagRecolorChangesTaskETag(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	logger := zap.NewNop()
+	stores := store.NewMemoryStore()
+	bus := events.NewBus(logger)
+	worktrees := service.NewWorktreeService(stores, &config.GitConfig{}, logger)
+	workflows := service.NewWorkflowService(stores, bus, logger)
+	tasks := service.NewTaskService(stores, worktrees, workflows, bus, logger)
+	tags := service.NewTagService(stores, tasks, bus, logger)
+
+	router := gin.New()
+	router.GET("/tasks/:id", NewTaskHandler(tasks, logger).GetTask)
+	router.PUT("/tags/:id", NewTagHandler(tags, logger).UpdateTag)
+	serve := func(method, path, body string, header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header = header
+		req.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w
+	}
+
+	task, err := tasks.CreateTask(&model.CreateTaskRequest{Title: "t", Tags: []string{"bug"}}, service.MutationOptions{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	w := serve(http.MethodGet, "/tasks/"+task.ID, "", http.Header{})
+	tag := w.Header().Get("ETag")
+	if w.Code != http.StatusOK || tag == "" {
+		t.Fatalf("GET task = %d with ETag %q", w.Code, tag)
+	}
+	if w := serve(http.MethodGet, "/tasks/"+task.ID, "", http.Header{"If-None-Match": {tag}}); w.Code != http.StatusNotModified {
+		t.Fatalf("GET unchanged task = %d, want %d", w.Code, http.StatusNotModified)
+	}
+
+	if w := serve(http.MethodPut, "/tags/"+task.TagDetails[0].ID, `{"color":"#ff0000"}`, http.Header{}); w.Code != http.StatusOK {
+		t.Fatalf("PUT tag = %d: %s", w.Code, w.Body)
+	}
+
+	w = serve(http.MethodGet, "/tasks/"+task.ID, "", http.Header{"If-None-Match": {tag}})
+	if w.Code != http.StatusOK {
+		t.Fatalf("GET task after recolor = %d, want %d", w.Code, http.StatusOK)
+	}
+	var got model.TaskResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decode task: %v", err)
+	}
+	if len(got.TagDetails) != 1 || got.TagDetails[0].Color != "#ff0000" {
+		t.Errorf("tags = %+v, want the recolored tag", got.TagDetails)
+	}
+}
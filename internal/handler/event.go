@@ -0,0 +1,72 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/events"
+)
+
+type EventHandler struct {
+	bus    *events.Bus
+	logger *zap.Logger
+}
+
+func NewEventHandler(bus *events.Bus, logger *zap.Logger) *EventHandler {
+	return &EventHandler{
+		bus:    bus,
+		logger: logger,
+	}
+}
+
+// StreamEvents handles GET /api/events
+// @Summary Stream board changes
+// @Description Stream task.*, project.* and agent.* change events as Server-Sent Events.
+// @Description The SSE event name is the event type. Agent events are sent regardless of project_id.
+// @Tags events
+// @Produce text/event-stream
+// @Param project_id query string false "Only send events of this project"
+// @Success 200 {object} events.Event
+// @Router /events [get]
+func (h *EventHandler) StreamEvents(c *gin.Context) {
+	projectID := c.Query("project_id")
+
+	stream, unsubscribe := h.bus.Subscribe(projectID)
+	defer unsubscribe()
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	// Let the client know the subscription is live so it can reload state
+	// it may have missed before connecting.
+	c.Render(-1, sse.Event{Event: "ready", Data: gin.H{"project_id": projectID, "time": time.Now()}})
+	c.Writer.Flush()
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case event, ok := <-stream:
+			if !ok {
+				h.logger.Warn("Event stream dropped", zap.String("project_id", projectID))
+				return false
+			}
+			c.Render(-1, sse.Event{Id: strconv.FormatInt(event.ID, 10), Event: event.Type, Data: event})
+			return true
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
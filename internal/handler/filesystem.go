@@ -9,6 +9,8 @@ import (
 
 	"github.com/gin-gonic/gin"
 	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/git"
 )
 
 type FilesystemHandler struct {
@@ -113,13 +115,7 @@ func (h *FilesystemHandler) ListDirectory(c *gin.Context) {
 		isDirectory := entry.IsDir()
 		
 		// Check if it's a git repository
-		isGitRepo := false
-		if isDirectory {
-			gitPath := filepath.Join(entryPath, ".git")
-			if _, err := os.Stat(gitPath); err == nil {
-				isGitRepo = true
-			}
-		}
+		isGitRepo := isDirectory && git.IsRepository(entryPath)
 
 		directoryEntries = append(directoryEntries, DirectoryEntry{
 			Name:        name,
@@ -200,13 +196,7 @@ func (h *FilesystemHandler) ValidateGitRepository(c *gin.Context) {
 	fileInfo, err := os.Stat(cleanPath)
 	isValidDir := err == nil && fileInfo.IsDir()
 	
-	isGitRepo := false
-	if isValidDir {
-		gitPath := filepath.Join(cleanPath, ".git")
-		if _, err := os.Stat(gitPath); err == nil {
-			isGitRepo = true
-		}
-	}
+	isGitRepo := isValidDir && git.IsRepository(cleanPath)
 	
 	var message string
 	if !isValidDir {
@@ -1,6 +1,7 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -32,19 +33,30 @@ func (h *ProjectHandler) CreateProject(c *gin.Context) {
 		return
 	}
 
-	project, err := h.projectService.CreateProject(&req)
+	project, err := h.projectService.CreateProject(&req, service.MutationOptions{Actor: requestActor(c)})
 	if err != nil {
+		if errors.Is(err, service.ErrAgentNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to create project", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create project"})
 		return
 	}
 
+	setETag(c, project.Version)
 	c.JSON(http.StatusCreated, project)
 }
 
-// GetProjects retrieves all projects
+// GetProjects retrieves all projects; archived ones only with include_archived
 func (h *ProjectHandler) GetProjects(c *gin.Context) {
-	projects, err := h.projectService.GetProjects()
+	var query model.ProjectListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	projects, err := h.projectService.GetProjects(&query)
 	if err != nil {
 		h.logger.Error("Failed to get projects", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to get projects"})
@@ -73,6 +85,9 @@ func (h *ProjectHandler) GetProject(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, project.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, project)
 }
 
@@ -91,21 +106,100 @@ func (h *ProjectHandler) UpdateProject(c *gin.Context) {
 		return
 	}
 
-	project, err := h.projectService.UpdateProject(id, &req)
+	project, err := h.projectService.UpdateProject(id, &req, service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		if err == service.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrAgentNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error("Failed to update project", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
+		return
+	}
+
+	setETag(c, project.Version)
+	c.JSON(http.StatusOK, project)
+}
+
+// PatchProject applies a JSON merge patch to a project; null clears a field
+func (h *ProjectHandler) PatchProject(c *gin.Context) {
+	id := c.Param("id")
+	if id == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Project ID is required"})
+		return
+	}
+
+	var req model.PatchProjectRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	project, err := h.projectService.PatchProject(id, &req, service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 			return
 		}
+		if err == service.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidPatch) || errors.Is(err, service.ErrAgentNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 		h.logger.Error("Failed to update project", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update project"})
 		return
 	}
 
+	setETag(c, project.Version)
 	c.JSON(http.StatusOK, project)
 }
 
-// DeleteProject deletes a project
+// ArchiveProject hides a project from the project list
+func (h *ProjectHandler) ArchiveProject(c *gin.Context) {
+	project, err := h.projectService.ArchiveProject(c.Param("id"), service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
+	h.respondArchiveChange(c, project, err, "Failed to archive project")
+}
+
+// UnarchiveProject brings an archived project back into the project list
+func (h *ProjectHandler) UnarchiveProject(c *gin.Context) {
+	project, err := h.projectService.UnarchiveProject(c.Param("id"), service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
+	h.respondArchiveChange(c, project, err, "Failed to unarchive project")
+}
+
+func (h *ProjectHandler) respondArchiveChange(c *gin.Context, project *model.ProjectResponse, err error, message string) {
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+			return
+		}
+		if err == service.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+		return
+	}
+
+	setETag(c, project.Version)
+	c.JSON(http.StatusOK, project)
+}
+
+// DeleteProject deletes a project; on_delete selects what happens to its
+// tasks: restrict (default), cascade or move to target_project_id
 func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 	id := c.Param("id")
 	if id == "" {
@@ -113,12 +207,39 @@ func (h *ProjectHandler) DeleteProject(c *gin.Context) {
 		return
 	}
 
-	err := h.projectService.DeleteProject(id)
+	var query model.DeleteProjectQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	err := h.projectService.DeleteProject(id, &query, service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
 			return
 		}
+		if err == service.ErrVersionMismatch {
+			c.JSON(http.StatusPreconditionFailed, gin.H{"error": err.Error()})
+			return
+		}
+		if errors.Is(err, service.ErrProjectHasTasks) {
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Project has tasks",
+				"message": err.Error(),
+			})
+			return
+		}
+		if errors.Is(err, service.ErrInvalidProjectDelete) || errors.Is(err, service.ErrProjectNotFound) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid project delete",
+				"message": err.Error(),
+			})
+			return
+		}
+		if respondStatusError(c, err) {
+			return
+		}
 		h.logger.Error("Failed to delete project", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete project"})
 		return
@@ -0,0 +1,211 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/git"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+type ReviewHandler struct {
+	reviewService *service.ReviewService
+	logger        *zap.Logger
+}
+
+func NewReviewHandler(reviewService *service.ReviewService, logger *zap.Logger) *ReviewHandler {
+	return &ReviewHandler{
+		reviewService: reviewService,
+		logger:        logger,
+	}
+}
+
+// GetTaskDiff handles GET /api/tasks/:id/diff
+// @Summary Get the changes on a task branch
+// @Description List files changed on the task branch against its base branch, with added/removed line counts
+// @Tags review
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param include_hunks query bool false "Include the unified hunks of every file"
+// @Success 200 {object} model.TaskDiffResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/diff [get]
+func (h *ReviewHandler) GetTaskDiff(c *gin.Context) {
+	id := c.Param("id")
+	includeHunks := c.Query("include_hunks") == "true"
+
+	diff, err := h.reviewService.GetTaskDiff(id, includeHunks)
+	if err != nil {
+		h.handleError(c, err, "Failed to get task diff")
+		return
+	}
+
+	c.JSON(http.StatusOK, diff)
+}
+
+// GetTaskFileDiff handles GET /api/tasks/:id/diff/file
+// @Summary Get the hunks of one changed file
+// @Description Get the unified diff hunks of a single file on the task branch
+// @Tags review
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param path query string true "File path relative to the repository root"
+// @Success 200 {object} model.DiffFile
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/diff/file [get]
+func (h *ReviewHandler) GetTaskFileDiff(c *gin.Context) {
+	id := c.Param("id")
+	path := c.Query("path")
+	if path == "" {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request",
+			"message": "Path parameter is required",
+		})
+		return
+	}
+
+	file, err := h.reviewService.GetTaskFileDiff(id, path)
+	if err != nil {
+		h.handleError(c, err, "Failed to get file diff")
+		return
+	}
+
+	c.JSON(http.StatusOK, file)
+}
+
+func (h *ReviewHandler) handleError(c *gin.Context, err error, message string) {
+	var conflict *git.ConflictError
+	if errors.As(err, &conflict) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":     message,
+			"message":   err.Error(),
+			"operation": conflict.Operation,
+			"conflicts": conflict.Files,
+		})
+		return
+	}
+
+	switch err {
+	case gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not found",
+			"message": "Task or file with the specified ID does not exist",
+		})
+	case service.ErrInvalidMergeStrategy:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	case service.ErrNoTaskBranch, service.ErrBaseNotCheckedOut, service.ErrProjectDirNotClean, service.ErrRunInProgress:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	default:
+		h.logger.Error(message, zap.Error(err), zap.String("task_id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	}
+}
+
+// MergeTask handles POST /api/tasks/:id/merge
+// @Summary Merge a task branch
+// @Description Merge or squash the task branch into the project's base branch, delete the branch and mark the task done.
+// @Description The project directory must have the base branch checked out with no uncommitted changes.
+// @Tags review
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param request body model.MergeTaskRequest false "Merge options"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Success 200 {object} model.MergeTaskResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/merge [post]
+func (h *ReviewHandler) MergeTask(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.MergeTaskRequest
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&req); err != nil {
+			h.logger.Error("Invalid request body", zap.Error(err))
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid request body",
+				"message": err.Error(),
+			})
+			return
+		}
+	}
+
+	result, err := h.reviewService.MergeTask(id, &req, service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		h.handleError(c, err, "Failed to merge task")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+// RebaseTask handles POST /api/tasks/:id/rebase
+// @Summary Rebase a task branch
+// @Description Rebase the task branch onto the latest base branch
+// @Tags review
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} model.TaskResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/rebase [post]
+func (h *ReviewHandler) RebaseTask(c *gin.Context) {
+	id := c.Param("id")
+
+	task, err := h.reviewService.RebaseTask(id)
+	if err != nil {
+		h.handleError(c, err, "Failed to rebase task")
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// DiscardTask handles POST /api/tasks/:id/discard
+// @Summary Discard a task branch
+// @Description Delete the task worktree and branch, throwing away the agent's changes. The task is kept.
+// @Tags review
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} model.TaskResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/discard [post]
+func (h *ReviewHandler) DiscardTask(c *gin.Context) {
+	id := c.Param("id")
+
+	task, err := h.reviewService.DiscardTask(id)
+	if err != nil {
+		h.handleError(c, err, "Failed to discard task")
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
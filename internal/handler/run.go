@@ -0,0 +1,327 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-contrib/sse"
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/executor"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+// streamKeepAlive is how often an idle event stream sends a comment so
+// proxies do not close the connection.
+const streamKeepAlive = 15 * time.Second
+
+type RunHandler struct {
+	runService *service.RunService
+	logger     *zap.Logger
+}
+
+func NewRunHandler(runService *service.RunService, logger *zap.Logger) *RunHandler {
+	return &RunHandler{
+		runService: runService,
+		logger:     logger,
+	}
+}
+
+// StartRun handles POST /api/tasks/:id/runs
+// @Summary Start an agent run
+// @Description Launch the task's agent (or the project's default agent) in the project directory
+// @Tags runs
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param force query bool false "Start even if the task is blocked by unfinished dependencies"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Success 201 {object} model.RunResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/runs [post]
+func (h *RunHandler) StartRun(c *gin.Context) {
+	taskID := c.Param("id")
+
+	force, _ := strconv.ParseBool(c.Query("force"))
+
+	run, err := h.runService.StartRun(taskID, service.MutationOptions{Force: force, Actor: requestActor(c)})
+	if err != nil {
+		var blocked *service.BlockedError
+		switch {
+		case err == gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Task not found",
+				"message": "Task with the specified ID does not exist",
+			})
+		case err == service.ErrNoAgentAssigned, err == service.ErrNoProjectDir, errors.Is(err, executor.ErrUnknownAgentType):
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Cannot start run",
+				"message": err.Error(),
+			})
+		case err == service.ErrRunInProgress:
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Cannot start run",
+				"message": err.Error(),
+			})
+		case errors.As(err, &blocked):
+			c.JSON(http.StatusConflict, gin.H{
+				"error":      "Cannot start run",
+				"message":    err.Error(),
+				"blocked_by": blocked.BlockedBy,
+			})
+		default:
+			h.logger.Error("Failed to start run", zap.Error(err), zap.String("task_id", taskID))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to start run",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusCreated, run)
+}
+
+// GetRuns handles GET /api/tasks/:id/runs
+// @Summary List agent runs
+// @Description Get all agent runs of a task, newest first
+// @Tags runs
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} model.RunListResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/runs [get]
+func (h *RunHandler) GetRuns(c *gin.Context) {
+	taskID := c.Param("id")
+
+	runs, err := h.runService.GetRuns(taskID)
+	if err != nil {
+		h.logger.Error("Failed to get runs", zap.Error(err), zap.String("task_id", taskID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get runs",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// GetRun handles GET /api/tasks/:id/runs/:runId
+// @Summary Get an agent run
+// @Description Get a specific agent run of a task
+// @Tags runs
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param runId path string true "Run ID"
+// @Success 200 {object} model.RunResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/runs/{runId} [get]
+func (h *RunHandler) GetRun(c *gin.Context) {
+	taskID := c.Param("id")
+	runID := c.Param("runId")
+
+	run, err := h.runService.GetRun(taskID, runID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Run not found",
+				"message": "Run with the specified ID does not exist",
+			})
+			return
+		}
+		h.logger.Error("Failed to get run", zap.Error(err), zap.String("run_id", runID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get run",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, run)
+}
+
+// CancelRun handles POST /api/tasks/:id/runs/:runId/cancel
+// @Summary Cancel an agent run
+// @Description Kill the agent process of a run that is still in progress
+// @Tags runs
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param runId path string true "Run ID"
+// @Success 202 {object} model.RunResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/runs/{runId}/cancel [post]
+func (h *RunHandler) CancelRun(c *gin.Context) {
+	taskID := c.Param("id")
+	runID := c.Param("runId")
+
+	run, err := h.runService.CancelRun(taskID, runID)
+	if err != nil {
+		switch err {
+		case gorm.ErrRecordNotFound:
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Run not found",
+				"message": "Run with the specified ID does not exist",
+			})
+		case service.ErrRunNotActive:
+			c.JSON(http.StatusConflict, gin.H{
+				"error":   "Cannot cancel run",
+				"message": err.Error(),
+			})
+		default:
+			h.logger.Error("Failed to cancel run", zap.Error(err), zap.String("run_id", runID))
+			c.JSON(http.StatusInternalServerError, gin.H{
+				"error":   "Failed to cancel run",
+				"message": err.Error(),
+			})
+		}
+		return
+	}
+
+	c.JSON(http.StatusAccepted, run)
+}
+
+// GetRunLog handles GET /api/tasks/:id/runs/:runId/log
+// @Summary Get agent run output
+// @Description Get the stdout/stderr lines of a run, live while it is still running
+// @Tags runs
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param runId path string true "Run ID"
+// @Success 200 {object} model.RunLogResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/runs/{runId}/log [get]
+func (h *RunHandler) GetRunLog(c *gin.Context) {
+	taskID := c.Param("id")
+	runID := c.Param("runId")
+
+	log, err := h.runService.GetRunLog(taskID, runID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Run not found",
+				"message": "Run with the specified ID does not exist",
+			})
+			return
+		}
+		h.logger.Error("Failed to get run log", zap.Error(err), zap.String("run_id", runID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get run log",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, log)
+}
+
+// StreamRun handles GET /api/tasks/:id/runs/:runId/stream
+// @Summary Stream agent run output
+// @Description Stream stdout/stderr of a run as Server-Sent Events. Buffered output is sent first,
+// @Description then live lines as "log" events until an "end" event carries the final run.
+// @Description Reconnecting clients resume after the Last-Event-ID header.
+// @Tags runs
+// @Produce text/event-stream
+// @Param id path string true "Task ID"
+// @Param runId path string true "Run ID"
+// @Success 200 {object} model.RunLogLine
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/runs/{runId}/stream [get]
+func (h *RunHandler) StreamRun(c *gin.Context) {
+	taskID := c.Param("id")
+	runID := c.Param("runId")
+
+	backlog, lines, stop, err := h.runService.StreamRunLog(taskID, runID)
+	if err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Run not found",
+				"message": "Run with the specified ID does not exist",
+			})
+			return
+		}
+		h.logger.Error("Failed to stream run", zap.Error(err), zap.String("run_id", runID))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to stream run",
+			"message": err.Error(),
+		})
+		return
+	}
+	defer stop()
+
+	lastSeq, _ := strconv.Atoi(c.GetHeader("Last-Event-ID"))
+
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(http.StatusOK)
+
+	for _, line := range backlog {
+		if line.Seq > lastSeq {
+			c.Render(-1, sse.Event{Id: strconv.Itoa(line.Seq), Event: "log", Data: line})
+		}
+	}
+	c.Writer.Flush()
+
+	if lines == nil {
+		h.sendRunEnd(c, taskID, runID)
+		return
+	}
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case line, ok := <-lines:
+			if !ok {
+				h.sendRunEnd(c, taskID, runID)
+				return false
+			}
+			if line.Seq > lastSeq {
+				c.Render(-1, sse.Event{Id: strconv.Itoa(line.Seq), Event: "log", Data: line})
+			}
+			return true
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			return true
+		case <-c.Request.Context().Done():
+			return false
+		}
+	})
+}
+
+// sendRunEnd emits the final state of a run once its output is exhausted. A
+// stream that was dropped while the run is still going ends without it, so
+// the client reconnects and resumes.
+func (h *RunHandler) sendRunEnd(c *gin.Context, taskID, runID string) {
+	run, err := h.runService.GetRun(taskID, runID)
+	if err != nil {
+		h.logger.Error("Failed to get run", zap.Error(err), zap.String("run_id", runID))
+		return
+	}
+	if run.Status == model.RunStatusRunning {
+		return
+	}
+
+	c.Render(-1, sse.Event{Event: "end", Data: run})
+	c.Writer.Flush()
+}
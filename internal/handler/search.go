@@ -0,0 +1,69 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+type SearchHandler struct {
+	searchService *service.SearchService
+	logger        *zap.Logger
+}
+
+func NewSearchHandler(searchService *service.SearchService, logger *zap.Logger) *SearchHandler {
+	return &SearchHandler{
+		searchService: searchService,
+		logger:        logger,
+	}
+}
+
+// Search handles GET /api/search
+// @Summary Search tasks and projects
+// @Description Full-text search over task titles, descriptions and tags and project names and descriptions.
+// @Description Results are ranked by relevance; matched terms are wrapped in <mark></mark>.
+// @Tags search
+// @Produce json
+// @Param q query string true "Search text"
+// @Param type query string false "task or project"
+// @Param project_id query string false "Only search this project"
+// @Param limit query int false "Maximum number of results (max 100)" default(20)
+// @Success 200 {object} model.SearchResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /search [get]
+func (h *SearchHandler) Search(c *gin.Context) {
+	var query model.SearchQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	results, err := h.searchService.Search(&query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidSearch) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid query",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		h.logger.Error("Failed to search", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to search",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
+}
@@ -0,0 +1,228 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+type TagHandler struct {
+	tagService *service.TagService
+	logger     *zap.Logger
+}
+
+func NewTagHandler(tagService *service.TagService, logger *zap.Logger) *TagHandler {
+	return &TagHandler{
+		tagService: tagService,
+		logger:     logger,
+	}
+}
+
+// GetTags handles GET /api/tags
+// @Summary List tags
+// @Description Get all tags by name with the number of tasks carrying each
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.TagListResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /tags [get]
+func (h *TagHandler) GetTags(c *gin.Context) {
+	tags, err := h.tagService.GetTags()
+	if err != nil {
+		h.handleError(c, err, "Failed to get tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
+// GetTag handles GET /api/tags/:id
+// @Summary Get a tag
+// @Description Get a tag with the number of tasks carrying it
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 200 {object} model.TagResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tags/{id} [get]
+func (h *TagHandler) GetTag(c *gin.Context) {
+	tag, err := h.tagService.GetTag(c.Param("id"))
+	if err != nil {
+		h.handleError(c, err, "Failed to get tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// CreateTag handles POST /api/tags
+// @Summary Create a tag
+// @Description Create a tag ahead of using it on tasks
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param tag body model.CreateTagRequest true "Tag data"
+// @Success 201 {object} model.TagResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tags [post]
+func (h *TagHandler) CreateTag(c *gin.Context) {
+	var req model.CreateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tag, err := h.tagService.CreateTag(&req)
+	if err != nil {
+		h.handleError(c, err, "Failed to create tag")
+		return
+	}
+
+	c.JSON(http.StatusCreated, tag)
+}
+
+// UpdateTag handles PUT /api/tags/:id
+// @Summary Update a tag
+// @Description Rename or recolor a tag. The change applies to every task carrying it.
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Param tag body model.UpdateTagRequest true "Tag data"
+// @Success 200 {object} model.TagResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tags/{id} [put]
+func (h *TagHandler) UpdateTag(c *gin.Context) {
+	var req model.UpdateTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tag, err := h.tagService.UpdateTag(c.Param("id"), &req, service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		h.handleError(c, err, "Failed to update tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// DeleteTag handles DELETE /api/tags/:id
+// @Summary Delete a tag
+// @Description Remove a tag from every task carrying it and delete it
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "Tag ID"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tags/{id} [delete]
+func (h *TagHandler) DeleteTag(c *gin.Context) {
+	if err := h.tagService.DeleteTag(c.Param("id"), service.MutationOptions{Actor: requestActor(c)}); err != nil {
+		h.handleError(c, err, "Failed to delete tag")
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// MergeTag handles POST /api/tags/:id/merge
+// @Summary Merge a tag into another
+// @Description Move every task carrying the tag over to the target tag and delete the merged tag
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Param id path string true "ID of the tag to merge"
+// @Param merge body model.MergeTagRequest true "Tag to merge into"
+// @Success 200 {object} model.TagResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tags/{id}/merge [post]
+func (h *TagHandler) MergeTag(c *gin.Context) {
+	var req model.MergeTagRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tag, err := h.tagService.MergeTag(c.Param("id"), &req, service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		h.handleError(c, err, "Failed to merge tag")
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// CollectGarbage handles POST /api/tags/gc
+// @Summary Delete unused tags
+// @Description Delete every tag no task carries anymore
+// @Tags tags
+// @Accept json
+// @Produce json
+// @Success 200 {object} model.TagGCResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /tags/gc [post]
+func (h *TagHandler) CollectGarbage(c *gin.Context) {
+	result, err := h.tagService.CollectGarbage()
+	if err != nil {
+		h.handleError(c, err, "Failed to delete unused tags")
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+func (h *TagHandler) handleError(c *gin.Context, err error, message string) {
+	switch err {
+	case gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Tag not found",
+			"message": "Tag with the specified ID does not exist",
+		})
+	case service.ErrTagExists:
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	case service.ErrInvalidTagName, service.ErrTagMergeSelf:
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"message": err.Error(),
+		})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	}
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
+	"strconv"
 
 	"github.com/amoylab/solo-api/internal/model"
 	"github.com/amoylab/solo-api/internal/service"
@@ -29,6 +31,7 @@ func NewTaskHandler(taskService *service.TaskService, logger *zap.Logger) *TaskH
 // @Accept json
 // @Produce json
 // @Param task body model.CreateTaskRequest true "Task creation request"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
 // @Success 201 {object} model.TaskResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -53,8 +56,12 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.CreateTask(&req)
+	task, err := h.taskService.CreateTask(&req, service.MutationOptions{Actor: requestActor(c)})
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
 		h.logger.Error("Failed to create task", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to create task",
@@ -63,6 +70,7 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 		return
 	}
 
+	setETag(c, task.Version)
 	c.JSON(http.StatusCreated, task)
 }
 
@@ -73,7 +81,9 @@ func (h *TaskHandler) CreateTask(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID"
+// @Param If-None-Match header string false "ETag of the version the client has; answers 304 while it is current"
 // @Success 200 {object} model.TaskResponse
+// @Success 304 "Not modified"
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
@@ -106,21 +116,56 @@ func (h *TaskHandler) GetTask(c *gin.Context) {
 		return
 	}
 
+	if notModified(c, task.Version) {
+		return
+	}
 	c.JSON(http.StatusOK, task)
 }
 
 // GetTasks handles GET /api/tasks
 // @Summary Get all tasks
-// @Description Get a list of all tasks
+// @Description Get a list of tasks, optionally filtered, sorted and paginated.
+// @Description Without a limit every matching task is returned.
 // @Tags tasks
 // @Accept json
 // @Produce json
+// @Param project_id query string false "Project ID"
+// @Param parent_id query string false "Only subtasks of this task"
+// @Param status query []string false "Statuses (repeated or comma separated)"
+// @Param tag query []string false "Tags the task must all carry (repeated or comma separated)"
+// @Param agent_id query string false "Agent ID"
+// @Param assignee query string false "Assignee"
+// @Param q query string false "Text to search in title and description"
+// @Param created_after query string false "Created at or after (RFC 3339 or YYYY-MM-DD)"
+// @Param created_before query string false "Created before (RFC 3339 or YYYY-MM-DD)"
+// @Param include_archived query bool false "Also list archived tasks"
+// @Param sort query string false "created_at, updated_at, title, status or assignee; prefix with - for descending" default(created_at)
+// @Param limit query int false "Page size (max 500)"
+// @Param cursor query string false "next_cursor of the previous page"
 // @Success 200 {object} model.TaskListResponse
+// @Failure 400 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /tasks [get]
 func (h *TaskHandler) GetTasks(c *gin.Context) {
-	tasks, err := h.taskService.GetTasks()
+	var query model.TaskListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tasks, err := h.taskService.GetTasks(&query)
 	if err != nil {
+		if errors.Is(err, service.ErrInvalidTaskQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid query",
+				"message": err.Error(),
+			})
+			return
+		}
+
 		h.logger.Error("Failed to get tasks", zap.Error(err))
 		c.JSON(http.StatusInternalServerError, gin.H{
 			"error":   "Failed to get tasks",
@@ -140,9 +185,15 @@ func (h *TaskHandler) GetTasks(c *gin.Context) {
 // @Produce json
 // @Param id path string true "Task ID"
 // @Param task body model.UpdateTaskRequest true "Task update request"
+// @Param force query bool false "Move the task into inprogress even if it is blocked by unfinished dependencies"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Param If-Match header string false "ETag of the version the change applies to; other versions fail with 412"
 // @Success 200 {object} model.TaskResponse
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /tasks/{id} [put]
 func (h *TaskHandler) UpdateTask(c *gin.Context) {
@@ -165,8 +216,14 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
-	task, err := h.taskService.UpdateTask(id, &req)
+	force, _ := strconv.ParseBool(c.Query("force"))
+
+	task, err := h.taskService.UpdateTask(id, &req, service.MutationOptions{Force: force, Actor: requestActor(c), IfMatch: ifMatch(c)})
 	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
 				"error":   "Task not found",
@@ -191,9 +248,244 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 		return
 	}
 
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
+}
+
+// PatchTask handles PATCH /api/tasks/:id
+// @Summary Patch a task
+// @Description Apply a JSON merge patch (RFC 7396) to a task: absent fields are left untouched and null clears a field
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param task body model.PatchTaskRequest true "Merge patch"
+// @Param force query bool false "Move the task into inprogress even if it is blocked by unfinished dependencies"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Param If-Match header string false "ETag of the version the change applies to; other versions fail with 412"
+// @Success 200 {object} model.TaskResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id} [patch]
+func (h *TaskHandler) PatchTask(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.PatchTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	force, _ := strconv.ParseBool(c.Query("force"))
+
+	task, err := h.taskService.PatchTask(id, &req, service.MutationOptions{Force: force, Actor: requestActor(c), IfMatch: ifMatch(c)})
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to update task", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to update task",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "Task with the specified ID does not exist",
+		})
+		return
+	}
+
+	setETag(c, task.Version)
 	c.JSON(http.StatusOK, task)
 }
 
+// MoveTask handles POST /api/tasks/:id/move
+// @Summary Move a task on the board
+// @Description Place a task between two tasks of a column, optionally changing its status. Without neighbors the task goes to the end of the column.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param move body model.MoveTaskRequest true "Target status and neighbors"
+// @Param force query bool false "Move the task into inprogress even if it is blocked by unfinished dependencies"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Success 200 {object} model.TaskResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 422 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/move [post]
+func (h *TaskHandler) MoveTask(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.MoveTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	force, _ := strconv.ParseBool(c.Query("force"))
+
+	task, err := h.taskService.MoveTask(id, &req, service.MutationOptions{Force: force, Actor: requestActor(c)})
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to move task", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to move task",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "Task with the specified ID does not exist",
+		})
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
+}
+
+// ArchiveTask handles POST /api/tasks/:id/archive
+// @Summary Archive a task
+// @Description Hide a task from task lists without deleting it; list with include_archived=true to see it
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Param If-Match header string false "ETag of the version the change applies to; other versions fail with 412"
+// @Success 200 {object} model.TaskResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/archive [post]
+func (h *TaskHandler) ArchiveTask(c *gin.Context) {
+	task, err := h.taskService.ArchiveTask(c.Param("id"), service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
+	h.respondArchiveChange(c, task, err, "Failed to archive task")
+}
+
+// UnarchiveTask handles POST /api/tasks/:id/unarchive
+// @Summary Unarchive a task
+// @Description Bring an archived task back into task lists
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Param If-Match header string false "ETag of the version the change applies to; other versions fail with 412"
+// @Success 200 {object} model.TaskResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/unarchive [post]
+func (h *TaskHandler) UnarchiveTask(c *gin.Context) {
+	task, err := h.taskService.UnarchiveTask(c.Param("id"), service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
+	h.respondArchiveChange(c, task, err, "Failed to unarchive task")
+}
+
+func (h *TaskHandler) respondArchiveChange(c *gin.Context, task *model.TaskResponse, err error, message string) {
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
+		h.logger.Error(message, zap.Error(err), zap.String("id", c.Param("id")))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "Task with the specified ID does not exist",
+		})
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusOK, task)
+}
+
+// BulkUpdateTasks handles POST /api/tasks/bulk
+// @Summary Apply an operation to many tasks
+// @Description Change the status, add or remove tags, assign an agent, move to a project or delete a list of tasks in one transaction. In atomic mode (default) nothing is applied when any task fails and the response is 422; in best_effort mode the other tasks are applied.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param bulk body model.BulkTaskRequest true "Operation and tasks"
+// @Param force query bool false "Move blocked tasks into inprogress even if they are blocked by unfinished dependencies"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Success 200 {object} model.BulkTaskResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 422 {object} model.BulkTaskResponse
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/bulk [post]
+func (h *TaskHandler) BulkUpdateTasks(c *gin.Context) {
+	var req model.BulkTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	force, _ := strconv.ParseBool(c.Query("force"))
+
+	response, err := h.taskService.BulkUpdateTasks(&req, service.MutationOptions{Force: force, Actor: requestActor(c)})
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidBulkRequest) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Validation failed",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		h.logger.Error("Failed to apply bulk operation", zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to apply bulk operation",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if response.Mode == model.BulkModeAtomic && response.Failed > 0 {
+		c.JSON(http.StatusUnprocessableEntity, response)
+		return
+	}
+	c.JSON(http.StatusOK, response)
+}
+
 // DeleteTask handles DELETE /api/tasks/:id
 // @Summary Delete a task
 // @Description Delete a task by its ID
@@ -201,9 +493,12 @@ func (h *TaskHandler) UpdateTask(c *gin.Context) {
 // @Accept json
 // @Produce json
 // @Param id path string true "Task ID"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Param If-Match header string false "ETag of the version the change applies to; other versions fail with 412"
 // @Success 204
 // @Failure 400 {object} map[string]interface{}
 // @Failure 404 {object} map[string]interface{}
+// @Failure 412 {object} map[string]interface{}
 // @Failure 500 {object} map[string]interface{}
 // @Router /tasks/{id} [delete]
 func (h *TaskHandler) DeleteTask(c *gin.Context) {
@@ -216,7 +511,7 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 		return
 	}
 
-	err := h.taskService.DeleteTask(id)
+	err := h.taskService.DeleteTask(id, service.MutationOptions{Actor: requestActor(c), IfMatch: ifMatch(c)})
 	if err != nil {
 		if err == gorm.ErrRecordNotFound {
 			c.JSON(http.StatusNotFound, gin.H{
@@ -225,6 +520,10 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 			})
 			return
 		}
+		if err == service.ErrVersionMismatch {
+			respondVersionMismatch(c, err)
+			return
+		}
 
 		h.logger.Error("Failed to delete task", zap.Error(err), zap.String("id", id))
 		c.JSON(http.StatusInternalServerError, gin.H{
@@ -236,3 +535,269 @@ func (h *TaskHandler) DeleteTask(c *gin.Context) {
 
 	c.JSON(http.StatusNoContent, nil)
 }
+
+// GetSubtasks handles GET /api/tasks/:id/subtasks
+// @Summary Get the subtasks of a task
+// @Description Get the direct subtasks of a task. Accepts the filter, sort and pagination parameters of GET /api/tasks.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} model.TaskListResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/subtasks [get]
+func (h *TaskHandler) GetSubtasks(c *gin.Context) {
+	id := c.Param("id")
+
+	var query model.TaskListQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	tasks, err := h.taskService.GetSubtasks(id, &query)
+	if err != nil {
+		if errors.Is(err, service.ErrInvalidTaskQuery) {
+			c.JSON(http.StatusBadRequest, gin.H{
+				"error":   "Invalid query",
+				"message": err.Error(),
+			})
+			return
+		}
+
+		h.logger.Error("Failed to get subtasks", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get subtasks",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if tasks == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "Task with the specified ID does not exist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// CreateSubtask handles POST /api/tasks/:id/subtasks
+// @Summary Create a subtask
+// @Description Create a task under the given parent task. The subtask joins the parent's project unless project_id is set.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Parent task ID"
+// @Param task body model.CreateTaskRequest true "Task creation request"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Success 201 {object} model.TaskResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/subtasks [post]
+func (h *TaskHandler) CreateSubtask(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.CreateTaskRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	task, err := h.taskService.CreateSubtask(id, &req, service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to create subtask", zap.Error(err), zap.String("parent_id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to create subtask",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "Task with the specified ID does not exist",
+		})
+		return
+	}
+
+	setETag(c, task.Version)
+	c.JSON(http.StatusCreated, task)
+}
+
+// GetDependencies handles GET /api/tasks/:id/dependencies
+// @Summary Get the dependencies of a task
+// @Description Get the tasks that must be finished before the task can start
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Success 200 {object} model.TaskListResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/dependencies [get]
+func (h *TaskHandler) GetDependencies(c *gin.Context) {
+	id := c.Param("id")
+
+	tasks, err := h.taskService.GetDependencies(id)
+	if err != nil {
+		h.logger.Error("Failed to get dependencies", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to get dependencies",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if tasks == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "Task with the specified ID does not exist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, tasks)
+}
+
+// AddDependency handles POST /api/tasks/:id/dependencies
+// @Summary Add a dependency
+// @Description Make the task wait for another task. Dependencies that would form a cycle are rejected.
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param dependency body model.AddDependencyRequest true "Task to depend on"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Success 200 {object} model.TaskResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/dependencies [post]
+func (h *TaskHandler) AddDependency(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.AddDependencyRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request body", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid request body",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	task, err := h.taskService.AddDependency(id, &req, service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		if respondValidationError(c, err) {
+			return
+		}
+
+		h.logger.Error("Failed to add dependency", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to add dependency",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	if task == nil {
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Task not found",
+			"message": "Task with the specified ID does not exist",
+		})
+		return
+	}
+
+	c.JSON(http.StatusOK, task)
+}
+
+// RemoveDependency handles DELETE /api/tasks/:id/dependencies/:dependsOnId
+// @Summary Remove a dependency
+// @Description Stop the task from waiting for another task
+// @Tags tasks
+// @Accept json
+// @Produce json
+// @Param id path string true "Task ID"
+// @Param dependsOnId path string true "ID of the task depended on"
+// @Param X-Actor header string false "Who makes the change, recorded in the activity log (default user)"
+// @Success 204
+// @Failure 404 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /tasks/{id}/dependencies/{dependsOnId} [delete]
+func (h *TaskHandler) RemoveDependency(c *gin.Context) {
+	id := c.Param("id")
+	dependsOnID := c.Param("dependsOnId")
+
+	if err := h.taskService.RemoveDependency(id, dependsOnID, service.MutationOptions{Actor: requestActor(c)}); err != nil {
+		if err == gorm.ErrRecordNotFound {
+			c.JSON(http.StatusNotFound, gin.H{
+				"error":   "Dependency not found",
+				"message": "The task does not depend on the specified task",
+			})
+			return
+		}
+
+		h.logger.Error("Failed to remove dependency", zap.Error(err), zap.String("id", id))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   "Failed to remove dependency",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	c.JSON(http.StatusNoContent, nil)
+}
+
+// respondValidationError writes the response for task changes the service
+// rejected as invalid and reports whether err was such a rejection.
+func respondValidationError(c *gin.Context, err error) bool {
+	if respondStatusError(c, err) {
+		return true
+	}
+
+	if errors.Is(err, service.ErrParentNotFound) || errors.Is(err, service.ErrTaskCycle) ||
+		errors.Is(err, service.ErrDependencyNotFound) || errors.Is(err, service.ErrDependencyCycle) ||
+		errors.Is(err, service.ErrInvalidMove) || errors.Is(err, service.ErrInvalidPatch) ||
+		errors.Is(err, service.ErrProjectNotFound) || errors.Is(err, service.ErrAgentNotFound) {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Validation failed",
+			"message": err.Error(),
+		})
+		return true
+	}
+
+	if errors.Is(err, service.ErrVersionMismatch) {
+		respondVersionMismatch(c, err)
+		return true
+	}
+
+	var blocked *service.BlockedError
+	if errors.As(err, &blocked) {
+		c.JSON(http.StatusConflict, gin.H{
+			"error":      "Task is blocked",
+			"message":    err.Error(),
+			"blocked_by": blocked.BlockedBy,
+		})
+		return true
+	}
+
+	return false
+}
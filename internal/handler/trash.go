@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+type TrashHandler struct {
+	trashService *service.TrashService
+	logger       *zap.Logger
+}
+
+func NewTrashHandler(trashService *service.TrashService, logger *zap.Logger) *TrashHandler {
+	return &TrashHandler{
+		trashService: trashService,
+		logger:       logger,
+	}
+}
+
+// GetTrash handles GET /api/trash
+// @Summary List the trash
+// @Description Get the trashed tasks and projects, most recently deleted first. Subtasks and tasks
+// @Description trashed along with their parent or project are counted with it instead of listed.
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param type query string false "Only list tasks or projects" Enums(task, project)
+// @Success 200 {object} model.TrashListResponse
+// @Failure 400 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /trash [get]
+func (h *TrashHandler) GetTrash(c *gin.Context) {
+	var query model.TrashQuery
+	if err := c.ShouldBindQuery(&query); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{
+			"error":   "Invalid query parameters",
+			"message": err.Error(),
+		})
+		return
+	}
+
+	trash, err := h.trashService.GetTrash(&query)
+	if err != nil {
+		h.handleError(c, err, "Failed to get trash")
+		return
+	}
+
+	c.JSON(http.StatusOK, trash)
+}
+
+// Restore handles POST /api/trash/:id/restore
+// @Summary Restore a trashed task or project
+// @Description Bring a task or project back from the trash together with everything deleted along with it.
+// @Description A task whose parent task or project is still in the trash cannot be restored on its own.
+// @Tags trash
+// @Accept json
+// @Produce json
+// @Param id path string true "Task or project ID"
+// @Success 200 {object} model.TrashRestoreResponse
+// @Failure 404 {object} map[string]interface{}
+// @Failure 409 {object} map[string]interface{}
+// @Failure 500 {object} map[string]interface{}
+// @Router /trash/{id}/restore [post]
+func (h *TrashHandler) Restore(c *gin.Context) {
+	restored, err := h.trashService.Restore(c.Param("id"), service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		h.handleError(c, err, "Failed to restore")
+		return
+	}
+
+	c.JSON(http.StatusOK, restored)
+}
+
+func (h *TrashHandler) handleError(c *gin.Context, err error, message string) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		c.JSON(http.StatusNotFound, gin.H{
+			"error":   "Not in trash",
+			"message": "No task or project with the specified ID is in the trash",
+		})
+	case errors.Is(err, service.ErrRestoreBlocked):
+		c.JSON(http.StatusConflict, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{
+			"error":   message,
+			"message": err.Error(),
+		})
+	}
+}
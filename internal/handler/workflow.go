@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/service"
+)
+
+type WorkflowHandler struct {
+	workflowService *service.WorkflowService
+	logger          *zap.Logger
+}
+
+func NewWorkflowHandler(workflowService *service.WorkflowService, logger *zap.Logger) *WorkflowHandler {
+	return &WorkflowHandler{
+		workflowService: workflowService,
+		logger:          logger,
+	}
+}
+
+// GetWorkflow retrieves the status workflow of a project
+func (h *WorkflowHandler) GetWorkflow(c *gin.Context) {
+	id := c.Param("id")
+
+	workflow, err := h.workflowService.GetWorkflow(id)
+	if err != nil {
+		h.handleError(c, err, "Failed to get workflow")
+		return
+	}
+
+	c.JSON(http.StatusOK, workflow)
+}
+
+// UpdateWorkflow replaces the status workflow of a project
+func (h *WorkflowHandler) UpdateWorkflow(c *gin.Context) {
+	id := c.Param("id")
+
+	var req model.UpdateWorkflowRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		h.logger.Error("Invalid request", zap.Error(err))
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	workflow, err := h.workflowService.UpdateWorkflow(id, &req, service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		h.handleError(c, err, "Failed to update workflow")
+		return
+	}
+
+	c.JSON(http.StatusOK, workflow)
+}
+
+// ResetWorkflow switches a project back to the default workflow
+func (h *WorkflowHandler) ResetWorkflow(c *gin.Context) {
+	id := c.Param("id")
+
+	workflow, err := h.workflowService.ResetWorkflow(id, service.MutationOptions{Actor: requestActor(c)})
+	if err != nil {
+		h.handleError(c, err, "Failed to reset workflow")
+		return
+	}
+
+	c.JSON(http.StatusOK, workflow)
+}
+
+func (h *WorkflowHandler) handleError(c *gin.Context, err error, message string) {
+	var workflowErr *service.WorkflowError
+	switch {
+	case err == gorm.ErrRecordNotFound:
+		c.JSON(http.StatusNotFound, gin.H{"error": "Project not found"})
+	case errors.As(err, &workflowErr) && workflowErr.InUse != nil:
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error(), "in_use": workflowErr.InUse})
+	case errors.As(err, &workflowErr):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	default:
+		h.logger.Error(message, zap.Error(err))
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// respondStatusError writes the response for a task status rejected by the
+// project's workflow and reports whether err was such an error. Unknown
+// statuses are bad requests; known statuses the task cannot move to are
+// unprocessable.
+func respondStatusError(c *gin.Context, err error) bool {
+	var statusErr *service.StatusError
+	if !errors.As(err, &statusErr) {
+		return false
+	}
+
+	code := http.StatusBadRequest
+	if statusErr.IsTransition() {
+		code = http.StatusUnprocessableEntity
+	}
+	c.JSON(code, gin.H{
+		"error":   "Validation failed",
+		"message": err.Error(),
+		"allowed": statusErr.Allowed,
+	})
+	return true
+}
@@ -0,0 +1,56 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	ActivityCreated  = "created"
+	ActivityUpdated  = "updated"
+	ActivityDeleted  = "deleted"
+	ActivityRestored = "restored" // Brought back from the trash
+	ActivityPurged   = "purged"   // Removed from the trash for good
+
+	EntityTask    = "task"
+	EntityProject = "project"
+	EntityAgent   = "agent"
+)
+
+// FieldChange is the value of a field before and after a change. Before is
+// null for created entities and After for deleted ones.
+type FieldChange struct {
+	Field  string      `json:"field"`
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+type ActivityResponse struct {
+	ID         int64         `json:"id"`
+	EntityType string        `json:"entity_type"`
+	EntityID   string        `json:"entity_id"`
+	ProjectID  string        `json:"project_id,omitempty"`
+	Action     string        `json:"action"`
+	Actor      string        `json:"actor"`
+	Changes    []FieldChange `json:"changes"`
+	CreatedAt  time.Time     `json:"created_at"`
+}
+
+// ActivityQuery filters and pages GET /api/activity. Entries are listed
+// newest first.
+type ActivityQuery struct {
+	EntityType string `form:"entity_type"`
+	EntityID   string `form:"entity_id"`
+	ProjectID  string `form:"project_id"`
+	Actor      string `form:"actor"`
+	Action     string `form:"action"`
+	Since      string `form:"since"` // RFC 3339 timestamp or YYYY-MM-DD
+	Until      string `form:"until"` // RFC 3339 timestamp or YYYY-MM-DD
+	Limit      int    `form:"limit"`
+	Cursor     string `form:"cursor"`
+}
+
+type ActivityListResponse struct {
+	Activity   []ActivityResponse `json:"activity"`
+	Total      int64              `json:"total"`
+	NextCursor string             `json:"next_cursor,omitempty"`
+}
@@ -9,6 +9,7 @@ type Agent struct {
 	Name        string    `json:"name"`
 	Type        string    `json:"type"`
 	Description string    `json:"description"`
+	Command     string    `json:"command"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
@@ -17,12 +18,34 @@ type CreateAgentRequest struct {
 	Name        string `json:"name" binding:"required"`
 	Type        string `json:"type" binding:"required"`
 	Description string `json:"description"`
+	Command     string `json:"command"`
 }
 
 type UpdateAgentRequest struct {
 	Name        string `json:"name"`
 	Type        string `json:"type"`
 	Description string `json:"description"`
+	Command     string `json:"command"`
+}
+
+// PatchAgentRequest is a JSON merge patch of an agent. Null clears
+// description and command; name and type cannot be cleared.
+type PatchAgentRequest struct {
+	Name        Patch[string] `json:"name" swaggertype:"string"`
+	Type        Patch[string] `json:"type" swaggertype:"string"`
+	Description Patch[string] `json:"description" swaggertype:"string"`
+	Command     Patch[string] `json:"command" swaggertype:"string"`
+}
+
+// Patch expresses a full update as a merge patch. Empty fields are left
+// untouched.
+func (r *UpdateAgentRequest) Patch() *PatchAgentRequest {
+	patch := &PatchAgentRequest{}
+	setIfNotEmpty(&patch.Name, r.Name)
+	setIfNotEmpty(&patch.Type, r.Type)
+	setIfNotEmpty(&patch.Description, r.Description)
+	setIfNotEmpty(&patch.Command, r.Command)
+	return patch
 }
 
 type AgentResponse struct {
@@ -30,6 +53,8 @@ type AgentResponse struct {
 	Name        string    `json:"name"`
 	Type        string    `json:"type"`
 	Description string    `json:"description"`
+	Command     string    `json:"command"`
+	Version     int64     `json:"version"`
 	CreatedAt   time.Time `json:"created_at"`
 	UpdatedAt   time.Time `json:"updated_at"`
 }
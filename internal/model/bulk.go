@@ -0,0 +1,51 @@
+package model
+
+const (
+	BulkOpStatus      = "status"
+	BulkOpAddTags     = "add_tags"
+	BulkOpRemoveTags  = "remove_tags"
+	BulkOpAssignAgent = "assign_agent"
+	BulkOpMoveProject = "move_project"
+	BulkOpDelete      = "delete"
+)
+
+const (
+	// BulkModeAtomic applies every task or none of them
+	BulkModeAtomic = "atomic"
+	// BulkModeBestEffort applies every task that can be, skipping failures
+	BulkModeBestEffort = "best_effort"
+)
+
+// Outcomes of the tasks in a bulk operation.
+const (
+	BulkResultApplied    = "applied"
+	BulkResultFailed     = "failed"
+	BulkResultRolledBack = "rolled_back" // Would have applied, but another task of an atomic operation failed
+)
+
+// BulkTaskRequest applies one operation to a list of tasks. Which of the
+// operation fields are required depends on the operation.
+type BulkTaskRequest struct {
+	TaskIDs   []string `json:"task_ids" binding:"required,min=1,max=500,unique,dive,required"`
+	Operation string   `json:"operation" binding:"required,oneof=status add_tags remove_tags assign_agent move_project delete"`
+	Mode      string   `json:"mode" binding:"omitempty,oneof=atomic best_effort"` // Defaults to atomic
+	Status    string   `json:"status"`                                            // For status
+	Tags      []string `json:"tags"`                                              // For add_tags and remove_tags
+	AgentID   *string  `json:"agent_id"`                                          // For assign_agent; empty unassigns
+	ProjectID string   `json:"project_id"`                                        // For move_project
+}
+
+type BulkTaskResult struct {
+	ID     string        `json:"id"`
+	Result string        `json:"result"`
+	Error  string        `json:"error,omitempty"`
+	Task   *TaskResponse `json:"task,omitempty"` // The updated task; not set for deletes
+}
+
+type BulkTaskResponse struct {
+	Operation string           `json:"operation"`
+	Mode      string           `json:"mode"`
+	Applied   int              `json:"applied"`
+	Failed    int              `json:"failed"`
+	Results   []BulkTaskResult `json:"results"` // In the order of the request
+}
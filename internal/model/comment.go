@@ -0,0 +1,51 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	CommentAuthorUser  = "user"
+	CommentAuthorAgent = "agent"
+)
+
+type CommentResponse struct {
+	ID         string     `json:"id"`
+	TaskID     string     `json:"task_id"`
+	AuthorType string     `json:"author_type"`
+	AuthorID   string     `json:"author_id"`
+	Body       string     `json:"body"` // Markdown
+	EditedAt   *time.Time `json:"edited_at,omitempty"`
+	Revisions  int64      `json:"revisions"` // Number of earlier bodies kept in the edit history
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type CommentListResponse struct {
+	Comments []CommentResponse `json:"comments"`
+	Total    int64             `json:"total"`
+}
+
+// CreateCommentRequest bodies are markdown of at most 64 KiB.
+type CreateCommentRequest struct {
+	Body       string `json:"body" binding:"required,max=65536"`
+	AuthorType string `json:"author_type" binding:"omitempty,oneof=user agent"` // Defaults to user
+	AuthorID   string `json:"author_id"`                                        // Required for agent comments
+}
+
+type UpdateCommentRequest struct {
+	Body string `json:"body" binding:"required,max=65536"`
+}
+
+// CommentRevision is an earlier body of an edited comment.
+type CommentRevision struct {
+	ID         string    `json:"id"`
+	Body       string    `json:"body"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+type CommentRevisionListResponse struct {
+	CommentID string            `json:"comment_id"`
+	Revisions []CommentRevision `json:"revisions"`
+	Total     int64             `json:"total"`
+}
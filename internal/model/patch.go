@@ -0,0 +1,45 @@
+package model
+
+import (
+	"encoding/json"
+)
+
+// Patch is a field of a JSON merge patch (RFC 7396). A field absent from
+// the document is left untouched, null clears it and any other value
+// replaces it.
+type Patch[T any] struct {
+	Set   bool // Present in the document
+	Null  bool // Present as null; Value holds the zero value
+	Value T
+}
+
+// PatchValue returns a patch field replacing the value.
+func PatchValue[T any](value T) Patch[T] {
+	return Patch[T]{Set: true, Value: value}
+}
+
+// PatchNull returns a patch field clearing the value.
+func PatchNull[T any]() Patch[T] {
+	return Patch[T]{Set: true, Null: true}
+}
+
+// UnmarshalJSON is only called for fields present in the document,
+// including null ones.
+func (p *Patch[T]) UnmarshalJSON(data []byte) error {
+	p.Set = true
+	if string(data) == "null" {
+		var zero T
+		p.Null = true
+		p.Value = zero
+		return nil
+	}
+	p.Null = false
+	return json.Unmarshal(data, &p.Value)
+}
+
+func (p Patch[T]) MarshalJSON() ([]byte, error) {
+	if !p.Set || p.Null {
+		return []byte("null"), nil
+	}
+	return json.Marshal(p.Value)
+}
@@ -9,6 +9,7 @@ type Project struct {
 	Name        string    `json:"name"`
 	Description string    `json:"description"`
 	Directory   string    `json:"directory"`
+	BaseBranch  string    `json:"base_branch,omitempty"`
 	AgentID     *string   `json:"agent_id,omitempty"`
 	Agent       *Agent    `json:"agent,omitempty"`
 	CreatedAt   time.Time `json:"created_at"`
@@ -19,6 +20,7 @@ type CreateProjectRequest struct {
 	Name        string  `json:"name" binding:"required"`
 	Description string  `json:"description"`
 	Directory   string  `json:"directory" binding:"required"`
+	BaseBranch  string  `json:"base_branch"`
 	AgentID     *string `json:"agent_id,omitempty"`
 }
 
@@ -26,18 +28,67 @@ type UpdateProjectRequest struct {
 	Name        string  `json:"name"`
 	Description string  `json:"description"`
 	Directory   string  `json:"directory"`
+	BaseBranch  string  `json:"base_branch"`
 	AgentID     *string `json:"agent_id,omitempty"`
 }
 
+// PatchProjectRequest is a JSON merge patch of a project. Null clears
+// description, base_branch and agent_id; name and directory cannot be
+// cleared.
+type PatchProjectRequest struct {
+	Name        Patch[string] `json:"name" swaggertype:"string"`
+	Description Patch[string] `json:"description" swaggertype:"string"`
+	Directory   Patch[string] `json:"directory" swaggertype:"string"`
+	BaseBranch  Patch[string] `json:"base_branch" swaggertype:"string"`
+	AgentID     Patch[string] `json:"agent_id" swaggertype:"string"`
+	Archived    *bool         `json:"-"` // Set by archive and unarchive
+}
+
+// Patch expresses a full update as a merge patch. Empty fields are left
+// untouched; an empty agent_id clears it.
+func (r *UpdateProjectRequest) Patch() *PatchProjectRequest {
+	patch := &PatchProjectRequest{}
+	setIfNotEmpty(&patch.Name, r.Name)
+	setIfNotEmpty(&patch.Description, r.Description)
+	setIfNotEmpty(&patch.Directory, r.Directory)
+	setIfNotEmpty(&patch.BaseBranch, r.BaseBranch)
+	if r.AgentID != nil {
+		patch.AgentID = PatchValue(*r.AgentID)
+	}
+	return patch
+}
+
+// What happens to the tasks of a deleted project.
+const (
+	ProjectDeleteRestrict = "restrict" // Refuse to delete a project with tasks
+	ProjectDeleteCascade  = "cascade"  // Delete the tasks with the project
+	ProjectDeleteMove     = "move"     // Move the tasks to another project first
+)
+
+// DeleteProjectQuery selects what happens to the tasks of a deleted
+// project. OnDelete defaults to restrict; move needs TargetProjectID.
+type DeleteProjectQuery struct {
+	OnDelete        string `form:"on_delete" binding:"omitempty,oneof=restrict cascade move"`
+	TargetProjectID string `form:"target_project_id"`
+}
+
 type ProjectResponse struct {
-	ID          string    `json:"id"`
-	Name        string    `json:"name"`
-	Description string    `json:"description"`
-	Directory   string    `json:"directory"`
-	AgentID     *string   `json:"agent_id,omitempty"`
-	Agent       *Agent    `json:"agent,omitempty"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Directory   string     `json:"directory"`
+	BaseBranch  string     `json:"base_branch,omitempty"`
+	AgentID     *string    `json:"agent_id,omitempty"`
+	Agent       *Agent     `json:"agent,omitempty"`
+	Version     int64      `json:"version"`
+	ArchivedAt  *time.Time `json:"archived_at,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
+}
+
+// ProjectListQuery filters the project list.
+type ProjectListQuery struct {
+	IncludeArchived bool `form:"include_archived"` // Archived projects are left out by default
 }
 
 type ProjectListResponse struct {
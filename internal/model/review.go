@@ -0,0 +1,52 @@
+package model
+
+type DiffLine struct {
+	Type    string `json:"type"` // context, add or delete
+	Content string `json:"content"`
+	OldLine int    `json:"old_line,omitempty"`
+	NewLine int    `json:"new_line,omitempty"`
+}
+
+type DiffHunk struct {
+	Header   string     `json:"header"`
+	OldStart int        `json:"old_start"`
+	OldLines int        `json:"old_lines"`
+	NewStart int        `json:"new_start"`
+	NewLines int        `json:"new_lines"`
+	Lines    []DiffLine `json:"lines"`
+}
+
+type DiffFile struct {
+	Path      string     `json:"path"`
+	OldPath   string     `json:"old_path,omitempty"`
+	Status    string     `json:"status"` // added, modified, deleted or renamed
+	Binary    bool       `json:"binary"`
+	Additions int        `json:"additions"`
+	Deletions int        `json:"deletions"`
+	Hunks     []DiffHunk `json:"hunks,omitempty"`
+}
+
+type TaskDiffResponse struct {
+	TaskID     string     `json:"task_id"`
+	Branch     string     `json:"branch"`
+	BaseBranch string     `json:"base_branch"`
+	Files      []DiffFile `json:"files"`
+	Additions  int        `json:"additions"`
+	Deletions  int        `json:"deletions"`
+}
+
+const (
+	MergeStrategyMerge  = "merge"
+	MergeStrategySquash = "squash"
+)
+
+type MergeTaskRequest struct {
+	Strategy string `json:"strategy"` // merge (default) or squash
+}
+
+type MergeTaskResponse struct {
+	Task       *TaskResponse `json:"task"`
+	Strategy   string        `json:"strategy"`
+	BaseBranch string        `json:"base_branch"`
+	Commit     string        `json:"commit"`
+}
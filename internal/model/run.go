@@ -0,0 +1,45 @@
+package model
+
+import (
+	"time"
+)
+
+const (
+	RunStatusRunning   = "running"
+	RunStatusSucceeded = "succeeded"
+	RunStatusFailed    = "failed"
+	RunStatusCancelled = "cancelled"
+)
+
+type RunResponse struct {
+	ID         string     `json:"id"`
+	TaskID     string     `json:"task_id"`
+	AgentID    string     `json:"agent_id"`
+	Status     string     `json:"status"`
+	Command    string     `json:"command"`
+	WorkingDir string     `json:"working_dir"`
+	ExitCode   *int       `json:"exit_code,omitempty"`
+	Error      string     `json:"error,omitempty"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at,omitempty"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"`
+}
+
+type RunListResponse struct {
+	Runs  []RunResponse `json:"runs"`
+	Total int64         `json:"total"`
+}
+
+type RunLogLine struct {
+	Seq    int       `json:"seq"`
+	Stream string    `json:"stream"`
+	Text   string    `json:"text"`
+	Time   time.Time `json:"time"`
+}
+
+type RunLogResponse struct {
+	RunID  string       `json:"run_id"`
+	Status string       `json:"status"`
+	Lines  []RunLogLine `json:"lines"`
+}
@@ -0,0 +1,32 @@
+package model
+
+const (
+	SearchTypeTask    = "task"
+	SearchTypeProject = "project"
+)
+
+type SearchQuery struct {
+	Q         string `form:"q" binding:"required"`
+	Type      string `form:"type"` // task or project, both when empty
+	ProjectID string `form:"project_id"`
+	Limit     int    `form:"limit"`
+}
+
+// SearchResult is a task or project matching a search. Title and Snippet
+// wrap matched terms in <mark></mark>.
+type SearchResult struct {
+	Type      string  `json:"type"`
+	ID        string  `json:"id"`
+	ProjectID string  `json:"project_id,omitempty"`
+	Title     string  `json:"title"`
+	Snippet   string  `json:"snippet"`
+	Status    string  `json:"status,omitempty"` // Tasks only
+	Tags      string  `json:"tags,omitempty"`   // Tasks only, space separated
+	Score     float64 `json:"score"`            // Higher is more relevant
+}
+
+type SearchResponse struct {
+	Query   string         `json:"query"`
+	Results []SearchResult `json:"results"`
+	Total   int            `json:"total"`
+}
@@ -0,0 +1,46 @@
+package model
+
+import (
+	"time"
+)
+
+// TagRef is a tag as shown on the tasks carrying it.
+type TagRef struct {
+	ID    string `json:"id"`
+	Name  string `json:"name"`
+	Color string `json:"color,omitempty"`
+}
+
+type TagResponse struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Color     string    `json:"color,omitempty"`
+	TaskCount int64     `json:"task_count"` // Number of tasks carrying the tag
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type TagListResponse struct {
+	Tags  []TagResponse `json:"tags"`
+	Total int64         `json:"total"`
+}
+
+type CreateTagRequest struct {
+	Name  string `json:"name" binding:"required"`
+	Color string `json:"color" binding:"omitempty,hexcolor"`
+}
+
+type UpdateTagRequest struct {
+	Name  string  `json:"name"`
+	Color *string `json:"color,omitempty" binding:"omitempty,hexcolor|len=0"` // Empty string clears the color
+}
+
+// MergeTagRequest names the tag another tag is merged into.
+type MergeTagRequest struct {
+	TargetID string `json:"target_id" binding:"required"`
+}
+
+type TagGCResponse struct {
+	Deleted []string `json:"deleted"` // Names of the removed tags
+	Total   int64    `json:"total"`
+}
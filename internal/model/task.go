@@ -4,55 +4,174 @@ import (
 	"time"
 )
 
+const (
+	PriorityLow    = "low"
+	PriorityMedium = "medium"
+	PriorityHigh   = "high"
+	PriorityUrgent = "urgent"
+)
+
 type Task struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	Assignee    string    `json:"assignee"`
-	AgentID     *string   `json:"agent_id,omitempty"`
-	Agent       *Agent    `json:"agent,omitempty"`
-	Tags        []string  `json:"tags"`
-	ProjectID   string    `json:"project_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID          string     `json:"id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Assignee    string     `json:"assignee"`
+	AgentID     *string    `json:"agent_id,omitempty"`
+	Agent       *Agent     `json:"agent,omitempty"`
+	Tags        []string   `json:"tags"`
+	ProjectID   string     `json:"project_id"`
+	ParentID    *string    `json:"parent_id,omitempty"`
+	CreatedAt   time.Time  `json:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at"`
 }
 
 type CreateTaskRequest struct {
-	Title       string   `json:"title" binding:"required"`
-	Description string   `json:"description"`
-	Status      string   `json:"status"`
-	Assignee    string   `json:"assignee"`
-	AgentID     *string  `json:"agent_id,omitempty"`
-	Tags        []string `json:"tags"`
-	ProjectID   string   `json:"project_id"`
+	Title       string     `json:"title" binding:"required"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Assignee    string     `json:"assignee"`
+	AgentID     *string    `json:"agent_id,omitempty"`
+	Tags        []string   `json:"tags"`
+	ProjectID   string     `json:"project_id"` // Defaults to the parent's project for subtasks
+	ParentID    *string    `json:"parent_id,omitempty"`
 }
 
 type UpdateTaskRequest struct {
-	Title       string   `json:"title"`
-	Description string   `json:"description"`
-	Status      string   `json:"status"`
-	Assignee    string   `json:"assignee"`
-	AgentID     *string  `json:"agent_id,omitempty"`
-	Tags        []string `json:"tags"`
-	ProjectID   string   `json:"project_id"`
+	Title       string     `json:"title"`
+	Description string     `json:"description"`
+	Status      string     `json:"status"`
+	Priority    string     `json:"priority" binding:"omitempty,oneof=low medium high urgent"`
+	DueDate     *time.Time `json:"due_date,omitempty"`
+	Assignee    string     `json:"assignee"`
+	AgentID     *string    `json:"agent_id,omitempty"`
+	Tags        []string   `json:"tags"`
+	ProjectID   string     `json:"project_id"`
+	ParentID    *string    `json:"parent_id,omitempty"` // Empty string makes the task top-level
+	Position    string     `json:"-"`                   // Set by moves; status and project changes move the task to the end of its column
+}
+
+// PatchTaskRequest is a JSON merge patch of a task. Null clears
+// description, due_date, assignee, agent_id, tags and parent_id and resets
+// priority to medium; title, status and project_id cannot be cleared.
+type PatchTaskRequest struct {
+	Title       Patch[string]    `json:"title" swaggertype:"string"`
+	Description Patch[string]    `json:"description" swaggertype:"string"`
+	Status      Patch[string]    `json:"status" swaggertype:"string"`
+	Priority    Patch[string]    `json:"priority" swaggertype:"string" enums:"low,medium,high,urgent"`
+	DueDate     Patch[time.Time] `json:"due_date" swaggertype:"string" format:"date-time"`
+	Assignee    Patch[string]    `json:"assignee" swaggertype:"string"`
+	AgentID     Patch[string]    `json:"agent_id" swaggertype:"string"`
+	Tags        Patch[[]string]  `json:"tags" swaggertype:"array,string"`
+	ProjectID   Patch[string]    `json:"project_id" swaggertype:"string"`
+	ParentID    Patch[string]    `json:"parent_id" swaggertype:"string"`
+	Position    string           `json:"-"` // Set by moves, like UpdateTaskRequest.Position
+	Archived    *bool            `json:"-"` // Set by archive and unarchive
+}
+
+// Patch expresses a full update as a merge patch. Empty fields are left
+// untouched; an empty agent_id or parent_id clears it.
+func (r *UpdateTaskRequest) Patch() *PatchTaskRequest {
+	patch := &PatchTaskRequest{Position: r.Position}
+	setIfNotEmpty(&patch.Title, r.Title)
+	setIfNotEmpty(&patch.Description, r.Description)
+	setIfNotEmpty(&patch.Status, r.Status)
+	setIfNotEmpty(&patch.Priority, r.Priority)
+	setIfNotEmpty(&patch.Assignee, r.Assignee)
+	setIfNotEmpty(&patch.ProjectID, r.ProjectID)
+	if r.DueDate != nil {
+		patch.DueDate = PatchValue(*r.DueDate)
+	}
+	if r.AgentID != nil {
+		patch.AgentID = PatchValue(*r.AgentID)
+	}
+	if r.ParentID != nil {
+		patch.ParentID = PatchValue(*r.ParentID)
+	}
+	if r.Tags != nil {
+		patch.Tags = PatchValue(r.Tags)
+	}
+	return patch
+}
+
+func setIfNotEmpty(field *Patch[string], value string) {
+	if value != "" {
+		*field = PatchValue(value)
+	}
+}
+
+// MoveTaskRequest places a task in a board column. AfterID and BeforeID
+// name the tasks it lands between; with neither, it goes to the end.
+type MoveTaskRequest struct {
+	Status   string `json:"status"` // Defaults to the task's current status
+	AfterID  string `json:"after_id"`
+	BeforeID string `json:"before_id"`
+}
+
+// SubtaskRollup summarizes the direct subtasks of a task. Completed counts
+// subtasks in a final status of their workflow.
+type SubtaskRollup struct {
+	Total     int64 `json:"total"`
+	Completed int64 `json:"completed"`
 }
 
 type TaskResponse struct {
-	ID          string    `json:"id"`
-	Title       string    `json:"title"`
-	Description string    `json:"description"`
-	Status      string    `json:"status"`
-	Assignee    string    `json:"assignee"`
-	AgentID     *string   `json:"agent_id,omitempty"`
-	Agent       *Agent    `json:"agent,omitempty"`
-	Tags        []string  `json:"tags"`
-	ProjectID   string    `json:"project_id"`
-	CreatedAt   time.Time `json:"created_at"`
-	UpdatedAt   time.Time `json:"updated_at"`
+	ID           string         `json:"id"`
+	Title        string         `json:"title"`
+	Description  string         `json:"description"`
+	Status       string         `json:"status"`
+	Priority     string         `json:"priority"`
+	DueDate      *time.Time     `json:"due_date,omitempty"`
+	Assignee     string         `json:"assignee"`
+	AgentID      *string        `json:"agent_id,omitempty"`
+	Agent        *Agent         `json:"agent,omitempty"`
+	Tags         []string       `json:"tags"`
+	TagDetails   []TagRef       `json:"tag_details"`
+	ProjectID    string         `json:"project_id"`
+	ParentID     *string        `json:"parent_id,omitempty"`
+	Position     string         `json:"position"`
+	Subtasks     *SubtaskRollup `json:"subtasks,omitempty"`
+	BlockedBy    []string       `json:"blocked_by,omitempty"` // Unfinished tasks this task depends on
+	CommentCount int64          `json:"comment_count"`
+	Branch       string         `json:"branch,omitempty"`
+	BaseBranch   string         `json:"base_branch,omitempty"`
+	WorktreePath string         `json:"worktree_path,omitempty"`
+	Version      int64          `json:"version"`                // Also sent as the ETag of single task responses
+	CompletedAt  *time.Time     `json:"completed_at,omitempty"` // When the task last entered a final status
+	ArchivedAt   *time.Time     `json:"archived_at,omitempty"`
+	CreatedAt    time.Time      `json:"created_at"`
+	UpdatedAt    time.Time      `json:"updated_at"`
+}
+
+type AddDependencyRequest struct {
+	DependsOnID string `json:"depends_on_id" binding:"required"`
+}
+
+// TaskListQuery filters, sorts and pages GET /api/tasks. Status and Tag
+// accept repeated parameters as well as comma separated values.
+type TaskListQuery struct {
+	ProjectID       string   `form:"project_id"`
+	ParentID        string   `form:"parent_id"`
+	Status          []string `form:"status"`
+	Tag             []string `form:"tag"`
+	AgentID         string   `form:"agent_id"`
+	Assignee        string   `form:"assignee"`
+	Q               string   `form:"q"`
+	Overdue         *bool    `form:"overdue"`          // Past due date and not in a final status
+	CreatedAfter    string   `form:"created_after"`    // RFC 3339 timestamp or YYYY-MM-DD
+	CreatedBefore   string   `form:"created_before"`   // RFC 3339 timestamp or YYYY-MM-DD
+	IncludeArchived bool     `form:"include_archived"` // Archived tasks are left out by default
+	Sort            string   `form:"sort"`             // Field name, prefixed with '-' for descending order; defaults to position
+	Limit           int      `form:"limit"`
+	Cursor          string   `form:"cursor"`
 }
 
 type TaskListResponse struct {
-	Tasks []TaskResponse `json:"tasks"`
-	Total int64          `json:"total"`
+	Tasks      []TaskResponse `json:"tasks"`
+	Total      int64          `json:"total"`
+	NextCursor string         `json:"next_cursor,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// TrashQuery filters GET /api/trash.
+type TrashQuery struct {
+	Type string `form:"type" binding:"omitempty,oneof=task project"`
+}
+
+// TrashItem is a task or project in the trash. Subtasks and tasks trashed
+// along with it are counted in TaskCount rather than listed; restoring the
+// item brings them back too.
+type TrashItem struct {
+	Type      string     `json:"type"` // task or project
+	ID        string     `json:"id"`
+	Title     string     `json:"title"` // Task title or project name
+	ProjectID string     `json:"project_id,omitempty"`
+	TaskCount int64      `json:"task_count"`
+	DeletedAt time.Time  `json:"deleted_at"`
+	PurgeAt   *time.Time `json:"purge_at,omitempty"` // Unset when trashed items are kept
+}
+
+type TrashListResponse struct {
+	Items []TrashItem `json:"items"`
+	Total int64       `json:"total"`
+}
+
+// TrashRestoreResponse carries the restored task or project.
+type TrashRestoreResponse struct {
+	Type          string           `json:"type"`
+	Task          *TaskResponse    `json:"task,omitempty"`
+	Project       *ProjectResponse `json:"project,omitempty"`
+	RestoredTasks int              `json:"restored_tasks"` // Including subtasks and the tasks of a project
+}
@@ -0,0 +1,31 @@
+package model
+
+const (
+	TaskStatusTodo       = "todo"
+	TaskStatusInProgress = "inprogress"
+	TaskStatusInReview   = "inreview"
+	TaskStatusDone       = "done"
+	TaskStatusCancelled  = "cancelled"
+)
+
+// WorkflowStatus is a column of the board.
+type WorkflowStatus struct {
+	Key   string `json:"key" binding:"required"`
+	Name  string `json:"name"`
+	Final bool   `json:"final"` // Work on the task is over, e.g. done or cancelled
+}
+
+// Workflow declares the statuses a task can have and which moves between
+// them are allowed. The first status is the one new tasks start in.
+type Workflow struct {
+	Statuses    []WorkflowStatus    `json:"statuses" binding:"required,min=1,dive"`
+	Transitions map[string][]string `json:"transitions"`
+}
+
+type UpdateWorkflowRequest = Workflow
+
+type WorkflowResponse struct {
+	ProjectID string `json:"project_id"`
+	Custom    bool   `json:"custom"` // False when the project uses the default workflow
+	Workflow
+}
@@ -0,0 +1,281 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"time"
+
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+const (
+	// ActorSystem is recorded for changes Solo makes on its own, such as
+	// moving a task along when an agent run starts.
+	ActorSystem = "system"
+
+	defaultActivityLimit = 50
+	maxActivityLimit     = 500
+)
+
+var ErrInvalidActivityQuery = errors.New("invalid activity query")
+
+// AgentActor is the actor recorded for changes an agent's run caused.
+func AgentActor(agentID string) string {
+	return "agent:" + agentID
+}
+
+// ActivityService reads the activity log. Entries are written by the
+// services making the changes, inside the same transaction.
+type ActivityService struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+func NewActivityService(db *database.Database, logger *zap.Logger) *ActivityService {
+	return &ActivityService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// GetActivity lists activity entries matching the query, newest first.
+func (s *ActivityService) GetActivity(query *model.ActivityQuery) (*model.ActivityListResponse, error) {
+	limit := query.Limit
+	if limit == 0 {
+		limit = defaultActivityLimit
+	}
+	if limit < 0 || limit > maxActivityLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidActivityQuery, maxActivityLimit)
+	}
+
+	db := s.db.GetDB().Model(&database.Activity{})
+	if query.EntityType != "" {
+		db = db.Where("entity_type = ?", query.EntityType)
+	}
+	if query.EntityID != "" {
+		db = db.Where("entity_id = ?", query.EntityID)
+	}
+	if query.ProjectID != "" {
+		db = db.Where("project_id = ?", query.ProjectID)
+	}
+	if query.Actor != "" {
+		db = db.Where("actor = ?", query.Actor)
+	}
+	if query.Action != "" {
+		db = db.Where("action = ?", query.Action)
+	}
+	if query.Since != "" {
+		since, err := parseQueryTime(query.Since)
+		if err != nil {
+			return nil, fmt.Errorf("%w: since: %v", ErrInvalidActivityQuery, err)
+		}
+		db = db.Where("created_at >= ?", since)
+	}
+	if query.Until != "" {
+		until, err := parseQueryTime(query.Until)
+		if err != nil {
+			return nil, fmt.Errorf("%w: until: %v", ErrInvalidActivityQuery, err)
+		}
+		db = db.Where("created_at < ?", until)
+	}
+
+	var total int64
+	if err := db.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+		s.logger.Error("Failed to count activity", zap.Error(err))
+		return nil, err
+	}
+
+	// Entries are appended in order, so the ID doubles as the cursor
+	page := db.Session(&gorm.Session{})
+	if query.Cursor != "" {
+		before, err := strconv.ParseInt(query.Cursor, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("%w: malformed cursor", ErrInvalidActivityQuery)
+		}
+		page = page.Where("id < ?", before)
+	}
+
+	var entries []database.Activity
+	if err := page.Order("id DESC").Limit(limit + 1).Find(&entries).Error; err != nil {
+		s.logger.Error("Failed to get activity", zap.Error(err))
+		return nil, err
+	}
+
+	var nextCursor string
+	if len(entries) > limit {
+		entries = entries[:limit]
+		nextCursor = strconv.FormatInt(entries[len(entries)-1].ID, 10)
+	}
+
+	responses := make([]model.ActivityResponse, len(entries))
+	for i, entry := range entries {
+		changes := []model.FieldChange{}
+		if entry.Changes != "" {
+			if err := json.Unmarshal([]byte(entry.Changes), &changes); err != nil {
+				s.logger.Warn("Failed to decode activity changes", zap.Error(err), zap.Int64("id", entry.ID))
+			}
+		}
+		responses[i] = model.ActivityResponse{
+			ID:         entry.ID,
+			EntityType: entry.EntityType,
+			EntityID:   entry.EntityID,
+			ProjectID:  entry.ProjectID,
+			Action:     entry.Action,
+			Actor:      entry.Actor,
+			Changes:    changes,
+			CreatedAt:  entry.CreatedAt,
+		}
+	}
+
+	return &model.ActivityListResponse{
+		Activity:   responses,
+		Total:      total,
+		NextCursor: nextCursor,
+	}, nil
+}
+
+// GetTaskActivity lists the activity of a single task. The history of
+// deleted tasks stays available.
+func (s *ActivityService) GetTaskActivity(taskID string, query *model.ActivityQuery) (*model.ActivityListResponse, error) {
+	query.EntityType = model.EntityTask
+	query.EntityID = taskID
+	return s.GetActivity(query)
+}
+
+// snapshot holds the audited fields of an entity by name.
+type snapshot map[string]interface{}
+
+// recordActivity appends an activity entry for a change made in tx. Updates
+// that changed no audited field are not recorded.
+func recordActivity(tx *gorm.DB, entityType, entityID, projectID, action, actor string, before, after snapshot) error {
+	return storeActivity(store.NewGormStore(tx), entityType, entityID, projectID, action, actor, before, after)
+}
+
+// storeActivity is recordActivity for changes made through a store.
+func storeActivity(st store.Store, entityType, entityID, projectID, action, actor string, before, after snapshot) error {
+	changes := diffSnapshots(before, after)
+	if action == model.ActivityUpdated && len(changes) == 0 {
+		return nil
+	}
+	if actor == "" {
+		actor = ActorSystem
+	}
+
+	encoded, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	return st.RecordActivity(&database.Activity{
+		EntityType: entityType,
+		EntityID:   entityID,
+		ProjectID:  projectID,
+		Action:     action,
+		Actor:      actor,
+		Changes:    string(encoded),
+		CreatedAt:  time.Now(),
+	})
+}
+
+// diffSnapshots lists the fields whose values differ, sorted by name.
+// Either snapshot may be nil for created and deleted entities.
+func diffSnapshots(before, after snapshot) []model.FieldChange {
+	fields := map[string]bool{}
+	for field := range before {
+		fields[field] = true
+	}
+	for field := range after {
+		fields[field] = true
+	}
+
+	changes := []model.FieldChange{}
+	for field := range fields {
+		old, updated := auditValue(before[field]), auditValue(after[field])
+		if reflect.DeepEqual(old, updated) {
+			continue
+		}
+		changes = append(changes, model.FieldChange{Field: field, Before: old, After: updated})
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	return changes
+}
+
+// auditValue normalizes a field value so equal values compare equal and
+// empty values are recorded as null.
+func auditValue(value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if v == "" {
+			return nil
+		}
+	case *string:
+		if v == nil || *v == "" {
+			return nil
+		}
+		return *v
+	case *time.Time:
+		if v == nil {
+			return nil
+		}
+		return v.UTC().Format(time.RFC3339Nano)
+	case []string:
+		if len(v) == 0 {
+			return nil
+		}
+		sorted := append([]string(nil), v...)
+		sort.Strings(sorted)
+		return sorted
+	}
+	return value
+}
+
+func taskSnapshot(task *database.Task, tags []string) snapshot {
+	return snapshot{
+		"title":       task.Title,
+		"description": task.Description,
+		"status":      task.Status,
+		"priority":    task.Priority,
+		"due_date":    task.DueDate,
+		"assignee":    task.Assignee,
+		"agent_id":    task.AgentID,
+		"project_id":  task.ProjectID,
+		"parent_id":   task.ParentID,
+		"position":    task.Position,
+		"archived_at": task.ArchivedAt,
+		"tags":        tags,
+	}
+}
+
+func projectSnapshot(project *database.Project) snapshot {
+	return snapshot{
+		"name":        project.Name,
+		"description": project.Description,
+		"directory":   project.Directory,
+		"base_branch": project.BaseBranch,
+		"agent_id":    project.AgentID,
+		"archived_at": project.ArchivedAt,
+	}
+}
+
+func agentSnapshot(agent *database.Agent) snapshot {
+	return snapshot{
+		"name":        agent.Name,
+		"type":        agent.Type,
+		"description": agent.Description,
+		"command":     agent.Command,
+	}
+}
+
+// taskTagNames maps each of the given tasks to the names of its tags.
+func taskTagNames(db *gorm.DB, taskIDs []string) (map[string][]string, error) {
+	return store.NewGormStore(db).Tasks().TagNames(taskIDs)
+}
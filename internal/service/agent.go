@@ -5,25 +5,32 @@ import (
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 
 	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
 	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
 )
 
 type AgentService struct {
-	db     *database.Database
-	logger *zap.Logger
+	stores         store.Store
+	taskService    *TaskService
+	projectService *ProjectService
+	events         *events.Bus
+	logger         *zap.Logger
 }
 
-func NewAgentService(db *database.Database, logger *zap.Logger) *AgentService {
+func NewAgentService(stores store.Store, taskService *TaskService, projectService *ProjectService, bus *events.Bus, logger *zap.Logger) *AgentService {
 	return &AgentService{
-		db:     db,
-		logger: logger,
+		stores:         stores,
+		taskService:    taskService,
+		projectService: projectService,
+		events:         bus,
+		logger:         logger,
 	}
 }
 
-func (s *AgentService) CreateAgent(req *model.CreateAgentRequest) (*model.AgentResponse, error) {
+func (s *AgentService) CreateAgent(req *model.CreateAgentRequest, opts MutationOptions) (*model.AgentResponse, error) {
 	s.logger.Info("Creating new agent", zap.String("name", req.Name))
 
 	agent := database.Agent{
@@ -31,56 +38,49 @@ func (s *AgentService) CreateAgent(req *model.CreateAgentRequest) (*model.AgentR
 		Name:        req.Name,
 		Type:        req.Type,
 		Description: req.Description,
+		Command:     req.Command,
+		Version:     1,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := s.db.GetDB().Create(&agent).Error; err != nil {
+	if err := s.stores.Transaction(func(st store.Store) error {
+		if err := st.Agents().Create(&agent); err != nil {
+			return err
+		}
+		return storeActivity(st, model.EntityAgent, agent.ID, "", model.ActivityCreated, opts.Actor,
+			nil, agentSnapshot(&agent))
+	}); err != nil {
 		s.logger.Error("Failed to create agent", zap.Error(err))
 		return nil, err
 	}
 
-	response := &model.AgentResponse{
-		ID:          agent.ID,
-		Name:        agent.Name,
-		Type:        agent.Type,
-		Description: agent.Description,
-		CreatedAt:   agent.CreatedAt,
-		UpdatedAt:   agent.UpdatedAt,
-	}
+	response := agentResponse(&agent)
 
 	s.logger.Info("Agent created successfully", zap.String("id", agent.ID))
+	s.events.Publish(events.Event{
+		Type:     events.AgentCreated,
+		EntityID: response.ID,
+		Data:     response,
+	})
 	return response, nil
 }
 
 func (s *AgentService) GetAgents() (*model.AgentListResponse, error) {
 	s.logger.Info("Getting all agents")
 
-	var agents []database.Agent
-	var total int64
-
-	if err := s.db.GetDB().Model(&database.Agent{}).Count(&total).Error; err != nil {
-		s.logger.Error("Failed to count agents", zap.Error(err))
-		return nil, err
-	}
-
-	if err := s.db.GetDB().Find(&agents).Error; err != nil {
+	agents, err := s.stores.Agents().List()
+	if err != nil {
 		s.logger.Error("Failed to get agents", zap.Error(err))
 		return nil, err
 	}
 
 	var agentResponses []model.AgentResponse
-	for _, agent := range agents {
-		agentResponses = append(agentResponses, model.AgentResponse{
-			ID:          agent.ID,
-			Name:        agent.Name,
-			Type:        agent.Type,
-			Description: agent.Description,
-			CreatedAt:   agent.CreatedAt,
-			UpdatedAt:   agent.UpdatedAt,
-		})
+	for i := range agents {
+		agentResponses = append(agentResponses, *agentResponse(&agents[i]))
 	}
 
+	total := int64(len(agents))
 	response := &model.AgentListResponse{
 		Agents: agentResponses,
 		Total:  total,
@@ -93,9 +93,9 @@ func (s *AgentService) GetAgents() (*model.AgentListResponse, error) {
 func (s *AgentService) GetAgent(id string) (*model.AgentResponse, error) {
 	s.logger.Info("Getting agent", zap.String("id", id))
 
-	var agent database.Agent
-	if err := s.db.GetDB().First(&agent, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	agent, err := s.stores.Agents().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			s.logger.Warn("Agent not found", zap.String("id", id))
 			return nil, err
 		}
@@ -103,25 +103,23 @@ func (s *AgentService) GetAgent(id string) (*model.AgentResponse, error) {
 		return nil, err
 	}
 
-	response := &model.AgentResponse{
-		ID:          agent.ID,
-		Name:        agent.Name,
-		Type:        agent.Type,
-		Description: agent.Description,
-		CreatedAt:   agent.CreatedAt,
-		UpdatedAt:   agent.UpdatedAt,
-	}
+	response := agentResponse(agent)
 
 	s.logger.Info("Agent retrieved successfully", zap.String("id", id))
 	return response, nil
 }
 
-func (s *AgentService) UpdateAgent(id string, req *model.UpdateAgentRequest) (*model.AgentResponse, error) {
+func (s *AgentService) UpdateAgent(id string, req *model.UpdateAgentRequest, opts MutationOptions) (*model.AgentResponse, error) {
+	return s.PatchAgent(id, req.Patch(), opts)
+}
+
+// PatchAgent applies a JSON merge patch to an agent.
+func (s *AgentService) PatchAgent(id string, req *model.PatchAgentRequest, opts MutationOptions) (*model.AgentResponse, error) {
 	s.logger.Info("Updating agent", zap.String("id", id))
 
-	var agent database.Agent
-	if err := s.db.GetDB().First(&agent, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	agent, err := s.stores.Agents().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			s.logger.Warn("Agent not found", zap.String("id", id))
 			return nil, err
 		}
@@ -129,54 +127,174 @@ func (s *AgentService) UpdateAgent(id string, req *model.UpdateAgentRequest) (*m
 		return nil, err
 	}
 
+	before := agentSnapshot(agent)
+
 	// Update fields
-	if req.Name != "" {
-		agent.Name = req.Name
-	}
-	if req.Type != "" {
-		agent.Type = req.Type
+	if err := patchRequired(&agent.Name, req.Name, "name"); err != nil {
+		return nil, err
 	}
-	if req.Description != "" {
-		agent.Description = req.Description
+	if err := patchRequired(&agent.Type, req.Type, "type"); err != nil {
+		return nil, err
 	}
+	patchString(&agent.Description, req.Description)
+	patchString(&agent.Command, req.Command)
 	agent.UpdatedAt = time.Now()
 
-	if err := s.db.GetDB().Save(&agent).Error; err != nil {
-		s.logger.Error("Failed to update agent", zap.Error(err))
+	if err := checkVersion(agent.Version, opts); err != nil {
 		return nil, err
 	}
-
-	response := &model.AgentResponse{
-		ID:          agent.ID,
-		Name:        agent.Name,
-		Type:        agent.Type,
-		Description: agent.Description,
-		CreatedAt:   agent.CreatedAt,
-		UpdatedAt:   agent.UpdatedAt,
+	if err := s.stores.Transaction(func(st store.Store) error {
+		if err := st.Agents().Update(agent); err != nil {
+			return err
+		}
+		if err := bumpAgentUsers(st, agent.ID); err != nil {
+			return err
+		}
+		return storeActivity(st, model.EntityAgent, agent.ID, "", model.ActivityUpdated, opts.Actor,
+			before, agentSnapshot(agent))
+	}); err != nil {
+		if err != ErrVersionMismatch {
+			s.logger.Error("Failed to update agent", zap.Error(err))
+		}
+		return nil, err
 	}
 
+	response := agentResponse(agent)
+
 	s.logger.Info("Agent updated successfully", zap.String("id", id))
+	s.events.Publish(events.Event{
+		Type:     events.AgentUpdated,
+		EntityID: response.ID,
+		Data:     response,
+	})
 	return response, nil
 }
 
-func (s *AgentService) DeleteAgent(id string) error {
+// bumpAgentUsers moves on the versions of the tasks and projects assigned
+// an agent, whose responses show it.
+func bumpAgentUsers(st store.Store, agentID string) error {
+	tasks, err := st.Tasks().Find(store.TaskFilter{AgentID: agentID, IncludeArchived: true}, store.TaskPage{})
+	if err != nil {
+		return err
+	}
+	taskIDs := make([]string, len(tasks))
+	for i := range tasks {
+		taskIDs[i] = tasks[i].ID
+	}
+	if err := st.Tasks().BumpVersions(taskIDs); err != nil {
+		return err
+	}
+
+	projects, err := st.Projects().List(true)
+	if err != nil {
+		return err
+	}
+	var projectIDs []string
+	for _, project := range projects {
+		if project.AgentID != nil && *project.AgentID == agentID {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+	return st.Projects().BumpVersions(projectIDs)
+}
+
+func (s *AgentService) DeleteAgent(id string, opts MutationOptions) error {
 	s.logger.Info("Deleting agent", zap.String("id", id))
 
-	var agent database.Agent
-	if err := s.db.GetDB().First(&agent, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	agent, err := s.stores.Agents().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			s.logger.Warn("Agent not found", zap.String("id", id))
 			return err
 		}
 		s.logger.Error("Failed to find agent", zap.Error(err))
 		return err
 	}
+	if err := checkVersion(agent.Version, opts); err != nil {
+		return err
+	}
+
+	// Tasks and projects using the agent are unassigned
+	taskOpts := MutationOptions{Actor: opts.Actor}
+	var updates []*taskUpdate
+	var projectIDs []string
 
-	if err := s.db.GetDB().Delete(&agent).Error; err != nil {
+	if err := s.stores.Transaction(func(st store.Store) error {
+		tasks, err := st.Tasks().Find(store.TaskFilter{AgentID: id, IncludeArchived: true},
+			store.TaskPage{Sort: "created_at"})
+		if err != nil {
+			return err
+		}
+		for _, task := range tasks {
+			update, err := s.taskService.updateTask(st, task.ID, &model.PatchTaskRequest{AgentID: model.PatchNull[string]()}, taskOpts)
+			if err != nil {
+				return err
+			}
+			if update != nil {
+				updates = append(updates, update)
+			}
+		}
+
+		if projectIDs, err = s.projectService.unassignAgent(st, id, opts); err != nil {
+			return err
+		}
+
+		// Trashed tasks and projects keep no reference to the agent either
+		if err := st.Agents().Delete(agent.ID); err != nil {
+			return err
+		}
+		return storeActivity(st, model.EntityAgent, agent.ID, "", model.ActivityDeleted, opts.Actor,
+			agentSnapshot(agent), nil)
+	}); err != nil {
 		s.logger.Error("Failed to delete agent", zap.Error(err))
 		return err
 	}
 
+	for _, update := range updates {
+		if _, err := s.taskService.finishUpdate(update, taskOpts); err != nil {
+			s.logger.Warn("Failed to reload unassigned task", zap.Error(err), zap.String("id", update.task.ID))
+		}
+	}
+	for _, projectID := range projectIDs {
+		if _, err := s.projectService.publishProjectUpdated(projectID); err != nil {
+			s.logger.Warn("Failed to reload unassigned project", zap.Error(err), zap.String("id", projectID))
+		}
+	}
+
 	s.logger.Info("Agent deleted successfully", zap.String("id", id))
+	s.events.Publish(events.Event{
+		Type:     events.AgentDeleted,
+		EntityID: id,
+	})
 	return nil
+}
+
+// agentResponse maps a stored agent to its API representation.
+func agentResponse(agent *database.Agent) *model.AgentResponse {
+	return &model.AgentResponse{
+		ID:          agent.ID,
+		Name:        agent.Name,
+		Type:        agent.Type,
+		Description: agent.Description,
+		Command:     agent.Command,
+		Version:     agent.Version,
+		CreatedAt:   agent.CreatedAt,
+		UpdatedAt:   agent.UpdatedAt,
+	}
+}
+
+// agentModel maps the agent assigned to a task or project, if any.
+func agentModel(agent *database.Agent) *model.Agent {
+	if agent == nil {
+		return nil
+	}
+	return &model.Agent{
+		ID:          agent.ID,
+		Name:        agent.Name,
+		Type:        agent.Type,
+		Description: agent.Description,
+		Command:     agent.Command,
+		CreatedAt:   agent.CreatedAt,
+		UpdatedAt:   agent.UpdatedAt,
+	}
 }
\ No newline at end of file
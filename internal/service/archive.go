@@ -0,0 +1,119 @@
+package service
+
+import (
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/config"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+// archiveInterval is how often finished tasks are looked for.
+const archiveInterval = time.Hour
+
+// ArchiveTask hides a task from task lists without deleting it. It returns
+// nil when the task does not exist.
+func (s *TaskService) ArchiveTask(id string, opts MutationOptions) (*model.TaskResponse, error) {
+	archived := true
+	return s.PatchTask(id, &model.PatchTaskRequest{Archived: &archived}, opts)
+}
+
+// UnarchiveTask brings an archived task back into task lists. It returns
+// nil when the task does not exist.
+func (s *TaskService) UnarchiveTask(id string, opts MutationOptions) (*model.TaskResponse, error) {
+	archived := false
+	return s.PatchTask(id, &model.PatchTaskRequest{Archived: &archived}, opts)
+}
+
+// StartArchiving archives finished tasks now and then periodically in the
+// background. Nothing is archived when AfterDays is zero.
+func (s *TaskService) StartArchiving(cfg *config.ArchiveConfig) {
+	if cfg.AfterDays <= 0 {
+		s.logger.Info("Automatic archiving disabled")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(archiveInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.ArchiveFinishedTasks(cfg.AfterDays); err != nil {
+				s.logger.Error("Failed to archive finished tasks", zap.Error(err))
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// ArchiveFinishedTasks archives the tasks that entered a final status of
+// their workflow at least the given number of days ago. Editing a finished
+// task does not restart the clock, but reopening or unarchiving it does: a
+// task unarchived while still finished is archived again once that many
+// days have passed since. It returns how many tasks were archived.
+func (s *TaskService) ArchiveFinishedTasks(days int) (int, error) {
+	final, err := s.finalStatuses()
+	if err != nil {
+		return 0, err
+	}
+
+	finished := true
+	completedBefore := time.Now().AddDate(0, 0, -days)
+	tasks, err := s.stores.Tasks().Find(store.TaskFilter{
+		Final:           final,
+		Finished:        &finished,
+		CompletedBefore: &completedBefore,
+	}, store.TaskPage{})
+	if err != nil {
+		return 0, err
+	}
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+
+	archived := 0
+	for _, id := range ids {
+		task, err := s.ArchiveTask(id, MutationOptions{})
+		if err != nil {
+			s.logger.Warn("Failed to archive finished task", zap.Error(err), zap.String("id", id))
+			continue
+		}
+		if task != nil {
+			archived++
+		}
+	}
+
+	if archived > 0 {
+		s.logger.Info("Archived finished tasks", zap.Int("count", archived), zap.Int("after_days", days))
+	}
+	return archived, nil
+}
+
+// ArchiveProject hides a project from the project list without deleting
+// it. Its tasks are left as they are.
+func (s *ProjectService) ArchiveProject(id string, opts MutationOptions) (*model.ProjectResponse, error) {
+	archived := true
+	return s.PatchProject(id, &model.PatchProjectRequest{Archived: &archived}, opts)
+}
+
+// UnarchiveProject brings an archived project back into the project list.
+func (s *ProjectService) UnarchiveProject(id string, opts MutationOptions) (*model.ProjectResponse, error) {
+	archived := false
+	return s.PatchProject(id, &model.PatchProjectRequest{Archived: &archived}, opts)
+}
+
+// archivedAt returns the archive time after archiving or unarchiving. An
+// archived item keeps the time it was first archived.
+func archivedAt(current *time.Time, archived bool) *time.Time {
+	if !archived {
+		return nil
+	}
+	if current != nil {
+		return current
+	}
+	now := time.Now()
+	return &now
+}
@@ -0,0 +1,216 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var ErrInvalidBulkRequest = errors.New("invalid bulk request")
+
+// errBulkRolledBack aborts the transaction of an atomic bulk operation
+// after one of its tasks failed.
+var errBulkRolledBack = errors.New("bulk operation rolled back")
+
+// BulkUpdateTasks applies one operation to a list of tasks in a single
+// transaction. Each task is changed in a savepoint of its own, so a failing
+// task leaves no partial change behind. In atomic mode any failure rolls
+// back the whole operation; in best effort mode the other tasks are still
+// applied. Every task is tried either way, so the results report all
+// failures at once.
+func (s *TaskService) BulkUpdateTasks(req *model.BulkTaskRequest, opts MutationOptions) (*model.BulkTaskResponse, error) {
+	mode := req.Mode
+	if mode == "" {
+		mode = model.BulkModeAtomic
+	}
+	if err := s.validateBulkRequest(req); err != nil {
+		return nil, err
+	}
+
+	response := &model.BulkTaskResponse{
+		Operation: req.Operation,
+		Mode:      mode,
+		Results:   make([]model.BulkTaskResult, len(req.TaskIDs)),
+	}
+	updates := make([]*taskUpdate, len(req.TaskIDs))
+	deletions := make([]*taskDeletion, len(req.TaskIDs))
+
+	now := time.Now()
+	err := s.stores.Transaction(func(st store.Store) error {
+		// Subtasks go along with their deleted parents
+		deleted := map[string]bool{}
+
+		for i, id := range req.TaskIDs {
+			result := &response.Results[i]
+			result.ID = id
+			if deleted[id] {
+				result.Result = model.BulkResultApplied
+				continue
+			}
+
+			err := st.Transaction(func(item store.Store) error {
+				var err error
+				if req.Operation == model.BulkOpDelete {
+					deletions[i], err = s.deleteTask(item, id, now, uuid.New().String(), opts)
+					return err
+				}
+
+				update, err := s.bulkUpdateRequest(item, id, req)
+				if err != nil {
+					return err
+				}
+				if updates[i], err = s.updateTask(item, id, update, opts); err == nil && updates[i] == nil {
+					err = store.ErrNotFound
+				}
+				return err
+			})
+			if err != nil {
+				if err == store.ErrNotFound {
+					err = errors.New("task not found")
+				}
+				result.Result = model.BulkResultFailed
+				result.Error = err.Error()
+				response.Failed++
+				updates[i], deletions[i] = nil, nil
+				continue
+			}
+
+			result.Result = model.BulkResultApplied
+			if deletions[i] != nil {
+				for _, task := range deletions[i].deleted {
+					deleted[task.ID] = true
+				}
+			}
+		}
+
+		if mode == model.BulkModeAtomic && response.Failed > 0 {
+			return errBulkRolledBack
+		}
+		return nil
+	})
+	if err == errBulkRolledBack {
+		for i := range response.Results {
+			if response.Results[i].Result == model.BulkResultApplied {
+				response.Results[i].Result = model.BulkResultRolledBack
+			}
+		}
+		s.logger.Info("Bulk task operation rolled back",
+			zap.String("operation", req.Operation),
+			zap.Int("failed", response.Failed))
+		return response, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to apply bulk task operation", zap.Error(err), zap.String("operation", req.Operation))
+		return nil, err
+	}
+
+	for i := range response.Results {
+		if response.Results[i].Result != model.BulkResultApplied {
+			continue
+		}
+		response.Applied++
+
+		switch {
+		case deletions[i] != nil:
+			s.finishDelete(deletions[i])
+		case updates[i] != nil:
+			task, err := s.finishUpdate(updates[i], opts)
+			if err != nil {
+				s.logger.Warn("Failed to reload bulk updated task", zap.Error(err), zap.String("id", updates[i].task.ID))
+			}
+			response.Results[i].Task = task
+		}
+	}
+
+	s.logger.Info("Bulk task operation applied",
+		zap.String("operation", req.Operation),
+		zap.Int("applied", response.Applied),
+		zap.Int("failed", response.Failed))
+	return response, nil
+}
+
+// validateBulkRequest checks that the operation has what it needs before
+// any task is touched.
+func (s *TaskService) validateBulkRequest(req *model.BulkTaskRequest) error {
+	switch req.Operation {
+	case model.BulkOpStatus:
+		if req.Status == "" {
+			return fmt.Errorf("%w: status is required", ErrInvalidBulkRequest)
+		}
+	case model.BulkOpAddTags, model.BulkOpRemoveTags:
+		for _, name := range req.Tags {
+			if name != "" {
+				return nil
+			}
+		}
+		return fmt.Errorf("%w: tags are required", ErrInvalidBulkRequest)
+	case model.BulkOpAssignAgent:
+		if req.AgentID == nil {
+			return fmt.Errorf("%w: agent_id is required, use an empty string to unassign", ErrInvalidBulkRequest)
+		}
+		if *req.AgentID != "" {
+			_, err := s.stores.Agents().Get(*req.AgentID)
+			return s.ensureExists(err, *req.AgentID, "agent")
+		}
+	case model.BulkOpMoveProject:
+		if req.ProjectID == "" {
+			return fmt.Errorf("%w: project_id is required", ErrInvalidBulkRequest)
+		}
+		_, err := s.stores.Projects().Get(req.ProjectID)
+		return s.ensureExists(err, req.ProjectID, "project")
+	}
+	return nil
+}
+
+// ensureExists turns the error of loading what a bulk operation refers to
+// into the error of the operation.
+func (s *TaskService) ensureExists(err error, id, name string) error {
+	if err == store.ErrNotFound {
+		return fmt.Errorf("%w: %s %s does not exist", ErrInvalidBulkRequest, name, id)
+	}
+	if err != nil {
+		s.logger.Error("Failed to check "+name, zap.Error(err), zap.String("id", id))
+	}
+	return err
+}
+
+// bulkUpdateRequest turns a bulk operation into the update of a single
+// task. Tag operations start from the tags the task carries.
+func (s *TaskService) bulkUpdateRequest(st store.Store, id string, req *model.BulkTaskRequest) (*model.PatchTaskRequest, error) {
+	switch req.Operation {
+	case model.BulkOpStatus:
+		return &model.PatchTaskRequest{Status: model.PatchValue(req.Status)}, nil
+	case model.BulkOpAssignAgent:
+		return &model.PatchTaskRequest{AgentID: model.PatchValue(*req.AgentID)}, nil
+	case model.BulkOpMoveProject:
+		return &model.PatchTaskRequest{ProjectID: model.PatchValue(req.ProjectID)}, nil
+	}
+
+	current, err := st.Tasks().TagNames([]string{id})
+	if err != nil {
+		return nil, err
+	}
+
+	listed := map[string]bool{}
+	for _, name := range req.Tags {
+		listed[name] = true
+	}
+
+	tags := []string{}
+	for _, name := range current[id] {
+		if req.Operation == model.BulkOpRemoveTags && listed[name] {
+			continue
+		}
+		tags = append(tags, name)
+	}
+	if req.Operation == model.BulkOpAddTags {
+		tags = append(tags, req.Tags...)
+	}
+	return &model.PatchTaskRequest{Tags: model.PatchValue(tags)}, nil
+}
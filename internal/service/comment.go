@@ -0,0 +1,338 @@
+package service
+
+import (
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var ErrInvalidCommentAuthor = errors.New("agent comments need the ID of an existing agent")
+
+// CommentService manages the comment threads of tasks. Missing tasks and
+// comments are reported as gorm.ErrRecordNotFound.
+type CommentService struct {
+	db     *database.Database
+	events *events.Bus
+	logger *zap.Logger
+}
+
+func NewCommentService(db *database.Database, bus *events.Bus, logger *zap.Logger) *CommentService {
+	return &CommentService{
+		db:     db,
+		events: bus,
+		logger: logger,
+	}
+}
+
+// GetComments lists the comments of a task, oldest first.
+func (s *CommentService) GetComments(taskID string) (*model.CommentListResponse, error) {
+	if _, err := s.getTask(taskID); err != nil {
+		return nil, err
+	}
+
+	var comments []database.Comment
+	if err := s.db.GetDB().Where("task_id = ?", taskID).Order("created_at, id").Find(&comments).Error; err != nil {
+		s.logger.Error("Failed to get comments", zap.Error(err), zap.String("task_id", taskID))
+		return nil, err
+	}
+
+	responses, err := s.commentsToResponses(comments)
+	if err != nil {
+		return nil, err
+	}
+
+	return &model.CommentListResponse{
+		Comments: responses,
+		Total:    int64(len(responses)),
+	}, nil
+}
+
+func (s *CommentService) GetComment(taskID, commentID string) (*model.CommentResponse, error) {
+	comment, err := s.getComment(taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+	return s.commentToResponse(comment)
+}
+
+func (s *CommentService) CreateComment(taskID string, req *model.CreateCommentRequest) (*model.CommentResponse, error) {
+	task, err := s.getTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	authorType, authorID := req.AuthorType, req.AuthorID
+	if authorType == "" {
+		authorType = model.CommentAuthorUser
+	}
+	if authorType == model.CommentAuthorAgent {
+		if authorID == "" {
+			return nil, ErrInvalidCommentAuthor
+		}
+		var count int64
+		if err := s.db.GetDB().Model(&database.Agent{}).Where("id = ?", authorID).Count(&count).Error; err != nil {
+			s.logger.Error("Failed to check comment author", zap.Error(err), zap.String("agent_id", authorID))
+			return nil, err
+		}
+		if count == 0 {
+			return nil, ErrInvalidCommentAuthor
+		}
+	}
+
+	now := time.Now()
+	comment := database.Comment{
+		ID:         uuid.New().String(),
+		TaskID:     taskID,
+		AuthorType: authorType,
+		AuthorID:   authorID,
+		Body:       req.Body,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	// The task's comment_count changes, so its version moves on
+	if err := s.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&comment).Error; err != nil {
+			return err
+		}
+		return store.NewGormStore(tx).Tasks().BumpVersions([]string{taskID})
+	}); err != nil {
+		s.logger.Error("Failed to create comment", zap.Error(err), zap.String("task_id", taskID))
+		return nil, err
+	}
+
+	response := &model.CommentResponse{
+		ID:         comment.ID,
+		TaskID:     comment.TaskID,
+		AuthorType: comment.AuthorType,
+		AuthorID:   comment.AuthorID,
+		Body:       comment.Body,
+		CreatedAt:  comment.CreatedAt,
+		UpdatedAt:  comment.UpdatedAt,
+	}
+
+	s.logger.Info("Comment created", zap.String("id", comment.ID), zap.String("task_id", taskID))
+	s.publish(events.CommentCreated, task, comment.ID, response)
+	return response, nil
+}
+
+// UpdateComment replaces the body of a comment. The previous body is kept
+// in the edit history.
+func (s *CommentService) UpdateComment(taskID, commentID string, req *model.UpdateCommentRequest) (*model.CommentResponse, error) {
+	task, err := s.getTask(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	comment, err := s.getComment(taskID, commentID)
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Body == comment.Body {
+		return s.commentToResponse(comment)
+	}
+
+	now := time.Now()
+	revision := database.CommentRevision{
+		ID:        uuid.New().String(),
+		CommentID: comment.ID,
+		Body:      comment.Body,
+		CreatedAt: now,
+	}
+
+	if err := s.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+		return tx.Model(comment).Updates(map[string]interface{}{
+			"body":       req.Body,
+			"edited_at":  now,
+			"updated_at": now,
+		}).Error
+	}); err != nil {
+		s.logger.Error("Failed to update comment", zap.Error(err), zap.String("id", commentID))
+		return nil, err
+	}
+
+	comment.Body = req.Body
+	comment.EditedAt = &now
+	comment.UpdatedAt = now
+
+	response, err := s.commentToResponse(comment)
+	if err != nil {
+		return nil, err
+	}
+
+	s.publish(events.CommentUpdated, task, comment.ID, response)
+	return response, nil
+}
+
+// DeleteComment deletes a comment together with its edit history.
+func (s *CommentService) DeleteComment(taskID, commentID string) error {
+	task, err := s.getTask(taskID)
+	if err != nil {
+		return err
+	}
+
+	var result *gorm.DB
+	if err := s.db.GetDB().Transaction(func(tx *gorm.DB) error {
+		result = tx.Delete(&database.Comment{}, "id = ? AND task_id = ?", commentID, taskID)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		if err := tx.Delete(&database.CommentRevision{}, "comment_id = ?", commentID).Error; err != nil {
+			return err
+		}
+		return store.NewGormStore(tx).Tasks().BumpVersions([]string{taskID})
+	}); err != nil {
+		s.logger.Error("Failed to delete comment", zap.Error(err), zap.String("id", commentID))
+		return err
+	}
+
+	if result.RowsAffected == 0 {
+		return gorm.ErrRecordNotFound
+	}
+
+	s.publish(events.CommentDeleted, task, commentID, nil)
+	return nil
+}
+
+// GetCommentRevisions lists the earlier bodies of a comment, newest first.
+func (s *CommentService) GetCommentRevisions(taskID, commentID string) (*model.CommentRevisionListResponse, error) {
+	if _, err := s.getComment(taskID, commentID); err != nil {
+		return nil, err
+	}
+
+	var revisions []database.CommentRevision
+	if err := s.db.GetDB().Where("comment_id = ?", commentID).Order("created_at DESC").Find(&revisions).Error; err != nil {
+		s.logger.Error("Failed to get comment revisions", zap.Error(err), zap.String("id", commentID))
+		return nil, err
+	}
+
+	responses := make([]model.CommentRevision, len(revisions))
+	for i, revision := range revisions {
+		responses[i] = model.CommentRevision{
+			ID:         revision.ID,
+			Body:       revision.Body,
+			ReplacedAt: revision.CreatedAt,
+		}
+	}
+
+	return &model.CommentRevisionListResponse{
+		CommentID: commentID,
+		Revisions: responses,
+		Total:     int64(len(responses)),
+	}, nil
+}
+
+func (s *CommentService) getTask(taskID string) (*database.Task, error) {
+	var task database.Task
+	if err := s.db.GetDB().Select("id", "project_id").First(&task, "id = ?", taskID).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			s.logger.Error("Failed to get task", zap.Error(err), zap.String("task_id", taskID))
+		}
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (s *CommentService) getComment(taskID, commentID string) (*database.Comment, error) {
+	var comment database.Comment
+	if err := s.db.GetDB().First(&comment, "id = ? AND task_id = ?", commentID, taskID).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			s.logger.Error("Failed to get comment", zap.Error(err), zap.String("id", commentID))
+		}
+		return nil, err
+	}
+	return &comment, nil
+}
+
+func (s *CommentService) publish(eventType string, task *database.Task, commentID string, data interface{}) {
+	s.events.Publish(events.Event{
+		Type:      eventType,
+		EntityID:  commentID,
+		ProjectID: task.ProjectID,
+		Data:      data,
+	})
+}
+
+func (s *CommentService) commentToResponse(comment *database.Comment) (*model.CommentResponse, error) {
+	responses, err := s.commentsToResponses([]database.Comment{*comment})
+	if err != nil {
+		return nil, err
+	}
+	return &responses[0], nil
+}
+
+// commentsToResponses converts comments, counting the edit history of each.
+func (s *CommentService) commentsToResponses(comments []database.Comment) ([]model.CommentResponse, error) {
+	responses := make([]model.CommentResponse, len(comments))
+	if len(comments) == 0 {
+		return responses, nil
+	}
+
+	ids := make([]string, len(comments))
+	for i := range comments {
+		ids[i] = comments[i].ID
+	}
+
+	var rows []struct {
+		CommentID string
+		Count     int64
+	}
+	if err := s.db.GetDB().Model(&database.CommentRevision{}).
+		Select("comment_id, COUNT(*) AS count").
+		Where("comment_id IN ?", ids).
+		Group("comment_id").
+		Scan(&rows).Error; err != nil {
+		s.logger.Error("Failed to count comment revisions", zap.Error(err))
+		return nil, err
+	}
+
+	revisions := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		revisions[row.CommentID] = row.Count
+	}
+
+	for i, comment := range comments {
+		responses[i] = model.CommentResponse{
+			ID:         comment.ID,
+			TaskID:     comment.TaskID,
+			AuthorType: comment.AuthorType,
+			AuthorID:   comment.AuthorID,
+			Body:       comment.Body,
+			EditedAt:   comment.EditedAt,
+			Revisions:  revisions[comment.ID],
+			CreatedAt:  comment.CreatedAt,
+			UpdatedAt:  comment.UpdatedAt,
+		}
+	}
+	return responses, nil
+}
+
+// attachCommentCounts fills in the number of comments on each task.
+func (s *TaskService) attachCommentCounts(tasks []model.TaskResponse) error {
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+
+	counts, err := s.stores.Tasks().CommentCounts(ids)
+	if err != nil {
+		s.logger.Error("Failed to count comments", zap.Error(err))
+		return err
+	}
+	for i := range tasks {
+		tasks[i].CommentCount = counts[tasks[i].ID]
+	}
+	return nil
+}
@@ -0,0 +1,233 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var (
+	ErrDependencyNotFound = errors.New("dependency task does not exist")
+	ErrDependencyCycle    = errors.New("dependency would create a cycle")
+)
+
+// BlockedError reports a task that cannot start because tasks it depends
+// on are not finished yet.
+type BlockedError struct {
+	BlockedBy []string
+}
+
+func (e *BlockedError) Error() string {
+	return fmt.Sprintf("task is blocked by unfinished tasks: %s", strings.Join(e.BlockedBy, ", "))
+}
+
+// GetDependencies lists the tasks a task depends on. It returns nil when the
+// task does not exist.
+func (s *TaskService) GetDependencies(taskID string) (*model.TaskListResponse, error) {
+	exists, err := s.taskExists(taskID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	dbTasks, err := s.stores.Dependencies().Tasks(taskID)
+	if err != nil {
+		s.logger.Error("Failed to get dependencies", zap.Error(err), zap.String("id", taskID))
+		return nil, err
+	}
+
+	tasks := make([]model.TaskResponse, len(dbTasks))
+	for i, dbTask := range dbTasks {
+		tasks[i] = *s.dbTaskToResponse(&dbTask)
+	}
+	if err := s.enrichTasks(tasks); err != nil {
+		return nil, err
+	}
+
+	return &model.TaskListResponse{
+		Tasks: tasks,
+		Total: int64(len(tasks)),
+	}, nil
+}
+
+// AddDependency makes a task wait for another one. It returns nil when the
+// task does not exist.
+func (s *TaskService) AddDependency(taskID string, req *model.AddDependencyRequest, opts MutationOptions) (*model.TaskResponse, error) {
+	exists, err := s.taskExists(taskID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	dependsOn, err := s.taskExists(req.DependsOnID)
+	if err != nil {
+		return nil, err
+	}
+	if !dependsOn {
+		return nil, ErrDependencyNotFound
+	}
+
+	if err := s.stores.Transaction(func(st store.Store) error {
+		reachable, err := dependsTransitively(st, req.DependsOnID, taskID)
+		if err != nil {
+			return err
+		}
+		if reachable {
+			return ErrDependencyCycle
+		}
+
+		added, err := st.Dependencies().Add(&database.TaskDependency{
+			TaskID:      taskID,
+			DependsOnID: req.DependsOnID,
+			CreatedAt:   time.Now(),
+		})
+		if err != nil || !added {
+			return err
+		}
+		return recordDependencyActivity(st, taskID, opts.Actor, nil, req.DependsOnID)
+	}); err != nil {
+		if err != ErrDependencyCycle {
+			s.logger.Error("Failed to add dependency", zap.Error(err), zap.String("id", taskID))
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Dependency added", zap.String("id", taskID), zap.String("depends_on_id", req.DependsOnID))
+	return s.publishTaskUpdated(taskID)
+}
+
+// RemoveDependency stops a task from waiting for another one.
+func (s *TaskService) RemoveDependency(taskID, dependsOnID string, opts MutationOptions) error {
+	var removed bool
+	if err := s.stores.Transaction(func(st store.Store) error {
+		var err error
+		if removed, err = st.Dependencies().Remove(taskID, dependsOnID); err != nil || !removed {
+			return err
+		}
+		return recordDependencyActivity(st, taskID, opts.Actor, dependsOnID, nil)
+	}); err != nil {
+		s.logger.Error("Failed to remove dependency", zap.Error(err), zap.String("id", taskID))
+		return err
+	}
+	if !removed {
+		return store.ErrNotFound
+	}
+
+	s.logger.Info("Dependency removed", zap.String("id", taskID), zap.String("depends_on_id", dependsOnID))
+	_, err := s.publishTaskUpdated(taskID)
+	return err
+}
+
+// recordDependencyActivity records a dependency of a task being added or
+// removed as a change of its depends_on field, moving its version on.
+func recordDependencyActivity(st store.Store, taskID, actor string, before, after interface{}) error {
+	task, err := st.Tasks().Get(taskID)
+	if err != nil {
+		return err
+	}
+	if err := st.Tasks().BumpVersions([]string{taskID}); err != nil {
+		return err
+	}
+	return storeActivity(st, model.EntityTask, taskID, task.ProjectID, model.ActivityUpdated, actor,
+		snapshot{"depends_on": before}, snapshot{"depends_on": after})
+}
+
+// dependsTransitively reports whether from depends on to, directly or
+// through other tasks. Adding a dependency of to on from would then close
+// a cycle.
+func dependsTransitively(st store.Store, from, to string) (bool, error) {
+	seen := map[string]bool{from: true}
+	frontier := []string{from}
+	for len(frontier) > 0 {
+		if seen[to] {
+			return true, nil
+		}
+
+		next, err := st.Dependencies().DependsOn(frontier)
+		if err != nil {
+			return false, err
+		}
+
+		frontier = frontier[:0]
+		for _, id := range next {
+			if !seen[id] {
+				seen[id] = true
+				frontier = append(frontier, id)
+			}
+		}
+	}
+	return seen[to], nil
+}
+
+// blockers lists the unfinished tasks a task depends on.
+func (s *TaskService) blockers(st store.Store, taskID string) ([]string, error) {
+	blockedBy, err := s.blockedBy(st, []string{taskID})
+	if err != nil {
+		return nil, err
+	}
+	return blockedBy[taskID], nil
+}
+
+// blockedBy maps each of the given tasks to the unfinished tasks it
+// depends on. Tasks that are not blocked are left out.
+func (s *TaskService) blockedBy(st store.Store, taskIDs []string) (map[string][]string, error) {
+	final, err := s.finalStatuses()
+	if err != nil {
+		return nil, err
+	}
+
+	blockedBy, err := st.Dependencies().Blocking(taskIDs, final)
+	if err != nil {
+		s.logger.Error("Failed to get blocking tasks", zap.Error(err))
+		return nil, err
+	}
+	return blockedBy, nil
+}
+
+// publishDependentsUpdated notifies subscribers about the tasks waiting for
+// a task, whose blocked_by list changes when it finishes or reopens.
+func (s *TaskService) publishDependentsUpdated(taskID string) {
+	dependents, err := s.stores.Dependencies().Dependents([]string{taskID})
+	if err != nil {
+		s.logger.Warn("Failed to get dependent tasks", zap.Error(err), zap.String("id", taskID))
+		return
+	}
+
+	for _, id := range dependents {
+		if _, err := s.publishTaskUpdated(id); err != nil {
+			s.logger.Warn("Failed to publish dependent task", zap.Error(err), zap.String("id", id))
+		}
+	}
+}
+
+// publishTasksUpdated publishes tasks whose responses changed with another
+// task, such as the parent of a finished subtask.
+func (s *TaskService) publishTasksUpdated(ids []string) {
+	for _, id := range ids {
+		if _, err := s.publishTaskUpdated(id); err != nil {
+			s.logger.Warn("Failed to publish task", zap.Error(err), zap.String("id", id))
+		}
+	}
+}
+
+// publishTaskUpdated reloads a task and publishes it as updated.
+func (s *TaskService) publishTaskUpdated(id string) (*model.TaskResponse, error) {
+	task, err := s.GetTaskByID(id)
+	if err != nil || task == nil {
+		return task, err
+	}
+
+	s.events.Publish(events.Event{
+		Type:      events.TaskUpdated,
+		EntityID:  task.ID,
+		ProjectID: task.ProjectID,
+		Data:      task,
+	})
+	return task, nil
+}
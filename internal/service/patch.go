@@ -0,0 +1,43 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+var ErrInvalidPatch = errors.New("invalid patch")
+
+// patchRequired applies a patch to a field that cannot be cleared.
+func patchRequired(field *string, patch model.Patch[string], name string) error {
+	if !patch.Set {
+		return nil
+	}
+	if patch.Null || patch.Value == "" {
+		return fmt.Errorf("%w: %s cannot be cleared", ErrInvalidPatch, name)
+	}
+	*field = patch.Value
+	return nil
+}
+
+// patchString applies a patch to an optional text field; null clears it.
+func patchString(field *string, patch model.Patch[string]) {
+	if patch.Set {
+		*field = patch.Value
+	}
+}
+
+// patchReference applies a patch to an optional ID; null and the empty
+// string both clear it.
+func patchReference(field **string, patch model.Patch[string]) {
+	if !patch.Set {
+		return
+	}
+	if patch.Null || patch.Value == "" {
+		*field = nil
+		return
+	}
+	value := patch.Value
+	*field = &value
+}
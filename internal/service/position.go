@@ -0,0 +1,295 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+// Positions order tasks within a board column (a project and status). They
+// are strings of rankDigits compared lexicographically, read as base 36
+// fractions, so a task can always be placed between two others by changing
+// its own position only. Positions never end in the lowest digit, which
+// keeps room in front of every position. Lower case letters and digits
+// sort the same under every collation.
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+
+var ErrInvalidMove = errors.New("invalid move")
+
+// maxRankLength bounds positions. Appending to a column lengthens the
+// positions at its end by a digit every 35 or so tasks, and placing tasks
+// between the same two over and over does too; a column whose positions
+// would grow longer than this is spread out again.
+const maxRankLength = 8
+
+// MoveTask places a task in a column, optionally changing its status. The
+// task lands between the given neighbors: after AfterID, before BeforeID,
+// or at the end of the column when neither is set. It returns nil when the
+// task does not exist.
+func (s *TaskService) MoveTask(id string, req *model.MoveTaskRequest, opts MutationOptions) (*model.TaskResponse, error) {
+	var update *taskUpdate
+	if err := s.stores.Transaction(func(st store.Store) error {
+		task, err := st.Tasks().Get(id)
+		if err != nil {
+			if err == store.ErrNotFound {
+				return nil
+			}
+			s.logger.Error("Failed to get task for move", zap.Error(err), zap.String("id", id))
+			return err
+		}
+
+		status := req.Status
+		if status == "" {
+			status = task.Status
+		}
+
+		// The neighbors are read in the transaction writing the position,
+		// so concurrent moves do not pick the same one
+		position, spread, err := positionBetween(st, task.ProjectID, status, id, req.AfterID, req.BeforeID)
+		if err != nil {
+			return err
+		}
+
+		update, err = s.updateTask(st, id, (&model.UpdateTaskRequest{Status: req.Status, Position: position}).Patch(), opts)
+		if update != nil {
+			update.spread = append(update.spread, spread...)
+		}
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if update == nil {
+		return nil, nil
+	}
+
+	return s.finishUpdate(update, opts)
+}
+
+// positionBetween picks a position between two tasks of a column. A missing
+// neighbor is taken from the column itself, skipping the task being moved
+// and tasks not positioned yet. It also returns the tasks spread out to make
+// room, see placeInColumn.
+func positionBetween(st store.Store, projectID, status, movingID, afterID, beforeID string) (string, []string, error) {
+	tasks, err := st.Tasks().Column(projectID, status)
+	if err != nil {
+		return "", nil, err
+	}
+	column := make([]database.Task, 0, len(tasks))
+	index := make(map[string]int, len(tasks))
+	for i := range tasks {
+		if tasks[i].ID == movingID {
+			continue
+		}
+		index[tasks[i].ID] = len(column)
+		column = append(column, tasks[i])
+	}
+
+	neighbor := func(neighborID string) (int, error) {
+		if neighborID == movingID {
+			return 0, fmt.Errorf("%w: a task cannot be placed next to itself", ErrInvalidMove)
+		}
+		i, ok := index[neighborID]
+		if !ok {
+			return 0, fmt.Errorf("%w: task %s is not in the target column", ErrInvalidMove, neighborID)
+		}
+		return i, nil
+	}
+
+	// The task goes in front of the task at slot
+	slot := len(column)
+	switch {
+	case afterID != "" && beforeID != "":
+		a, err := neighbor(afterID)
+		if err != nil {
+			return "", nil, err
+		}
+		b, err := neighbor(beforeID)
+		if err != nil {
+			return "", nil, err
+		}
+		if a >= b {
+			return "", nil, fmt.Errorf("%w: task %s is not above task %s", ErrInvalidMove, afterID, beforeID)
+		}
+		slot = a + 1
+	case afterID != "":
+		a, err := neighbor(afterID)
+		if err != nil {
+			return "", nil, err
+		}
+		slot = a + 1
+	case beforeID != "":
+		b, err := neighbor(beforeID)
+		if err != nil {
+			return "", nil, err
+		}
+		slot = b
+	}
+
+	return placeInColumn(st, column, slot)
+}
+
+// lastPosition returns a position after every task of a column, together
+// with the tasks spread out to make room, see placeInColumn.
+func lastPosition(st store.Store, projectID, status string) (string, []string, error) {
+	last, err := st.Tasks().LastPosition(projectID, status)
+	if err != nil {
+		return "", nil, err
+	}
+	if position := rankBetween(last, ""); len(position) <= maxRankLength {
+		return position, nil, nil
+	}
+
+	column, err := st.Tasks().Column(projectID, status)
+	if err != nil {
+		return "", nil, err
+	}
+	return placeInColumn(st, column, len(column))
+}
+
+// placeInColumn returns a position in front of the task at slot of a
+// column, which is given by position without the task being placed. When
+// its neighbors leave no room, as tasks tied by concurrent moves do, or the
+// position would grow longer than maxRankLength, the column is spread out
+// evenly in st first. The tasks moved that way count as changed; their IDs
+// are returned for publishing.
+func placeInColumn(st store.Store, column []database.Task, slot int) (string, []string, error) {
+	var after, before string
+	if slot > 0 {
+		after = column[slot-1].Position
+	}
+	if slot < len(column) {
+		before = column[slot].Position
+	}
+	if before == "" || after < before {
+		if position := rankBetween(after, before); len(position) <= maxRankLength {
+			return position, nil, nil
+		}
+	}
+
+	ranks := spreadRanks(len(column) + 1)
+	var spread []string
+	for i := range column {
+		rank := ranks[i]
+		if i >= slot {
+			rank = ranks[i+1]
+		}
+		if rank == column[i].Position {
+			continue
+		}
+		if err := st.Tasks().SetPosition(column[i].ID, rank); err != nil {
+			return "", nil, err
+		}
+		spread = append(spread, column[i].ID)
+	}
+	if len(spread) > 0 {
+		if err := st.Tasks().BumpVersions(spread); err != nil {
+			return "", nil, err
+		}
+	}
+	return ranks[slot], spread, nil
+}
+
+// AssignMissingPositions gives tasks created before positions existed a
+// place at the end of their column, oldest first.
+func (s *TaskService) AssignMissingPositions() error {
+	tasks, err := s.stores.Tasks().Unpositioned()
+	if err != nil {
+		s.logger.Error("Failed to load tasks without position", zap.Error(err))
+		return err
+	}
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	return s.stores.Transaction(func(st store.Store) error {
+		for start := 0; start < len(tasks); {
+			end := start
+			for end < len(tasks) && tasks[end].ProjectID == tasks[start].ProjectID && tasks[end].Status == tasks[start].Status {
+				end++
+			}
+
+			position, _, err := lastPosition(st, tasks[start].ProjectID, tasks[start].Status)
+			if err != nil {
+				return err
+			}
+			for i := start; i < end; i++ {
+				if err := st.Tasks().SetPosition(tasks[i].ID, position); err != nil {
+					return err
+				}
+				position = rankBetween(position, "")
+			}
+			start = end
+		}
+
+		s.logger.Info("Assigned task positions", zap.Int("count", len(tasks)))
+		return nil
+	})
+}
+
+// rankBetween returns a position sorting after a and before b. An empty a
+// means the start of the column, an empty b its end. Positions at the end
+// grow by the smallest step, incrementing the first digit that has room
+// rather than adding one. Once every digit of a is the highest one a
+// position still gains a character, which is what maxRankLength bounds.
+func rankBetween(a, b string) string {
+	base := len(rankDigits)
+	appending := a != "" && b == ""
+	upperOpen := b == ""
+
+	var rank strings.Builder
+	for i := 0; ; i++ {
+		low := 0
+		if i < len(a) {
+			low = strings.IndexByte(rankDigits, a[i])
+		}
+		high := base
+		if !upperOpen && i < len(b) {
+			high = strings.IndexByte(rankDigits, b[i])
+		}
+
+		switch {
+		case high-low > 1 && appending:
+			rank.WriteByte(rankDigits[low+1])
+			return rank.String()
+		case high-low > 1:
+			rank.WriteByte(rankDigits[(low+high)/2])
+			return rank.String()
+		case high-low == 1:
+			// No digit fits here; keep a's digit and look further down,
+			// where everything above a sorts before b
+			rank.WriteByte(rankDigits[low])
+			upperOpen = true
+		default:
+			rank.WriteByte(rankDigits[low])
+		}
+	}
+}
+
+// spreadRanks returns n positions spread evenly over the whole range, as
+// short as n allows.
+func spreadRanks(n int) []string {
+	base := len(rankDigits)
+	width, span := 1, base
+	for span <= n {
+		width++
+		span *= base
+	}
+
+	ranks := make([]string, n)
+	digits := make([]byte, width)
+	for i := range ranks {
+		value := (i + 1) * span / (n + 1)
+		for j := width - 1; j >= 0; j-- {
+			digits[j] = rankDigits[value%base]
+			value /= base
+		}
+		ranks[i] = strings.TrimRight(string(digits), rankDigits[:1])
+	}
+	return ranks
+}
@@ -0,0 +1,94 @@
+package service
+
+import (
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+func TestSpreadRanks(t *testing.T) {
+	for _, n := range []int{1, 2, 35, 36, 1000} {
+		ranks := spreadRanks(n)
+		for i, rank := range ranks {
+			if rank == "" || strings.HasSuffix(rank, rankDigits[:1]) {
+				t.Fatalf("spreadRanks(%d)[%d] = %q", n, i, rank)
+			}
+			if i > 0 && ranks[i-1] >= rank {
+				t.Fatalf("spreadRanks(%d) out of order at %d: %q, %q", n, i, ranks[i-1], rank)
+			}
+		}
+	}
+}
+
+// columnOrder returns the task IDs of a column by position.
+func columnOrder(t *testing.T, s *testServices, projectID, status string) []string {
+	t.Helper()
+	tasks, err := s.tasks.stores.Tasks().Column(projectID, status)
+	if err != nil {
+		t.Fatalf("Column: %v", err)
+	}
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+		if len(tasks[i].Position) > maxRankLength {
+			t.Errorf("position %q longer than %d", tasks[i].Position, maxRankLength)
+		}
+	}
+	return ids
+}
+
+func TestAppendKeepsPositionsShort(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		var want []string
+		for i := 0; i < 300; i++ {
+			want = append(want, s.task(t, &model.CreateTaskRequest{Title: "t", ProjectID: project.ID}).ID)
+		}
+
+		got := columnOrder(t, s, project.ID, model.TaskStatusTodo)
+		if strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("column order changed by spreading it out")
+		}
+	})
+}
+
+func TestMoveTaskBetweenTiedTasks(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		moving := s.task(t, &model.CreateTaskRequest{Title: "moving", ProjectID: project.ID})
+		tied := []string{
+			s.task(t, &model.CreateTaskRequest{Title: "first", ProjectID: project.ID}).ID,
+			s.task(t, &model.CreateTaskRequest{Title: "second", ProjectID: project.ID}).ID,
+		}
+		// Concurrent moves can leave two tasks on the same position
+		for _, id := range tied {
+			if err := s.tasks.stores.Tasks().SetPosition(id, "t"); err != nil {
+				t.Fatalf("SetPosition: %v", err)
+			}
+		}
+		sort.Strings(tied)
+		before, err := s.tasks.GetTaskByID(tied[1])
+		if err != nil {
+			t.Fatalf("GetTaskByID: %v", err)
+		}
+
+		if _, err := s.tasks.MoveTask(moving.ID, &model.MoveTaskRequest{AfterID: tied[0], BeforeID: tied[1]}, MutationOptions{}); err != nil {
+			t.Fatalf("MoveTask: %v", err)
+		}
+		got := columnOrder(t, s, project.ID, model.TaskStatusTodo)
+		if want := []string{tied[0], moving.ID, tied[1]}; strings.Join(got, ",") != strings.Join(want, ",") {
+			t.Errorf("column = %v, want %v", got, want)
+		}
+		after, err := s.tasks.GetTaskByID(tied[1])
+		if err != nil {
+			t.Fatalf("GetTaskByID: %v", err)
+		}
+		if after.Version <= before.Version {
+			t.Errorf("version of a task spread out = %d, want it moved on from %d", after.Version, before.Version)
+		}
+	})
+}
@@ -1,93 +1,102 @@
 package service
 
 import (
+	"errors"
+	"fmt"
 	"time"
 
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 
 	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
 	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var (
+	ErrProjectHasTasks      = errors.New("project still has tasks")
+	ErrInvalidProjectDelete = errors.New("invalid project delete")
 )
 
 type ProjectService struct {
-	db     *database.Database
-	logger *zap.Logger
+	stores      store.Store
+	taskService *TaskService
+	events      *events.Bus
+	logger      *zap.Logger
 }
 
-func NewProjectService(db *database.Database, logger *zap.Logger) *ProjectService {
+func NewProjectService(stores store.Store, taskService *TaskService, bus *events.Bus, logger *zap.Logger) *ProjectService {
 	return &ProjectService{
-		db:     db,
-		logger: logger,
+		stores:      stores,
+		taskService: taskService,
+		events:      bus,
+		logger:      logger,
 	}
 }
 
-func (s *ProjectService) CreateProject(req *model.CreateProjectRequest) (*model.ProjectResponse, error) {
+func (s *ProjectService) CreateProject(req *model.CreateProjectRequest, opts MutationOptions) (*model.ProjectResponse, error) {
 	s.logger.Info("Creating new project", zap.String("name", req.Name))
 
+	agentID := optionalReference(req.AgentID)
+	if err := checkAgent(s.stores, agentID); err != nil {
+		return nil, err
+	}
+
 	project := database.Project{
 		ID:          uuid.New().String(),
 		Name:        req.Name,
 		Description: req.Description,
 		Directory:   req.Directory,
-		AgentID:     req.AgentID,
+		BaseBranch:  req.BaseBranch,
+		AgentID:     agentID,
+		Version:     1,
 		CreatedAt:   time.Now(),
 		UpdatedAt:   time.Now(),
 	}
 
-	if err := s.db.GetDB().Create(&project).Error; err != nil {
+	if err := s.stores.Transaction(func(st store.Store) error {
+		if err := st.Projects().Create(&project); err != nil {
+			return err
+		}
+		return storeActivity(st, model.EntityProject, project.ID, project.ID, model.ActivityCreated, opts.Actor,
+			nil, projectSnapshot(&project))
+	}); err != nil {
 		s.logger.Error("Failed to create project", zap.Error(err))
 		return nil, err
 	}
 
 	// Load project with agent for response
 	s.logger.Info("Project created successfully", zap.String("id", project.ID))
-	return s.GetProject(project.ID)
+	response, err := s.GetProject(project.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(events.Event{
+		Type:      events.ProjectCreated,
+		EntityID:  response.ID,
+		ProjectID: response.ID,
+		Data:      response,
+	})
+	return response, nil
 }
 
-func (s *ProjectService) GetProjects() (*model.ProjectListResponse, error) {
+func (s *ProjectService) GetProjects(query *model.ProjectListQuery) (*model.ProjectListResponse, error) {
 	s.logger.Info("Getting all projects")
 
-	var projects []database.Project
-	var total int64
-
-	if err := s.db.GetDB().Model(&database.Project{}).Count(&total).Error; err != nil {
-		s.logger.Error("Failed to count projects", zap.Error(err))
-		return nil, err
-	}
-
-	if err := s.db.GetDB().Preload("Agent").Find(&projects).Error; err != nil {
+	projects, err := s.stores.Projects().List(query.IncludeArchived)
+	if err != nil {
 		s.logger.Error("Failed to get projects", zap.Error(err))
 		return nil, err
 	}
 
 	var projectResponses []model.ProjectResponse
-	for _, project := range projects {
-		var agent *model.Agent
-		if project.Agent != nil {
-			agent = &model.Agent{
-				ID:          project.Agent.ID,
-				Name:        project.Agent.Name,
-				Type:        project.Agent.Type,
-				Description: project.Agent.Description,
-				CreatedAt:   project.Agent.CreatedAt,
-				UpdatedAt:   project.Agent.UpdatedAt,
-			}
-		}
-
-		projectResponses = append(projectResponses, model.ProjectResponse{
-			ID:          project.ID,
-			Name:        project.Name,
-			Description: project.Description,
-			Directory:   project.Directory,
-			AgentID:     project.AgentID,
-			Agent:       agent,
-			CreatedAt:   project.CreatedAt,
-			UpdatedAt:   project.UpdatedAt,
-		})
+	for i := range projects {
+		projectResponses = append(projectResponses, *projectResponse(&projects[i]))
 	}
 
+	total := int64(len(projects))
 	response := &model.ProjectListResponse{
 		Projects: projectResponses,
 		Total:    total,
@@ -100,9 +109,9 @@ func (s *ProjectService) GetProjects() (*model.ProjectListResponse, error) {
 func (s *ProjectService) GetProject(id string) (*model.ProjectResponse, error) {
 	s.logger.Info("Getting project", zap.String("id", id))
 
-	var project database.Project
-	if err := s.db.GetDB().Preload("Agent").First(&project, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	project, err := s.stores.Projects().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			s.logger.Warn("Project not found", zap.String("id", id))
 			return nil, err
 		}
@@ -110,39 +119,23 @@ func (s *ProjectService) GetProject(id string) (*model.ProjectResponse, error) {
 		return nil, err
 	}
 
-	var agent *model.Agent
-	if project.Agent != nil {
-		agent = &model.Agent{
-			ID:          project.Agent.ID,
-			Name:        project.Agent.Name,
-			Type:        project.Agent.Type,
-			Description: project.Agent.Description,
-			CreatedAt:   project.Agent.CreatedAt,
-			UpdatedAt:   project.Agent.UpdatedAt,
-		}
-	}
-
-	response := &model.ProjectResponse{
-		ID:          project.ID,
-		Name:        project.Name,
-		Description: project.Description,
-		Directory:   project.Directory,
-		AgentID:     project.AgentID,
-		Agent:       agent,
-		CreatedAt:   project.CreatedAt,
-		UpdatedAt:   project.UpdatedAt,
-	}
+	response := projectResponse(project)
 
 	s.logger.Info("Project retrieved successfully", zap.String("id", id))
 	return response, nil
 }
 
-func (s *ProjectService) UpdateProject(id string, req *model.UpdateProjectRequest) (*model.ProjectResponse, error) {
+func (s *ProjectService) UpdateProject(id string, req *model.UpdateProjectRequest, opts MutationOptions) (*model.ProjectResponse, error) {
+	return s.PatchProject(id, req.Patch(), opts)
+}
+
+// PatchProject applies a JSON merge patch to a project.
+func (s *ProjectService) PatchProject(id string, req *model.PatchProjectRequest, opts MutationOptions) (*model.ProjectResponse, error) {
 	s.logger.Info("Updating project", zap.String("id", id))
 
-	var project database.Project
-	if err := s.db.GetDB().First(&project, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	project, err := s.stores.Projects().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			s.logger.Warn("Project not found", zap.String("id", id))
 			return nil, err
 		}
@@ -150,48 +143,230 @@ func (s *ProjectService) UpdateProject(id string, req *model.UpdateProjectReques
 		return nil, err
 	}
 
+	before := projectSnapshot(project)
+
 	// Update fields
-	if req.Name != "" {
-		project.Name = req.Name
-	}
-	if req.Description != "" {
-		project.Description = req.Description
+	if err := patchRequired(&project.Name, req.Name, "name"); err != nil {
+		return nil, err
 	}
-	if req.Directory != "" {
-		project.Directory = req.Directory
+	if err := patchRequired(&project.Directory, req.Directory, "directory"); err != nil {
+		return nil, err
 	}
-	if req.AgentID != nil {
-		project.AgentID = req.AgentID
+	patchString(&project.Description, req.Description)
+	patchString(&project.BaseBranch, req.BaseBranch)
+	patchReference(&project.AgentID, req.AgentID)
+	if req.Archived != nil {
+		project.ArchivedAt = archivedAt(project.ArchivedAt, *req.Archived)
 	}
 	project.UpdatedAt = time.Now()
 
-	if err := s.db.GetDB().Save(&project).Error; err != nil {
-		s.logger.Error("Failed to update project", zap.Error(err))
+	if err := checkVersion(project.Version, opts); err != nil {
+		return nil, err
+	}
+	if err := s.stores.Transaction(func(st store.Store) error {
+		if req.AgentID.Set {
+			if err := checkAgent(st, project.AgentID); err != nil {
+				return err
+			}
+		}
+
+		if err := st.Projects().Update(project); err != nil {
+			return err
+		}
+		return storeActivity(st, model.EntityProject, project.ID, project.ID, model.ActivityUpdated, opts.Actor,
+			before, projectSnapshot(project))
+	}); err != nil {
+		if err != ErrVersionMismatch && !errors.Is(err, ErrAgentNotFound) {
+			s.logger.Error("Failed to update project", zap.Error(err))
+		}
 		return nil, err
 	}
 
 	s.logger.Info("Project updated successfully", zap.String("id", id))
-	return s.GetProject(id)
+	return s.publishProjectUpdated(id)
 }
 
-func (s *ProjectService) DeleteProject(id string) error {
+func (s *ProjectService) publishProjectUpdated(id string) (*model.ProjectResponse, error) {
+	response, err := s.GetProject(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(events.Event{
+		Type:      events.ProjectUpdated,
+		EntityID:  response.ID,
+		ProjectID: response.ID,
+		Data:      response,
+	})
+	return response, nil
+}
+
+// unassignAgent clears a deleted agent from the projects using it as their
+// default agent. It returns the IDs of the changed projects.
+func (s *ProjectService) unassignAgent(st store.Store, agentID string, opts MutationOptions) ([]string, error) {
+	projects, err := st.Projects().List(true)
+	if err != nil {
+		return nil, err
+	}
+
+	var ids []string
+	for i := range projects {
+		project := &projects[i]
+		if project.AgentID == nil || *project.AgentID != agentID {
+			continue
+		}
+		before := projectSnapshot(project)
+		project.AgentID = nil
+		project.Agent = nil
+		project.UpdatedAt = time.Now()
+		if err := st.Projects().Update(project); err != nil {
+			return nil, err
+		}
+		if err := storeActivity(st, model.EntityProject, project.ID, project.ID, model.ActivityUpdated, opts.Actor,
+			before, projectSnapshot(project)); err != nil {
+			return nil, err
+		}
+		ids = append(ids, project.ID)
+	}
+	return ids, nil
+}
+
+// DeleteProject moves a project to the trash. Its tasks are kept from being
+// orphaned as the query selects: their presence refuses the delete, they are
+// trashed along with the project, or they are moved to another project first.
+func (s *ProjectService) DeleteProject(id string, query *model.DeleteProjectQuery, opts MutationOptions) error {
 	s.logger.Info("Deleting project", zap.String("id", id))
 
-	var project database.Project
-	if err := s.db.GetDB().First(&project, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	onDelete := query.OnDelete
+	if onDelete == "" {
+		onDelete = model.ProjectDeleteRestrict
+	}
+	switch {
+	case onDelete == model.ProjectDeleteMove && query.TargetProjectID == "":
+		return fmt.Errorf("%w: target_project_id is required to move tasks", ErrInvalidProjectDelete)
+	case onDelete == model.ProjectDeleteMove && query.TargetProjectID == id:
+		return fmt.Errorf("%w: tasks cannot be moved to the project being deleted", ErrInvalidProjectDelete)
+	case onDelete != model.ProjectDeleteMove && query.TargetProjectID != "":
+		return fmt.Errorf("%w: target_project_id only applies to on_delete=move", ErrInvalidProjectDelete)
+	}
+
+	project, err := s.stores.Projects().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			s.logger.Warn("Project not found", zap.String("id", id))
 			return err
 		}
 		s.logger.Error("Failed to find project", zap.Error(err))
 		return err
 	}
+	if err := checkVersion(project.Version, opts); err != nil {
+		return err
+	}
+
+	// Task changes are made by the same actor, whatever version the
+	// project was matched against
+	taskOpts := MutationOptions{Actor: opts.Actor}
+	now := time.Now()
+	batch := uuid.New().String() // Shared by the project and the tasks trashed with it
+	var deletions []*taskDeletion
+	var updates []*taskUpdate
+
+	if err := s.stores.Transaction(func(st store.Store) error {
+		tasks, err := st.Tasks().Find(store.TaskFilter{ProjectID: id, IncludeArchived: true},
+			store.TaskPage{Sort: "created_at"})
+		if err != nil {
+			return err
+		}
+		taskIDs := make([]string, len(tasks))
+		for i := range tasks {
+			taskIDs[i] = tasks[i].ID
+		}
 
-	if err := s.db.GetDB().Delete(&project).Error; err != nil {
-		s.logger.Error("Failed to delete project", zap.Error(err))
+		switch onDelete {
+		case model.ProjectDeleteRestrict:
+			if len(taskIDs) > 0 {
+				return fmt.Errorf("%w (%d), pass on_delete=cascade or on_delete=move", ErrProjectHasTasks, len(taskIDs))
+			}
+		case model.ProjectDeleteCascade:
+			// Subtasks go along with their deleted parents
+			deleted := map[string]bool{}
+			for _, taskID := range taskIDs {
+				if deleted[taskID] {
+					continue
+				}
+				deletion, err := s.taskService.deleteTask(st, taskID, now, batch, taskOpts)
+				if err != nil {
+					return err
+				}
+				for _, task := range deletion.deleted {
+					deleted[task.ID] = true
+				}
+				deletions = append(deletions, deletion)
+			}
+		case model.ProjectDeleteMove:
+			if err := checkProject(st, query.TargetProjectID); err != nil {
+				return err
+			}
+			for _, taskID := range taskIDs {
+				update, err := s.taskService.updateTask(st, taskID,
+					&model.PatchTaskRequest{ProjectID: model.PatchValue(query.TargetProjectID)}, taskOpts)
+				if err != nil {
+					return err
+				}
+				if update != nil {
+					updates = append(updates, update)
+				}
+			}
+		}
+
+		if err := st.Projects().Trash(project.ID, now, batch); err != nil {
+			return err
+		}
+		return storeActivity(st, model.EntityProject, project.ID, project.ID, model.ActivityDeleted, opts.Actor,
+			projectSnapshot(project), nil)
+	}); err != nil {
+		var statusErr *StatusError
+		if !errors.Is(err, ErrProjectHasTasks) && !errors.Is(err, ErrProjectNotFound) && !errors.As(err, &statusErr) {
+			s.logger.Error("Failed to delete project", zap.Error(err))
+		}
 		return err
 	}
 
-	s.logger.Info("Project deleted successfully", zap.String("id", id))
+	for _, deletion := range deletions {
+		s.taskService.finishDelete(deletion)
+	}
+	for _, update := range updates {
+		if _, err := s.taskService.finishUpdate(update, taskOpts); err != nil {
+			s.logger.Warn("Failed to reload moved task", zap.Error(err), zap.String("id", update.task.ID))
+		}
+	}
+
+	s.logger.Info("Project deleted successfully", zap.String("id", id),
+		zap.String("on_delete", onDelete),
+		zap.Int("deleted_tasks", len(deletions)),
+		zap.Int("moved_tasks", len(updates)))
+	s.events.Publish(events.Event{
+		Type:      events.ProjectDeleted,
+		EntityID:  id,
+		ProjectID: id,
+	})
 	return nil
+}
+
+// projectResponse maps a stored project, loaded with its agent, to its API
+// representation.
+func projectResponse(project *database.Project) *model.ProjectResponse {
+	return &model.ProjectResponse{
+		ID:          project.ID,
+		Name:        project.Name,
+		Description: project.Description,
+		Directory:   project.Directory,
+		BaseBranch:  project.BaseBranch,
+		AgentID:     project.AgentID,
+		Agent:       agentModel(project.Agent),
+		Version:     project.Version,
+		ArchivedAt:  project.ArchivedAt,
+		CreatedAt:   project.CreatedAt,
+		UpdatedAt:   project.UpdatedAt,
+	}
 }
\ No newline at end of file
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var (
+	ErrProjectNotFound = errors.New("project does not exist")
+	ErrAgentNotFound   = errors.New("agent does not exist")
+)
+
+// checkProject verifies that the project a task is put in exists. Tasks
+// without a project have an empty ID.
+func checkProject(st store.Store, id string) error {
+	if id == "" {
+		return nil
+	}
+	_, err := st.Projects().Get(id)
+	return referenceError(err, ErrProjectNotFound, id)
+}
+
+// checkAgent verifies that an assigned agent exists.
+func checkAgent(st store.Store, id *string) error {
+	if id == nil {
+		return nil
+	}
+	_, err := st.Agents().Get(*id)
+	return referenceError(err, ErrAgentNotFound, *id)
+}
+
+func referenceError(err, notFound error, id string) error {
+	if err == store.ErrNotFound {
+		return fmt.Errorf("%w: %s", notFound, id)
+	}
+	return err
+}
+
+// optionalReference turns an empty ID into no reference.
+func optionalReference(id *string) *string {
+	if id == nil || *id == "" {
+		return nil
+	}
+	return id
+}
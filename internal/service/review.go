@@ -0,0 +1,351 @@
+package service
+
+import (
+	"errors"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/git"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var (
+	ErrNoTaskBranch         = errors.New("task has no branch to review")
+	ErrBaseNotCheckedOut    = errors.New("project directory must have the base branch checked out")
+	ErrProjectDirNotClean   = errors.New("project directory has uncommitted changes")
+	ErrInvalidMergeStrategy = errors.New("merge strategy must be merge or squash")
+)
+
+// ReviewService exposes what an agent changed on a task branch and applies
+// the outcome of the review to the project repository.
+type ReviewService struct {
+	stores      store.Store
+	taskService *TaskService
+	worktrees   *WorktreeService
+	logger      *zap.Logger
+}
+
+func NewReviewService(stores store.Store, taskService *TaskService, worktrees *WorktreeService, logger *zap.Logger) *ReviewService {
+	return &ReviewService{
+		stores:      stores,
+		taskService: taskService,
+		worktrees:   worktrees,
+		logger:      logger,
+	}
+}
+
+// GetTaskDiff lists the files changed on the task branch since it diverged
+// from its base branch, with per-file line counts and optionally their hunks.
+func (s *ReviewService) GetTaskDiff(taskID string, withHunks bool) (*model.TaskDiffResponse, error) {
+	task, project, err := s.loadTaskBranch(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := git.Diff(project.Directory, task.BaseBranch, task.Branch)
+	if err != nil {
+		s.logger.Error("Failed to diff task branch", zap.Error(err), zap.String("task_id", taskID))
+		return nil, err
+	}
+
+	response := &model.TaskDiffResponse{
+		TaskID:     task.ID,
+		Branch:     task.Branch,
+		BaseBranch: task.BaseBranch,
+		Files:      make([]model.DiffFile, len(diff)),
+	}
+	for i, file := range diff {
+		response.Files[i] = diffFileToResponse(&file, withHunks)
+		response.Additions += file.Additions
+		response.Deletions += file.Deletions
+	}
+
+	return response, nil
+}
+
+// GetTaskFileDiff returns the unified hunks of a single file on the task branch.
+func (s *ReviewService) GetTaskFileDiff(taskID, path string) (*model.DiffFile, error) {
+	task, project, err := s.loadTaskBranch(taskID)
+	if err != nil {
+		return nil, err
+	}
+
+	diff, err := git.Diff(project.Directory, task.BaseBranch, task.Branch, path)
+	if err != nil {
+		s.logger.Error("Failed to diff task file", zap.Error(err), zap.String("task_id", taskID), zap.String("path", path))
+		return nil, err
+	}
+
+	for _, file := range diff {
+		if file.Path == path || file.OldPath == path {
+			response := diffFileToResponse(&file, true)
+			return &response, nil
+		}
+	}
+
+	return nil, store.ErrNotFound
+}
+
+// MergeTask merges (or squashes) the task branch into the project's base
+// branch, removes the task branch and marks the task done.
+func (s *ReviewService) MergeTask(taskID string, req *model.MergeTaskRequest, opts MutationOptions) (*model.MergeTaskResponse, error) {
+	strategy := req.Strategy
+	if strategy == "" {
+		strategy = model.MergeStrategyMerge
+	}
+	if strategy != model.MergeStrategyMerge && strategy != model.MergeStrategySquash {
+		return nil, ErrInvalidMergeStrategy
+	}
+
+	s.logger.Info("Merging task branch", zap.String("task_id", taskID), zap.String("strategy", strategy))
+
+	task, baseBranch, commit, err := s.mergeTaskBranch(taskID, strategy)
+	if err != nil {
+		return nil, err
+	}
+
+	// Workflows without a done status, or that do not allow reaching it
+	// from the task's current status, leave the status alone. The
+	// repository lock is released by now, finishing the task may clean up
+	// a worktree the merge could not remove.
+	response, err := s.taskService.advanceStatus(task, task.Status, model.TaskStatusDone, opts.Actor)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Task branch merged", zap.String("task_id", taskID), zap.String("commit", commit))
+	return &model.MergeTaskResponse{
+		Task:       response,
+		Strategy:   strategy,
+		BaseBranch: baseBranch,
+		Commit:     commit,
+	}, nil
+}
+
+// mergeTaskBranch does the git side of MergeTask under the repository lock.
+// It returns the merged task, the branch it went into and the resulting
+// commit.
+func (s *ReviewService) mergeTaskBranch(taskID, strategy string) (*database.Task, string, string, error) {
+	task, project, unlock, err := s.lockTaskBranch(taskID)
+	if err != nil {
+		return nil, "", "", err
+	}
+	defer unlock()
+	if err := s.ensureNoActiveRun(taskID); err != nil {
+		return nil, "", "", err
+	}
+
+	baseBranch := project.BaseBranch
+	if baseBranch == "" {
+		baseBranch = task.BaseBranch
+	}
+
+	current, err := git.CurrentBranch(project.Directory)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if current != baseBranch {
+		return nil, "", "", ErrBaseNotCheckedOut
+	}
+	clean, err := git.IsClean(project.Directory)
+	if err != nil {
+		return nil, "", "", err
+	}
+	if !clean {
+		return nil, "", "", ErrProjectDirNotClean
+	}
+
+	if _, err := s.worktrees.commitWorktree(task, commitMessage(task)); err != nil {
+		s.logger.Error("Failed to commit pending worktree changes", zap.Error(err), zap.String("task_id", taskID))
+		return nil, "", "", err
+	}
+
+	if err := git.Merge(project.Directory, task.Branch, commitMessage(task), strategy == model.MergeStrategySquash); err != nil {
+		s.logger.Warn("Failed to merge task branch", zap.Error(err), zap.String("task_id", taskID))
+		return nil, "", "", err
+	}
+
+	commit, err := git.HeadCommit(project.Directory)
+	if err != nil {
+		return nil, "", "", err
+	}
+
+	if err := s.worktrees.removeWorktree(task, project, true); err != nil {
+		s.logger.Warn("Failed to clean up merged task branch", zap.Error(err), zap.String("task_id", taskID))
+	}
+	return task, baseBranch, commit, nil
+}
+
+// RebaseTask replays the task branch onto the latest base branch inside the
+// task worktree, recreating the worktree if it was removed.
+func (s *ReviewService) RebaseTask(taskID string) (*model.TaskResponse, error) {
+	s.logger.Info("Rebasing task branch", zap.String("task_id", taskID))
+
+	task, project, unlock, err := s.lockTaskBranch(taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := s.ensureNoActiveRun(taskID); err != nil {
+		return nil, err
+	}
+
+	baseBranch := project.BaseBranch
+	if baseBranch == "" {
+		baseBranch = task.BaseBranch
+	}
+
+	path, err := s.worktrees.ensureWorktree(task, project)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := s.worktrees.commitWorktree(task, commitMessage(task)); err != nil {
+		s.logger.Error("Failed to commit pending worktree changes", zap.Error(err), zap.String("task_id", taskID))
+		return nil, err
+	}
+
+	if err := git.Rebase(path, baseBranch); err != nil {
+		s.logger.Warn("Failed to rebase task branch", zap.Error(err), zap.String("task_id", taskID))
+		return nil, err
+	}
+
+	s.logger.Info("Task branch rebased", zap.String("task_id", taskID), zap.String("onto", baseBranch))
+	return s.taskService.GetTaskByID(taskID)
+}
+
+// DiscardTask throws away the task's worktree and branch, including any
+// changes an agent made. The task itself is kept.
+func (s *ReviewService) DiscardTask(taskID string) (*model.TaskResponse, error) {
+	s.logger.Info("Discarding task branch", zap.String("task_id", taskID))
+
+	task, project, unlock, err := s.lockTaskBranch(taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer unlock()
+	if err := s.ensureNoActiveRun(taskID); err != nil {
+		return nil, err
+	}
+
+	if err := s.worktrees.removeWorktree(task, project, true); err != nil {
+		return nil, err
+	}
+
+	s.logger.Info("Task branch discarded", zap.String("task_id", taskID))
+	return s.taskService.GetTaskByID(taskID)
+}
+
+func (s *ReviewService) ensureNoActiveRun(taskID string) error {
+	active, err := s.stores.Runs().Active(taskID)
+	if err != nil {
+		s.logger.Error("Failed to check active runs", zap.Error(err))
+		return err
+	}
+	if active {
+		return ErrRunInProgress
+	}
+	return nil
+}
+
+// commitMessage builds the commit message for a task's changes from its title
+// and description.
+func commitMessage(task *database.Task) string {
+	subject := strings.TrimSpace(strings.SplitN(task.Title, "\n", 2)[0])
+	if runes := []rune(subject); len(runes) > 72 {
+		subject = strings.TrimSpace(string(runes[:69])) + "..."
+	}
+
+	message := subject
+	if description := strings.TrimSpace(task.Description); description != "" {
+		message += "\n\n" + description
+	}
+	return message + "\n\nSolo-Task: " + task.ID
+}
+
+// lockTaskBranch loads a task branch like loadTaskBranch and takes the git
+// lock of its repository. The task is loaded again once the lock is held, as a
+// review that held it before may have merged or discarded the branch.
+func (s *ReviewService) lockTaskBranch(taskID string) (*database.Task, *database.Project, func(), error) {
+	_, project, err := s.loadTaskBranch(taskID)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+
+	unlock := s.worktrees.lockRepository(project.Directory)
+	task, project, err := s.loadTaskBranch(taskID)
+	if err != nil {
+		unlock()
+		return nil, nil, nil, err
+	}
+	return task, project, unlock, nil
+}
+
+// loadTaskBranch loads a task together with its project and makes sure the
+// task branch still exists in the project repository.
+func (s *ReviewService) loadTaskBranch(taskID string) (*database.Task, *database.Project, error) {
+	task, err := s.stores.Tasks().Get(taskID)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to get task", zap.Error(err), zap.String("id", taskID))
+		}
+		return nil, nil, err
+	}
+
+	if task.Branch == "" || task.BaseBranch == "" || task.ProjectID == "" {
+		return nil, nil, ErrNoTaskBranch
+	}
+
+	project, err := s.stores.Projects().Get(task.ProjectID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, nil, ErrNoTaskBranch
+		}
+		s.logger.Error("Failed to get project", zap.Error(err), zap.String("id", task.ProjectID))
+		return nil, nil, err
+	}
+
+	if !git.IsRepository(project.Directory) || !git.BranchExists(project.Directory, task.Branch) {
+		return nil, nil, ErrNoTaskBranch
+	}
+
+	return task, project, nil
+}
+
+func diffFileToResponse(file *git.FileDiff, withHunks bool) model.DiffFile {
+	response := model.DiffFile{
+		Path:      file.Path,
+		OldPath:   file.OldPath,
+		Status:    file.Status,
+		Binary:    file.Binary,
+		Additions: file.Additions,
+		Deletions: file.Deletions,
+	}
+	if !withHunks {
+		return response
+	}
+
+	response.Hunks = make([]model.DiffHunk, len(file.Hunks))
+	for i, hunk := range file.Hunks {
+		lines := make([]model.DiffLine, len(hunk.Lines))
+		for j, line := range hunk.Lines {
+			lines[j] = model.DiffLine{
+				Type:    line.Type,
+				Content: line.Content,
+				OldLine: line.OldLine,
+				NewLine: line.NewLine,
+			}
+		}
+		response.Hunks[i] = model.DiffHunk{
+			Header:   hunk.Header,
+			OldStart: hunk.OldStart,
+			OldLines: hunk.OldLines,
+			NewStart: hunk.NewStart,
+			NewLines: hunk.NewLines,
+			Lines:    lines,
+		}
+	}
+	return response
+}
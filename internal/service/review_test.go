@@ -0,0 +1,97 @@
+package service
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+// gitRepo creates a repository with one commit on main.
+func gitRepo(t *testing.T) string {
+	t.Helper()
+	for _, key := range []string{"GIT_AUTHOR_NAME", "GIT_COMMITTER_NAME"} {
+		t.Setenv(key, "solo")
+	}
+	for _, key := range []string{"GIT_AUTHOR_EMAIL", "GIT_COMMITTER_EMAIL"} {
+		t.Setenv(key, "solo@example.com")
+	}
+
+	dir := t.TempDir()
+	runGit(t, dir, "init", "-q", "-b", "main")
+	if err := os.WriteFile(filepath.Join(dir, "README"), []byte("solo\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	runGit(t, dir, "add", "-A")
+	runGit(t, dir, "commit", "-q", "-m", "initial")
+	return dir
+}
+
+func runGit(t *testing.T, dir string, args ...string) {
+	t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	if out, err := cmd.CombinedOutput(); err != nil {
+		t.Fatalf("git %v: %v\n%s", args, err, out)
+	}
+}
+
+func TestMergeTaskCleanupFailure(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		s.worktrees.cfg.WorktreeRoot = t.TempDir()
+		repo := gitRepo(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: repo, BaseBranch: "main"})
+		task := s.task(t, &model.CreateTaskRequest{Title: "work", ProjectID: project.ID})
+
+		dbTask, err := s.tasks.stores.Tasks().Get(task.ID)
+		if err != nil {
+			t.Fatalf("get task: %v", err)
+		}
+		dbProject, err := s.projects.stores.Projects().Get(project.ID)
+		if err != nil {
+			t.Fatalf("get project: %v", err)
+		}
+		path, err := s.worktrees.EnsureWorktree(dbTask, dbProject)
+		if err != nil {
+			t.Fatalf("EnsureWorktree: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(path, "work"), []byte("done\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		// A locked worktree cannot be removed, so the merge cannot clean up
+		runGit(t, repo, "worktree", "lock", path)
+
+		done := make(chan error, 1)
+		go func() {
+			_, err := s.reviews.MergeTask(task.ID, &model.MergeTaskRequest{}, MutationOptions{})
+			done <- err
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				t.Fatalf("MergeTask: %v", err)
+			}
+		case <-time.After(10 * time.Second):
+			t.Fatal("MergeTask did not return")
+		}
+
+		if _, err := os.Stat(filepath.Join(repo, "work")); err != nil {
+			t.Errorf("merged file missing from the base branch: %v", err)
+		}
+		// The repository lock was released
+		locked := make(chan struct{})
+		go func() {
+			s.worktrees.lockRepository(repo)()
+			close(locked)
+		}()
+		select {
+		case <-locked:
+		case <-time.After(10 * time.Second):
+			t.Fatal("repository still locked after the merge")
+		}
+	})
+}
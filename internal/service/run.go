@@ -0,0 +1,418 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/executor"
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+var (
+	ErrNoAgentAssigned = errors.New("task has no agent and its project has no default agent")
+	ErrNoProjectDir    = errors.New("task must belong to a project with a directory")
+	ErrRunInProgress   = errors.New("task already has a run in progress")
+	ErrRunNotActive    = errors.New("run is not in progress")
+)
+
+type RunService struct {
+	db          *database.Database
+	executor    *executor.Executor
+	taskService *TaskService
+	worktrees   *WorktreeService
+	logger      *zap.Logger
+
+	// starting is held from checking a task has no active run until the
+	// new run is recorded, so concurrent starts cannot both pass the check.
+	starting sync.Mutex
+}
+
+func NewRunService(db *database.Database, executor *executor.Executor, taskService *TaskService, worktrees *WorktreeService, logger *zap.Logger) *RunService {
+	return &RunService{
+		db:          db,
+		executor:    executor,
+		taskService: taskService,
+		worktrees:   worktrees,
+		logger:      logger,
+	}
+}
+
+// StartRun launches the task's agent (or the project's default agent) and
+// records the run. Git projects run in a dedicated worktree on the task's
+// branch; other projects run directly in the project directory. Tasks
+// blocked by unfinished dependencies only start when forced.
+func (s *RunService) StartRun(taskID string, opts MutationOptions) (*model.RunResponse, error) {
+	s.logger.Info("Starting agent run", zap.String("task_id", taskID))
+
+	var task database.Task
+	if err := s.db.GetDB().Preload("Agent").First(&task, "id = ?", taskID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			s.logger.Warn("Task not found", zap.String("id", taskID))
+			return nil, err
+		}
+		s.logger.Error("Failed to get task", zap.Error(err))
+		return nil, err
+	}
+
+	if task.ProjectID == "" {
+		return nil, ErrNoProjectDir
+	}
+
+	var project database.Project
+	if err := s.db.GetDB().Preload("Agent").First(&project, "id = ?", task.ProjectID).Error; err != nil {
+		if err == gorm.ErrRecordNotFound {
+			return nil, ErrNoProjectDir
+		}
+		s.logger.Error("Failed to get project", zap.Error(err))
+		return nil, err
+	}
+	if project.Directory == "" {
+		return nil, ErrNoProjectDir
+	}
+
+	agent := task.Agent
+	if agent == nil {
+		agent = project.Agent
+	}
+	if agent == nil {
+		return nil, ErrNoAgentAssigned
+	}
+
+	s.starting.Lock()
+	defer s.starting.Unlock()
+
+	active, err := s.taskService.stores.Runs().Active(taskID)
+	if err != nil {
+		s.logger.Error("Failed to check active runs", zap.Error(err))
+		return nil, err
+	}
+	if active {
+		return nil, ErrRunInProgress
+	}
+
+	if !opts.Force {
+		blockedBy, err := s.taskService.blockers(s.taskService.stores, taskID)
+		if err != nil {
+			return nil, err
+		}
+		if len(blockedBy) > 0 {
+			return nil, &BlockedError{BlockedBy: blockedBy}
+		}
+	}
+
+	prompt := executor.TaskPrompt(task.Title, task.Description)
+	command, err := executor.AgentCommand(agent.Type, agent.Command, prompt, project.Directory)
+	if err != nil {
+		return nil, err
+	}
+
+	command.Dir, err = s.worktrees.EnsureWorktree(&task, &project)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	run := database.Run{
+		ID:         uuid.New().String(),
+		TaskID:     task.ID,
+		AgentID:    agent.ID,
+		Status:     model.RunStatusRunning,
+		Command:    command.String(),
+		WorkingDir: command.Dir,
+		StartedAt:  now,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+
+	if err := s.taskService.stores.Runs().Create(&run); err != nil {
+		s.logger.Error("Failed to create run", zap.Error(err))
+		return nil, err
+	}
+
+	if err := s.executor.Start(run.ID, command, func(result executor.Result) {
+		s.finishRun(run.ID, result)
+	}); err != nil {
+		s.logger.Error("Failed to start agent process", zap.Error(err), zap.String("run_id", run.ID))
+		exitCode := -1
+		finishedAt := time.Now()
+		run.Status = model.RunStatusFailed
+		run.ExitCode = &exitCode
+		run.Error = err.Error()
+		run.FinishedAt = &finishedAt
+		run.UpdatedAt = finishedAt
+		if err := s.db.GetDB().Save(&run).Error; err != nil {
+			s.logger.Error("Failed to update run", zap.Error(err))
+		}
+		return s.runToResponse(&run), nil
+	}
+
+	if task.Status == model.TaskStatusTodo {
+		if _, err := s.taskService.advanceStatus(&task, model.TaskStatusTodo, model.TaskStatusInProgress, opts.Actor); err != nil {
+			s.logger.Warn("Failed to move task to inprogress", zap.Error(err), zap.String("task_id", task.ID))
+		}
+	}
+
+	s.logger.Info("Agent run started", zap.String("run_id", run.ID), zap.String("task_id", task.ID))
+	return s.runToResponse(&run), nil
+}
+
+// finishRun records the outcome of a run once its process has exited.
+func (s *RunService) finishRun(runID string, result executor.Result) {
+	var run database.Run
+	if err := s.db.GetDB().First(&run, "id = ?", runID).Error; err != nil {
+		s.logger.Error("Failed to load finished run", zap.Error(err), zap.String("run_id", runID))
+		return
+	}
+
+	finishedAt := time.Now()
+	exitCode := result.ExitCode
+	run.ExitCode = &exitCode
+	run.FinishedAt = &finishedAt
+	run.UpdatedAt = finishedAt
+
+	if log, err := json.Marshal(runLogLines(result.Output)); err != nil {
+		s.logger.Error("Failed to encode run log", zap.Error(err), zap.String("run_id", runID))
+	} else {
+		run.Log = string(log)
+	}
+
+	switch {
+	case result.Cancelled:
+		run.Status = model.RunStatusCancelled
+	case result.Err != nil:
+		run.Status = model.RunStatusFailed
+		run.Error = result.Err.Error()
+	default:
+		run.Status = model.RunStatusSucceeded
+	}
+
+	if err := s.db.GetDB().Save(&run).Error; err != nil {
+		s.logger.Error("Failed to update run", zap.Error(err), zap.String("run_id", runID))
+		return
+	}
+
+	var dbTask database.Task
+	if err := s.db.GetDB().First(&dbTask, "id = ?", run.TaskID).Error; err != nil {
+		s.logger.Error("Failed to load task of finished run", zap.Error(err), zap.String("run_id", runID))
+		return
+	}
+
+	// Keep whatever the agent produced on the task branch, even for failed runs
+	message := fmt.Sprintf("%s\nSolo-Run: %s (%s)", commitMessage(&dbTask), run.ID, run.Status)
+	if committed, err := s.worktrees.CommitWorktree(&dbTask, message); err != nil {
+		s.logger.Error("Failed to commit agent changes", zap.Error(err), zap.String("run_id", runID))
+	} else if committed {
+		s.logger.Info("Committed agent changes", zap.String("run_id", runID), zap.String("branch", dbTask.Branch))
+	}
+
+	if run.Status != model.RunStatusSucceeded {
+		return
+	}
+
+	if dbTask.Status == model.TaskStatusInProgress {
+		if _, err := s.taskService.advanceStatus(&dbTask, model.TaskStatusInProgress, model.TaskStatusInReview, AgentActor(run.AgentID)); err != nil {
+			s.logger.Warn("Failed to move task to inreview", zap.Error(err), zap.String("task_id", dbTask.ID))
+		}
+	}
+}
+
+func (s *RunService) GetRuns(taskID string) (*model.RunListResponse, error) {
+	var runs []database.Run
+	if err := s.db.GetDB().Where("task_id = ?", taskID).Order("created_at DESC").Find(&runs).Error; err != nil {
+		s.logger.Error("Failed to get runs", zap.Error(err), zap.String("task_id", taskID))
+		return nil, err
+	}
+
+	responses := make([]model.RunResponse, len(runs))
+	for i := range runs {
+		responses[i] = *s.runToResponse(&runs[i])
+	}
+
+	return &model.RunListResponse{
+		Runs:  responses,
+		Total: int64(len(responses)),
+	}, nil
+}
+
+func (s *RunService) GetRun(taskID, runID string) (*model.RunResponse, error) {
+	var run database.Run
+	if err := s.db.GetDB().First(&run, "id = ? AND task_id = ?", runID, taskID).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			s.logger.Error("Failed to get run", zap.Error(err), zap.String("run_id", runID))
+		}
+		return nil, err
+	}
+
+	return s.runToResponse(&run), nil
+}
+
+// GetRunLog returns the output of a run, read live while it is still running.
+func (s *RunService) GetRunLog(taskID, runID string) (*model.RunLogResponse, error) {
+	var run database.Run
+	if err := s.db.GetDB().First(&run, "id = ? AND task_id = ?", runID, taskID).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			s.logger.Error("Failed to get run", zap.Error(err), zap.String("run_id", runID))
+		}
+		return nil, err
+	}
+
+	lines, err := s.persistedLog(&run)
+	if output, ok := s.executor.Output(runID); ok {
+		lines, err = runLogLines(output.Lines()), nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to decode run log", zap.Error(err), zap.String("run_id", runID))
+		return nil, err
+	}
+
+	return &model.RunLogResponse{
+		RunID:  run.ID,
+		Status: run.Status,
+		Lines:  lines,
+	}, nil
+}
+
+// StreamRunLog returns the output written so far plus a channel of live lines.
+// The channel is nil when the run has already finished, and is closed when the
+// run ends or the reader falls too far behind. Callers must invoke stop.
+func (s *RunService) StreamRunLog(taskID, runID string) (backlog []model.RunLogLine, lines <-chan model.RunLogLine, stop func(), err error) {
+	var run database.Run
+	if err := s.db.GetDB().First(&run, "id = ? AND task_id = ?", runID, taskID).Error; err != nil {
+		if err != gorm.ErrRecordNotFound {
+			s.logger.Error("Failed to get run", zap.Error(err), zap.String("run_id", runID))
+		}
+		return nil, nil, nil, err
+	}
+
+	output, ok := s.executor.Output(runID)
+	if !ok {
+		backlog, err := s.persistedLog(&run)
+		if err != nil {
+			s.logger.Error("Failed to decode run log", zap.Error(err), zap.String("run_id", runID))
+			return nil, nil, nil, err
+		}
+		return backlog, nil, func() {}, nil
+	}
+
+	raw, live, unsubscribe := output.Subscribe()
+	out := make(chan model.RunLogLine)
+	done := make(chan struct{})
+	go func() {
+		defer close(out)
+		for line := range live {
+			select {
+			case out <- runLogLine(line):
+			case <-done:
+				return
+			}
+		}
+	}()
+
+	var once sync.Once
+	stop = func() {
+		once.Do(func() {
+			close(done)
+			unsubscribe()
+		})
+	}
+
+	return runLogLines(raw), out, stop, nil
+}
+
+func (s *RunService) persistedLog(run *database.Run) ([]model.RunLogLine, error) {
+	lines := []model.RunLogLine{}
+	if run.Log == "" {
+		return lines, nil
+	}
+	if err := json.Unmarshal([]byte(run.Log), &lines); err != nil {
+		return nil, err
+	}
+	return lines, nil
+}
+
+// CancelRun kills the agent process of an active run.
+func (s *RunService) CancelRun(taskID, runID string) (*model.RunResponse, error) {
+	s.logger.Info("Cancelling agent run", zap.String("run_id", runID))
+
+	run, err := s.GetRun(taskID, runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.Status != model.RunStatusRunning {
+		return nil, ErrRunNotActive
+	}
+
+	if err := s.executor.Cancel(runID); err != nil {
+		if err == executor.ErrNotRunning {
+			return nil, ErrRunNotActive
+		}
+		return nil, err
+	}
+
+	return run, nil
+}
+
+// RecoverInterruptedRuns marks runs left in the running state by a previous
+// server process as failed, since their processes no longer exist.
+func (s *RunService) RecoverInterruptedRuns() error {
+	now := time.Now()
+	result := s.db.GetDB().Model(&database.Run{}).
+		Where("status = ?", model.RunStatusRunning).
+		Updates(map[string]interface{}{
+			"status":      model.RunStatusFailed,
+			"error":       "interrupted by server restart",
+			"finished_at": now,
+			"updated_at":  now,
+		})
+	if result.Error != nil {
+		s.logger.Error("Failed to recover interrupted runs", zap.Error(result.Error))
+		return result.Error
+	}
+
+	if result.RowsAffected > 0 {
+		s.logger.Warn("Marked interrupted runs as failed", zap.Int64("count", result.RowsAffected))
+	}
+	return nil
+}
+
+func (s *RunService) runToResponse(run *database.Run) *model.RunResponse {
+	return &model.RunResponse{
+		ID:         run.ID,
+		TaskID:     run.TaskID,
+		AgentID:    run.AgentID,
+		Status:     run.Status,
+		Command:    run.Command,
+		WorkingDir: run.WorkingDir,
+		ExitCode:   run.ExitCode,
+		Error:      run.Error,
+		StartedAt:  run.StartedAt,
+		FinishedAt: run.FinishedAt,
+		CreatedAt:  run.CreatedAt,
+		UpdatedAt:  run.UpdatedAt,
+	}
+}
+
+func runLogLine(line executor.Line) model.RunLogLine {
+	return model.RunLogLine{
+		Seq:    line.Seq,
+		Stream: line.Stream,
+		Text:   line.Text,
+		Time:   line.Time,
+	}
+}
+
+func runLogLines(lines []executor.Line) []model.RunLogLine {
+	result := make([]model.RunLogLine, len(lines))
+	for i, line := range lines {
+		result[i] = runLogLine(line)
+	}
+	return result
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+const (
+	defaultSearchLimit = 20
+	maxSearchLimit     = 100
+)
+
+var ErrInvalidSearch = errors.New("invalid search")
+
+// Title matches rank above tag matches above matches in descriptions.
+const (
+	searchTitleWeight = 10.0
+	searchBodyWeight  = 2.0
+	searchTagsWeight  = 5.0
+)
+
+// searchColumnWeights passes the weights to bm25. The unindexed entity
+// columns come first and weigh nothing.
+var searchColumnWeights = fmt.Sprintf("0, 0, 0, %.1f, %.1f, %.1f", searchTitleWeight, searchBodyWeight, searchTagsWeight)
+
+// SearchService runs full-text searches over tasks and projects. SQLite
+// searches its FTS5 index; other databases match terms as substrings, see
+// searchWithoutIndex.
+type SearchService struct {
+	db     *database.Database
+	logger *zap.Logger
+}
+
+func NewSearchService(db *database.Database, logger *zap.Logger) *SearchService {
+	return &SearchService{
+		db:     db,
+		logger: logger,
+	}
+}
+
+// Search returns the tasks and projects matching every term of the query,
+// best matches first. The last term also matches as a prefix so results
+// show up while the query is being typed.
+func (s *SearchService) Search(query *model.SearchQuery) (*model.SearchResponse, error) {
+	terms := searchTerms(query.Q)
+	if len(terms) == 0 {
+		return nil, fmt.Errorf("%w: query has no searchable terms", ErrInvalidSearch)
+	}
+	if query.Type != "" && query.Type != model.SearchTypeTask && query.Type != model.SearchTypeProject {
+		return nil, fmt.Errorf("%w: type must be task or project", ErrInvalidSearch)
+	}
+
+	limit := query.Limit
+	if limit <= 0 {
+		limit = defaultSearchLimit
+	}
+	if limit > maxSearchLimit {
+		limit = maxSearchLimit
+	}
+
+	if s.db.Type() != database.TypeSQLite {
+		return s.searchWithoutIndex(query, terms, limit)
+	}
+
+	where := []string{database.SearchIndexTable + " MATCH ?"}
+	args := []interface{}{ftsQuery(terms)}
+	if query.Type != "" {
+		where = append(where, database.SearchIndexTable+".entity_type = ?")
+		args = append(args, query.Type)
+	}
+	if query.ProjectID != "" {
+		where = append(where, database.SearchIndexTable+".project_id = ?")
+		args = append(args, query.ProjectID)
+	}
+	condition := strings.Join(where, " AND ")
+
+	var total int64
+	if err := s.db.GetDB().Table(database.SearchIndexTable).Where(condition, args...).Count(&total).Error; err != nil {
+		s.logger.Error("Failed to count search results", zap.Error(err), zap.String("q", query.Q))
+		return nil, err
+	}
+
+	var rows []struct {
+		EntityType string
+		EntityID   string
+		ProjectID  string
+		Title      string
+		Snippet    string
+		Tags       string
+		Status     string
+		Rank       float64
+	}
+	if err := s.db.GetDB().Table(database.SearchIndexTable).
+		Select(fmt.Sprintf(`search_index.entity_type, search_index.entity_id, search_index.project_id,
+			highlight(search_index, 3, '<mark>', '</mark>') AS title,
+			snippet(search_index, 4, '<mark>', '</mark>', '…', 16) AS snippet,
+			search_index.tags, COALESCE(tasks.status, '') AS status,
+			bm25(search_index, %s) AS rank`, searchColumnWeights)).
+		Joins("LEFT JOIN tasks ON search_index.entity_type = 'task' AND tasks.id = search_index.entity_id").
+		Where(condition, args...).
+		Order("rank").
+		Limit(limit).
+		Scan(&rows).Error; err != nil {
+		s.logger.Error("Failed to search", zap.Error(err), zap.String("q", query.Q))
+		return nil, err
+	}
+
+	results := make([]model.SearchResult, len(rows))
+	for i, row := range rows {
+		results[i] = model.SearchResult{
+			Type:      row.EntityType,
+			ID:        row.EntityID,
+			ProjectID: row.ProjectID,
+			Title:     row.Title,
+			Snippet:   row.Snippet,
+			Status:    row.Status,
+			Tags:      row.Tags,
+			// bm25 scores are negative, the most relevant being the lowest
+			Score: -row.Rank,
+		}
+	}
+
+	return &model.SearchResponse{
+		Query:   query.Q,
+		Results: results,
+		Total:   int(total),
+	}, nil
+}
+
+// searchTerms splits free text into the terms to search for, leaving out
+// quotes and terms without a single word character.
+func searchTerms(text string) []string {
+	var terms []string
+	for _, term := range strings.Fields(text) {
+		term = strings.ReplaceAll(term, `"`, "")
+		if strings.IndexFunc(term, isWordRune) < 0 {
+			continue
+		}
+		terms = append(terms, term)
+	}
+	return terms
+}
+
+// ftsQuery turns search terms into an FTS5 query matching all of them.
+// Terms are quoted so FTS5 operators and punctuation in the input are taken
+// literally.
+func ftsQuery(terms []string) string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		quoted[i] = `"` + term + `"`
+	}
+	quoted[len(quoted)-1] += "*"
+	return strings.Join(quoted, " ")
+}
+
+func isWordRune(r rune) bool {
+	return r == '_' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 127
+}
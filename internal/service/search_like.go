@@ -0,0 +1,168 @@
+package service
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+// snippetWords is how many words of a description a result shows, as the
+// FTS5 snippet does.
+const snippetWords = 16
+
+const taskTagMatch = `EXISTS (SELECT 1 FROM task_tags JOIN tags ON tags.id = task_tags.tag_id
+	WHERE task_tags.task_id = tasks.id AND LOWER(tags.name) LIKE ? ESCAPE '!')`
+
+// searchWithoutIndex searches databases without an FTS5 index. Every term
+// has to appear somewhere in the title, description or, for tasks, a tag
+// name. Matches are ranked and highlighted here rather than by the
+// database, with the same column weights as the index.
+func (s *SearchService) searchWithoutIndex(query *model.SearchQuery, terms []string, limit int) (*model.SearchResponse, error) {
+	matcher := termMatcher(terms)
+	results := []model.SearchResult{}
+
+	if query.Type != model.SearchTypeProject {
+		db := s.db.GetDB().Preload("TaskTags.Tag")
+		for _, term := range terms {
+			pattern := store.LikePattern(term)
+			db = db.Where("(LOWER(tasks.title) LIKE ? ESCAPE '!' OR LOWER(tasks.description) LIKE ? ESCAPE '!' OR "+taskTagMatch+")",
+				pattern, pattern, pattern)
+		}
+		if query.ProjectID != "" {
+			db = db.Where("tasks.project_id = ?", query.ProjectID)
+		}
+
+		var tasks []database.Task
+		if err := db.Find(&tasks).Error; err != nil {
+			s.logger.Error("Failed to search tasks", zap.Error(err), zap.String("q", query.Q))
+			return nil, err
+		}
+		for _, task := range tasks {
+			names := make([]string, len(task.TaskTags))
+			for i, taskTag := range task.TaskTags {
+				names[i] = taskTag.Tag.Name
+			}
+			tags := strings.Join(names, " ")
+
+			results = append(results, model.SearchResult{
+				Type:      model.SearchTypeTask,
+				ID:        task.ID,
+				ProjectID: task.ProjectID,
+				Title:     highlight(matcher, task.Title),
+				Snippet:   snippet(matcher, task.Description),
+				Status:    task.Status,
+				Tags:      tags,
+				Score:     matchScore(matcher, task.Title, task.Description, tags),
+			})
+		}
+	}
+
+	if query.Type != model.SearchTypeTask {
+		db := s.db.GetDB()
+		for _, term := range terms {
+			pattern := store.LikePattern(term)
+			db = db.Where("(LOWER(projects.name) LIKE ? ESCAPE '!' OR LOWER(projects.description) LIKE ? ESCAPE '!')", pattern, pattern)
+		}
+		if query.ProjectID != "" {
+			db = db.Where("projects.id = ?", query.ProjectID)
+		}
+
+		var projects []database.Project
+		if err := db.Find(&projects).Error; err != nil {
+			s.logger.Error("Failed to search projects", zap.Error(err), zap.String("q", query.Q))
+			return nil, err
+		}
+		for _, project := range projects {
+			results = append(results, model.SearchResult{
+				Type:      model.SearchTypeProject,
+				ID:        project.ID,
+				ProjectID: project.ID,
+				Title:     highlight(matcher, project.Name),
+				Snippet:   snippet(matcher, project.Description),
+				Score:     matchScore(matcher, project.Name, project.Description, ""),
+			})
+		}
+	}
+
+	sort.SliceStable(results, func(i, j int) bool {
+		return results[i].Score > results[j].Score
+	})
+	total := len(results)
+	if len(results) > limit {
+		results = results[:limit]
+	}
+
+	return &model.SearchResponse{
+		Query:   query.Q,
+		Results: results,
+		Total:   total,
+	}, nil
+}
+
+// termMatcher finds any of the terms, ignoring case. Longer terms are tried
+// first so a term containing another is highlighted whole.
+func termMatcher(terms []string) *regexp.Regexp {
+	sorted := append([]string(nil), terms...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return len(sorted[i]) > len(sorted[j])
+	})
+
+	quoted := make([]string, len(sorted))
+	for i, term := range sorted {
+		quoted[i] = regexp.QuoteMeta(term)
+	}
+	return regexp.MustCompile("(?i)" + strings.Join(quoted, "|"))
+}
+
+// matchScore weighs the matches in each column like bm25 does in the index.
+func matchScore(matcher *regexp.Regexp, title, body, tags string) float64 {
+	return searchTitleWeight*float64(len(matcher.FindAllStringIndex(title, -1))) +
+		searchBodyWeight*float64(len(matcher.FindAllStringIndex(body, -1))) +
+		searchTagsWeight*float64(len(matcher.FindAllStringIndex(tags, -1)))
+}
+
+func highlight(matcher *regexp.Regexp, text string) string {
+	return matcher.ReplaceAllString(text, "<mark>$0</mark>")
+}
+
+// snippet shows the words of text around its first match, marking cut off
+// ends with an ellipsis.
+func snippet(matcher *regexp.Regexp, text string) string {
+	words := strings.Fields(text)
+	if len(words) == 0 {
+		return ""
+	}
+
+	start := 0
+	for i, word := range words {
+		if matcher.MatchString(word) {
+			start = i - 2
+			break
+		}
+	}
+	if start > len(words)-snippetWords {
+		start = len(words) - snippetWords
+	}
+	if start < 0 {
+		start = 0
+	}
+	end := start + snippetWords
+	if end > len(words) {
+		end = len(words)
+	}
+
+	result := highlight(matcher, strings.Join(words[start:end], " "))
+	if start > 0 {
+		result = "…" + result
+	}
+	if end < len(words) {
+		result += "…"
+	}
+	return result
+}
@@ -0,0 +1,125 @@
+package service
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/config"
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+// postgresDSNEnv names a PostgreSQL database the tests may wipe. The
+// PostgreSQL tests are skipped when it is not set.
+const postgresDSNEnv = "SOLO_TEST_POSTGRES_DSN"
+
+// testStore opens an empty store for each test.
+type testStore struct {
+	name   string
+	dsnEnv string // Skips the store when this variable is not set
+	open   func(t *testing.T, dsn string) store.Store
+}
+
+var testStores = []testStore{
+	{
+		name: "memory",
+		open: func(t *testing.T, dsn string) store.Store {
+			return store.NewMemoryStore()
+		},
+	},
+	{
+		name: database.TypeSQLite,
+		open: func(t *testing.T, dsn string) store.Store {
+			return openTestDatabase(t, &config.DatabaseConfig{
+				Type: database.TypeSQLite,
+				DSN:  filepath.Join(t.TempDir(), "solo.db"),
+			})
+		},
+	},
+	{
+		name:   database.TypePostgres,
+		dsnEnv: postgresDSNEnv,
+		open: func(t *testing.T, dsn string) store.Store {
+			cfg := &config.DatabaseConfig{Type: database.TypePostgres, DSN: dsn}
+
+			// Start from an empty schema, dropping what earlier tests left
+			db, err := database.Open(cfg, zap.NewNop())
+			if err != nil {
+				t.Fatalf("open %s: %v", postgresDSNEnv, err)
+			}
+			err = db.DB.Exec("DROP SCHEMA public CASCADE").Error
+			if err == nil {
+				err = db.DB.Exec("CREATE SCHEMA public").Error
+			}
+			db.Close()
+			if err != nil {
+				t.Fatalf("reset %s: %v", postgresDSNEnv, err)
+			}
+
+			return openTestDatabase(t, cfg)
+		},
+	},
+}
+
+// openTestDatabase migrates a database and closes it after the test.
+func openTestDatabase(t *testing.T, cfg *config.DatabaseConfig) store.Store {
+	t.Helper()
+	db, err := database.NewDatabase(cfg, zap.NewNop())
+	if err != nil {
+		t.Fatalf("open %s database: %v", cfg.Type, err)
+	}
+	t.Cleanup(func() { db.Close() })
+	return store.NewGormStore(db.DB)
+}
+
+// forEachStore runs a test against every store. The test gets services on
+// a new empty store from each call of newServices.
+func forEachStore(t *testing.T, test func(t *testing.T, newServices func(t *testing.T) *testServices)) {
+	for _, ts := range testStores {
+		t.Run(ts.name, func(t *testing.T) {
+			var dsn string
+			if ts.dsnEnv != "" {
+				if dsn = os.Getenv(ts.dsnEnv); dsn == "" {
+					t.Skipf("%s is not set", ts.dsnEnv)
+				}
+			}
+			test(t, func(t *testing.T) *testServices {
+				t.Helper()
+				return newTestServices(ts.open(t, dsn))
+			})
+		})
+	}
+}
+
+type testServices struct {
+	tasks     *TaskService
+	projects  *ProjectService
+	agents    *AgentService
+	tags      *TagService
+	worktrees *WorktreeService
+	reviews   *ReviewService
+	trash     *TrashService
+}
+
+// newTestServices wires the services that work through a store.
+func newTestServices(stores store.Store) *testServices {
+	logger := zap.NewNop()
+	bus := events.NewBus(logger)
+	worktrees := NewWorktreeService(stores, &config.GitConfig{}, logger)
+	workflows := NewWorkflowService(stores, bus, logger)
+	tasks := NewTaskService(stores, worktrees, workflows, bus, logger)
+	projects := NewProjectService(stores, tasks, bus, logger)
+	return &testServices{
+		tasks:     tasks,
+		projects:  projects,
+		agents:    NewAgentService(stores, tasks, projects, bus, logger),
+		tags:      NewTagService(stores, tasks, bus, logger),
+		worktrees: worktrees,
+		reviews:   NewReviewService(stores, tasks, worktrees, logger),
+		trash:     NewTrashService(stores, &config.TrashConfig{}, tasks, projects, bus, logger),
+	}
+}
@@ -0,0 +1,164 @@
+package service
+
+import (
+	"errors"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var (
+	ErrParentNotFound = errors.New("parent task does not exist")
+	ErrTaskCycle      = errors.New("a task cannot be moved under itself or one of its subtasks")
+)
+
+// GetSubtasks lists the direct subtasks of a task. It returns nil when the
+// parent task does not exist.
+func (s *TaskService) GetSubtasks(parentID string, query *model.TaskListQuery) (*model.TaskListResponse, error) {
+	exists, err := s.taskExists(parentID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	query.ParentID = parentID
+	return s.GetTasks(query)
+}
+
+// CreateSubtask creates a task under a parent task. It returns nil when the
+// parent task does not exist.
+func (s *TaskService) CreateSubtask(parentID string, req *model.CreateTaskRequest, opts MutationOptions) (*model.TaskResponse, error) {
+	exists, err := s.taskExists(parentID)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	req.ParentID = &parentID
+	return s.CreateTask(req, opts)
+}
+
+func (s *TaskService) taskExists(id string) (bool, error) {
+	_, err := s.stores.Tasks().Get(id)
+	if err == store.ErrNotFound {
+		return false, nil
+	}
+	if err != nil {
+		s.logger.Error("Failed to check task", zap.Error(err), zap.String("id", id))
+		return false, err
+	}
+	return true, nil
+}
+
+// loadParent loads the would-be parent of a task and makes sure attaching
+// the task to it does not create a cycle. taskID is empty for new tasks.
+func (s *TaskService) loadParent(st store.Store, taskID, parentID string) (*database.Task, error) {
+	parent, err := st.Tasks().Get(parentID)
+	if err != nil {
+		if err == store.ErrNotFound {
+			return nil, ErrParentNotFound
+		}
+		return nil, err
+	}
+
+	if taskID == "" {
+		return parent, nil
+	}
+
+	// Walk up from the parent; meeting the task itself means a cycle
+	seen := map[string]bool{}
+	ancestor := parent
+	for {
+		if ancestor.ID == taskID {
+			return nil, ErrTaskCycle
+		}
+		if ancestor.ParentID == nil || seen[ancestor.ID] {
+			return parent, nil
+		}
+		seen[ancestor.ID] = true
+
+		if ancestor, err = st.Tasks().Get(*ancestor.ParentID); err != nil {
+			if err == store.ErrNotFound {
+				return parent, nil
+			}
+			return nil, err
+		}
+	}
+}
+
+// descendants loads every subtask below a task, depth first levels last.
+func (s *TaskService) descendants(st store.Store, id string) ([]database.Task, error) {
+	var result []database.Task
+	seen := map[string]bool{id: true}
+	frontier := []string{id}
+	for len(frontier) > 0 {
+		children, err := st.Tasks().Subtasks(frontier)
+		if err != nil {
+			return nil, err
+		}
+
+		frontier = frontier[:0]
+		for _, child := range children {
+			if seen[child.ID] {
+				continue
+			}
+			seen[child.ID] = true
+			result = append(result, child)
+			frontier = append(frontier, child.ID)
+		}
+	}
+	return result, nil
+}
+
+// cancelSubtasks cancels the open subtasks of a cancelled task. Subtasks
+// the workflow does not allow to be cancelled, such as finished ones, are
+// left alone. Cancelling a subtask cancels its own subtasks in turn. actor
+// is the one who cancelled the parent.
+func (s *TaskService) cancelSubtasks(parentID, actor string) {
+	children, err := s.stores.Tasks().Subtasks([]string{parentID})
+	if err != nil {
+		s.logger.Error("Failed to load subtasks", zap.Error(err), zap.String("id", parentID))
+		return
+	}
+
+	for i := range children {
+		if children[i].Status == model.TaskStatusCancelled || s.workflows.IsFinal(children[i].ProjectID, children[i].Status) {
+			continue
+		}
+		if _, err := s.advanceStatus(&children[i], children[i].Status, model.TaskStatusCancelled, actor); err != nil {
+			s.logger.Warn("Failed to cancel subtask", zap.Error(err), zap.String("id", children[i].ID))
+		}
+	}
+}
+
+// attachSubtaskRollups fills in the subtask summary of tasks that have
+// subtasks.
+func (s *TaskService) attachSubtaskRollups(tasks []model.TaskResponse) error {
+	if len(tasks) == 0 {
+		return nil
+	}
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+
+	final, err := s.finalStatuses()
+	if err != nil {
+		return err
+	}
+
+	counts, err := s.stores.Tasks().SubtaskCounts(ids, final)
+	if err != nil {
+		s.logger.Error("Failed to count subtasks", zap.Error(err))
+		return err
+	}
+
+	for i := range tasks {
+		if count, ok := counts[tasks[i].ID]; ok {
+			tasks[i].Subtasks = &model.SubtaskRollup{Total: count.Total, Completed: count.Completed}
+		}
+	}
+	return nil
+}
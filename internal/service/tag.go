@@ -0,0 +1,328 @@
+package service
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var (
+	ErrInvalidTagName = errors.New("tag name must not be empty")
+	ErrTagExists      = errors.New("a tag with this name already exists")
+	ErrTagMergeSelf   = errors.New("a tag cannot be merged into itself")
+)
+
+// TagService manages tags. Tags are also created implicitly when tasks are
+// tagged with new names. Missing tags are reported as store.ErrNotFound.
+type TagService struct {
+	stores      store.Store
+	taskService *TaskService
+	events      *events.Bus
+	logger      *zap.Logger
+}
+
+func NewTagService(stores store.Store, taskService *TaskService, bus *events.Bus, logger *zap.Logger) *TagService {
+	return &TagService{
+		stores:      stores,
+		taskService: taskService,
+		events:      bus,
+		logger:      logger,
+	}
+}
+
+// GetTags lists all tags by name with the number of tasks carrying each.
+func (s *TagService) GetTags() (*model.TagListResponse, error) {
+	dbTags, err := s.stores.Tags().List()
+	if err != nil {
+		s.logger.Error("Failed to get tags", zap.Error(err))
+		return nil, err
+	}
+	counts, err := s.stores.Tags().CountTasks()
+	if err != nil {
+		s.logger.Error("Failed to count tagged tasks", zap.Error(err))
+		return nil, err
+	}
+
+	tags := make([]model.TagResponse, len(dbTags))
+	for i := range dbTags {
+		tags[i] = *tagResponse(&dbTags[i], counts[dbTags[i].ID])
+	}
+	return &model.TagListResponse{
+		Tags:  tags,
+		Total: int64(len(tags)),
+	}, nil
+}
+
+func (s *TagService) GetTag(id string) (*model.TagResponse, error) {
+	tag, err := s.stores.Tags().Get(id)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to get tag", zap.Error(err), zap.String("id", id))
+		}
+		return nil, err
+	}
+	counts, err := s.stores.Tags().CountTasks(id)
+	if err != nil {
+		s.logger.Error("Failed to count tagged tasks", zap.Error(err), zap.String("id", id))
+		return nil, err
+	}
+	return tagResponse(tag, counts[id]), nil
+}
+
+func (s *TagService) CreateTag(req *model.CreateTagRequest) (*model.TagResponse, error) {
+	name := strings.TrimSpace(req.Name)
+	if name == "" {
+		return nil, ErrInvalidTagName
+	}
+	if err := s.ensureNameFree(name, ""); err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	tag := database.Tag{
+		ID:        uuid.New().String(),
+		Name:      name,
+		Color:     req.Color,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.stores.Tags().Create(&tag); err != nil {
+		s.logger.Error("Failed to create tag", zap.Error(err))
+		return nil, err
+	}
+
+	s.logger.Info("Tag created", zap.String("id", tag.ID), zap.String("name", tag.Name))
+	return s.publishTag(events.TagCreated, tag.ID)
+}
+
+// UpdateTag renames or recolors a tag. Renaming a tag counts as an update
+// of the tasks carrying it; recoloring only moves their versions on. Those
+// tasks are republished so clients pick up the change.
+func (s *TagService) UpdateTag(id string, req *model.UpdateTagRequest, opts MutationOptions) (*model.TagResponse, error) {
+	tag, err := s.stores.Tags().Get(id)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to get tag", zap.Error(err), zap.String("id", id))
+		}
+		return nil, err
+	}
+
+	renamed := false
+	if name := strings.TrimSpace(req.Name); name != "" && name != tag.Name {
+		if err := s.ensureNameFree(name, id); err != nil {
+			return nil, err
+		}
+		tag.Name = name
+		renamed = true
+	}
+	if req.Color != nil {
+		tag.Color = *req.Color
+	}
+	tag.UpdatedAt = time.Now()
+
+	if err := s.stores.Transaction(func(st store.Store) error {
+		taskIDs, err := st.Tags().TaskIDs(id)
+		if err != nil {
+			return err
+		}
+		if !renamed {
+			if err := st.Tags().Update(tag); err != nil {
+				return err
+			}
+			return st.Tasks().BumpVersions(taskIDs)
+		}
+		return retagTasks(st, taskIDs, opts.Actor, func() error {
+			return st.Tags().Update(tag)
+		})
+	}); err != nil {
+		s.logger.Error("Failed to update tag", zap.Error(err), zap.String("id", id))
+		return nil, err
+	}
+
+	s.publishTaggedTasks(id)
+	return s.publishTag(events.TagUpdated, id)
+}
+
+// DeleteTag removes a tag from every task carrying it and deletes it.
+func (s *TagService) DeleteTag(id string, opts MutationOptions) error {
+	var taskIDs []string
+	if err := s.stores.Transaction(func(st store.Store) error {
+		var err error
+		if taskIDs, err = st.Tags().TaskIDs(id); err != nil {
+			return err
+		}
+		return retagTasks(st, taskIDs, opts.Actor, func() error {
+			return st.Tags().Delete(id)
+		})
+	}); err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to delete tag", zap.Error(err), zap.String("id", id))
+		}
+		return err
+	}
+
+	s.logger.Info("Tag deleted", zap.String("id", id))
+	s.publishTasks(taskIDs)
+	s.events.Publish(events.Event{
+		Type:     events.TagDeleted,
+		EntityID: id,
+	})
+	return nil
+}
+
+// MergeTag moves every task carrying a tag over to the target tag and
+// deletes the merged tag.
+func (s *TagService) MergeTag(id string, req *model.MergeTagRequest, opts MutationOptions) (*model.TagResponse, error) {
+	if id == req.TargetID {
+		return nil, ErrTagMergeSelf
+	}
+
+	var taskIDs []string
+	if err := s.stores.Transaction(func(st store.Store) error {
+		var err error
+		if taskIDs, err = st.Tags().TaskIDs(id); err != nil {
+			return err
+		}
+		return retagTasks(st, taskIDs, opts.Actor, func() error {
+			return st.Tags().Merge(id, req.TargetID)
+		})
+	}); err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to merge tag", zap.Error(err), zap.String("id", id))
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Tag merged", zap.String("id", id), zap.String("target_id", req.TargetID))
+	s.publishTasks(taskIDs)
+	s.events.Publish(events.Event{
+		Type:     events.TagDeleted,
+		EntityID: id,
+	})
+	return s.publishTag(events.TagUpdated, req.TargetID)
+}
+
+// CollectGarbage deletes tags no task carries anymore, together with tag
+// assignments left behind by deleted tasks. Tags of trashed tasks are kept
+// for when they are restored.
+func (s *TagService) CollectGarbage() (*model.TagGCResponse, error) {
+	unused, err := s.stores.Tags().DeleteUnused()
+	if err != nil {
+		s.logger.Error("Failed to collect unused tags", zap.Error(err))
+		return nil, err
+	}
+
+	names := make([]string, len(unused))
+	for i, tag := range unused {
+		names[i] = tag.Name
+		s.events.Publish(events.Event{
+			Type:     events.TagDeleted,
+			EntityID: tag.ID,
+		})
+	}
+
+	s.logger.Info("Collected unused tags", zap.Int("count", len(names)))
+	return &model.TagGCResponse{
+		Deleted: names,
+		Total:   int64(len(names)),
+	}, nil
+}
+
+// retagTasks makes change, which changes the tags of the given tasks, and
+// records it as an update of each task, moving its version on. Trashed tasks
+// are changed without a record.
+func retagTasks(st store.Store, taskIDs []string, actor string, change func() error) error {
+	before, err := st.Tasks().TagNames(taskIDs)
+	if err != nil {
+		return err
+	}
+	if err := change(); err != nil {
+		return err
+	}
+	after, err := st.Tasks().TagNames(taskIDs)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	for _, id := range taskIDs {
+		task, err := st.Tasks().Get(id)
+		if err == store.ErrNotFound {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		task.UpdatedAt = now
+		if err := st.Tasks().Update(task); err != nil {
+			return err
+		}
+		if err := storeActivity(st, model.EntityTask, id, task.ProjectID, model.ActivityUpdated, actor,
+			taskSnapshot(task, before[id]), taskSnapshot(task, after[id])); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *TagService) ensureNameFree(name, exceptID string) error {
+	taken, err := s.stores.Tags().NameTaken(name, exceptID)
+	if err != nil {
+		s.logger.Error("Failed to check tag name", zap.Error(err))
+		return err
+	}
+	if taken {
+		return ErrTagExists
+	}
+	return nil
+}
+
+func tagResponse(tag *database.Tag, taskCount int64) *model.TagResponse {
+	return &model.TagResponse{
+		ID:        tag.ID,
+		Name:      tag.Name,
+		Color:     tag.Color,
+		TaskCount: taskCount,
+		CreatedAt: tag.CreatedAt,
+		UpdatedAt: tag.UpdatedAt,
+	}
+}
+
+func (s *TagService) publishTag(eventType, id string) (*model.TagResponse, error) {
+	tag, err := s.GetTag(id)
+	if err != nil {
+		return nil, err
+	}
+
+	s.events.Publish(events.Event{
+		Type:     eventType,
+		EntityID: tag.ID,
+		Data:     tag,
+	})
+	return tag, nil
+}
+
+func (s *TagService) publishTaggedTasks(tagID string) {
+	taskIDs, err := s.stores.Tags().TaskIDs(tagID)
+	if err != nil {
+		s.logger.Warn("Failed to load tagged tasks", zap.Error(err), zap.String("id", tagID))
+		return
+	}
+	s.publishTasks(taskIDs)
+}
+
+func (s *TagService) publishTasks(taskIDs []string) {
+	for _, taskID := range taskIDs {
+		if _, err := s.taskService.publishTaskUpdated(taskID); err != nil {
+			s.logger.Warn("Failed to publish tagged task", zap.Error(err), zap.String("id", taskID))
+		}
+	}
+}
@@ -1,285 +1,698 @@
 package service
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
 	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
 	"github.com/google/uuid"
 	"go.uber.org/zap"
-	"gorm.io/gorm"
 )
 
+// MutationOptions carries per-request settings for changes.
+type MutationOptions struct {
+	Force   bool    // Move blocked tasks into inprogress anyway
+	Actor   string  // Who makes the change, recorded in the activity log; empty for Solo itself
+	IfMatch []int64 // Versions the change may apply to, from If-Match; nil for any
+}
+
 type TaskService struct {
-	db     *database.Database
-	logger *zap.Logger
+	stores    store.Store
+	worktrees *WorktreeService
+	workflows *WorkflowService
+	events    *events.Bus
+	logger    *zap.Logger
 }
 
-func NewTaskService(db *database.Database, logger *zap.Logger) *TaskService {
+func NewTaskService(stores store.Store, worktrees *WorktreeService, workflows *WorkflowService, bus *events.Bus, logger *zap.Logger) *TaskService {
 	return &TaskService{
-		db:     db,
-		logger: logger,
+		stores:    stores,
+		worktrees: worktrees,
+		workflows: workflows,
+		events:    bus,
+		logger:    logger,
 	}
 }
 
-func (s *TaskService) CreateTask(req *model.CreateTaskRequest) (*model.TaskResponse, error) {
+func (s *TaskService) CreateTask(req *model.CreateTaskRequest, opts MutationOptions) (*model.TaskResponse, error) {
 	id := uuid.New().String()
 	now := time.Now()
 
-	status := req.Status
-	if status == "" {
-		status = "todo"
-	}
-
-	// Start transaction
-	tx := s.db.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
+	projectID := req.ProjectID
+	var parentID *string
+	if req.ParentID != nil && *req.ParentID != "" {
+		parent, err := s.loadParent(s.stores, "", *req.ParentID)
+		if err != nil {
+			return nil, err
 		}
-	}()
-
-	// Create task
-	dbTask := &database.Task{
-		ID:          id,
-		Title:       req.Title,
-		Description: req.Description,
-		Status:      status,
-		Assignee:    req.Assignee,
-		AgentID:     req.AgentID,
-		ProjectID:   req.ProjectID,
-		CreatedAt:   now,
-		UpdatedAt:   now,
+		// Subtasks live in their parent's project unless told otherwise
+		if projectID == "" {
+			projectID = parent.ProjectID
+		}
+		parentID = &parent.ID
 	}
 
-	if err := tx.Create(dbTask).Error; err != nil {
-		tx.Rollback()
-		s.logger.Error("Failed to create task", zap.Error(err))
+	agentID := optionalReference(req.AgentID)
+	if err := checkProject(s.stores, projectID); err != nil {
+		return nil, err
+	}
+	if err := checkAgent(s.stores, agentID); err != nil {
 		return nil, err
 	}
 
-	// Handle tags
-	if err := s.handleTaskTags(tx, id, req.Tags); err != nil {
-		tx.Rollback()
-		s.logger.Error("Failed to handle task tags", zap.Error(err))
+	status := req.Status
+	if status == "" {
+		initial, err := s.workflows.InitialStatus(projectID)
+		if err != nil {
+			s.logger.Error("Failed to resolve initial status", zap.Error(err))
+			return nil, err
+		}
+		status = initial
+	} else if err := s.workflows.ValidateStatus(projectID, status); err != nil {
 		return nil, err
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		s.logger.Error("Failed to commit transaction", zap.Error(err))
+	priority := req.Priority
+	if priority == "" {
+		priority = model.PriorityMedium
+	}
+
+	var spread []string // Tasks spread out to make room for the new one
+	if err := s.stores.Transaction(func(st store.Store) error {
+		// New tasks go to the end of their column
+		position, moved, err := lastPosition(st, projectID, status)
+		if err != nil {
+			s.logger.Error("Failed to position task", zap.Error(err))
+			return err
+		}
+		spread = moved
+
+		var completedAt *time.Time
+		if s.workflows.IsFinal(projectID, status) {
+			completedAt = &now
+		}
+
+		// Create task
+		dbTask := &database.Task{
+			ID:          id,
+			Title:       req.Title,
+			Description: req.Description,
+			Status:      status,
+			Priority:    priority,
+			DueDate:     localTime(req.DueDate),
+			Assignee:    req.Assignee,
+			AgentID:     agentID,
+			ProjectID:   projectID,
+			ParentID:    parentID,
+			Position:    position,
+			Version:     1,
+			CreatedAt:   now,
+			UpdatedAt:   now,
+			CompletedAt: completedAt,
+		}
+
+		if err := st.Tasks().Create(dbTask); err != nil {
+			s.logger.Error("Failed to create task", zap.Error(err))
+			return err
+		}
+
+		// Handle tags
+		if err := st.Tasks().SetTags(id, req.Tags); err != nil {
+			s.logger.Error("Failed to handle task tags", zap.Error(err))
+			return err
+		}
+
+		tags, err := st.Tasks().TagNames([]string{id})
+		if err != nil {
+			return err
+		}
+		if err := storeActivity(st, model.EntityTask, id, projectID, model.ActivityCreated, opts.Actor,
+			nil, taskSnapshot(dbTask, tags[id])); err != nil {
+			s.logger.Error("Failed to record task activity", zap.Error(err))
+			return err
+		}
+
+		// The parent's subtask rollup changes
+		if parentID != nil {
+			return st.Tasks().BumpVersions([]string{*parentID})
+		}
+		return nil
+	}); err != nil {
 		return nil, err
 	}
 
 	// Load task with tags for response
-	return s.GetTaskByID(id)
+	task, err := s.GetTaskByID(id)
+	if err != nil || task == nil {
+		return task, err
+	}
+
+	s.events.Publish(events.Event{
+		Type:      events.TaskCreated,
+		EntityID:  task.ID,
+		ProjectID: task.ProjectID,
+		Data:      task,
+	})
+	if parentID != nil {
+		s.publishTasksUpdated([]string{*parentID})
+	}
+	s.publishTasksUpdated(spread)
+	return task, nil
 }
 
 func (s *TaskService) GetTaskByID(id string) (*model.TaskResponse, error) {
-	var dbTask database.Task
-	if err := s.db.DB.Preload("TaskTags.Tag").Preload("Agent").First(&dbTask, "id = ?", id).Error; err != nil {
-		if err == gorm.ErrRecordNotFound {
+	dbTask, err := s.stores.Tasks().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			return nil, nil
 		}
 		s.logger.Error("Failed to get task", zap.Error(err), zap.String("id", id))
 		return nil, err
 	}
 
-	return s.dbTaskToResponse(&dbTask), nil
+	tasks := []model.TaskResponse{*s.dbTaskToResponse(dbTask)}
+	if err := s.enrichTasks(tasks); err != nil {
+		return nil, err
+	}
+	return &tasks[0], nil
 }
 
-func (s *TaskService) GetTasks() (*model.TaskListResponse, error) {
-	var dbTasks []database.Task
-	var total int64
+// GetTasks lists the tasks matching the query. Total counts every matching
+// task; when a limit is set, NextCursor points at the following page.
+func (s *TaskService) GetTasks(query *model.TaskListQuery) (*model.TaskListResponse, error) {
+	sort, err := parseTaskSort(query.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if query.Limit < 0 || query.Limit > maxTaskLimit {
+		return nil, fmt.Errorf("%w: limit must be between 1 and %d", ErrInvalidTaskQuery, maxTaskLimit)
+	}
 
-	if err := s.db.DB.Preload("TaskTags.Tag").Preload("Agent").Find(&dbTasks).Error; err != nil {
-		s.logger.Error("Failed to get tasks", zap.Error(err))
+	filter, err := s.taskFilter(query)
+	if err != nil {
+		return nil, err
+	}
+	page, err := taskPage(sort, query.Cursor)
+	if err != nil {
 		return nil, err
 	}
+	if query.Limit > 0 {
+		// Fetch one extra task to know whether another page follows
+		page.Limit = query.Limit + 1
+	}
 
-	if err := s.db.DB.Model(&database.Task{}).Count(&total).Error; err != nil {
+	total, err := s.stores.Tasks().Count(filter)
+	if err != nil {
 		s.logger.Error("Failed to count tasks", zap.Error(err))
 		return nil, err
 	}
 
+	dbTasks, err := s.stores.Tasks().Find(filter, page)
+	if err != nil {
+		s.logger.Error("Failed to get tasks", zap.Error(err))
+		return nil, err
+	}
+
+	var nextCursor string
+	if query.Limit > 0 && len(dbTasks) > query.Limit {
+		dbTasks = dbTasks[:query.Limit]
+		nextCursor = encodeTaskCursor(&dbTasks[len(dbTasks)-1], sort)
+	}
+
 	tasks := make([]model.TaskResponse, len(dbTasks))
 	for i, dbTask := range dbTasks {
 		tasks[i] = *s.dbTaskToResponse(&dbTask)
 	}
+	if err := s.enrichTasks(tasks); err != nil {
+		return nil, err
+	}
 
 	return &model.TaskListResponse{
-		Tasks: tasks,
-		Total: total,
+		Tasks:      tasks,
+		Total:      total,
+		NextCursor: nextCursor,
 	}, nil
 }
 
-func (s *TaskService) UpdateTask(id string, req *model.UpdateTaskRequest) (*model.TaskResponse, error) {
-	// Start transaction
-	tx := s.db.DB.Begin()
-	defer func() {
-		if r := recover(); r != nil {
-			tx.Rollback()
-		}
-	}()
+func (s *TaskService) UpdateTask(id string, req *model.UpdateTaskRequest, opts MutationOptions) (*model.TaskResponse, error) {
+	return s.PatchTask(id, req.Patch(), opts)
+}
 
-	var dbTask database.Task
-	if err := tx.First(&dbTask, "id = ?", id).Error; err != nil {
-		tx.Rollback()
-		if err == gorm.ErrRecordNotFound {
+// PatchTask applies a JSON merge patch to a task. It returns nil when the
+// task does not exist.
+func (s *TaskService) PatchTask(id string, req *model.PatchTaskRequest, opts MutationOptions) (*model.TaskResponse, error) {
+	var update *taskUpdate
+	if err := s.stores.Transaction(func(st store.Store) error {
+		var err error
+		update, err = s.updateTask(st, id, req, opts)
+		return err
+	}); err != nil {
+		return nil, err
+	}
+	if update == nil {
+		return nil, nil
+	}
+
+	return s.finishUpdate(update, opts)
+}
+
+// taskUpdate is a task change made in a transaction, kept for the work that
+// follows once it is committed.
+type taskUpdate struct {
+	task              database.Task
+	previousStatus    string
+	previousProjectID string
+	parents           []string // Parents whose subtask rollup changed
+	spread            []string // Tasks of the column spread out to make room
+}
+
+// updateTask applies a task update in st. It returns nil when the task does
+// not exist.
+func (s *TaskService) updateTask(st store.Store, id string, req *model.PatchTaskRequest, opts MutationOptions) (*taskUpdate, error) {
+	dbTask, err := st.Tasks().Get(id)
+	if err != nil {
+		if err == store.ErrNotFound {
 			return nil, nil
 		}
 		s.logger.Error("Failed to get task for update", zap.Error(err), zap.String("id", id))
 		return nil, err
 	}
+	if err := checkVersion(dbTask.Version, opts); err != nil {
+		return nil, err
+	}
+
+	previousProjectID := dbTask.ProjectID
+	previousStatus := dbTask.Status
+	previousParentID := dbTask.ParentID
+	previouslyArchived := dbTask.ArchivedAt != nil
+
+	tags, err := st.Tasks().TagNames([]string{id})
+	if err != nil {
+		s.logger.Error("Failed to get task tags", zap.Error(err), zap.String("id", id))
+		return nil, err
+	}
+	before := taskSnapshot(dbTask, tags[id])
 
 	// Update fields
-	if req.Title != "" {
-		dbTask.Title = req.Title
+	if err := patchRequired(&dbTask.Title, req.Title, "title"); err != nil {
+		return nil, err
+	}
+	if err := patchRequired(&dbTask.Status, req.Status, "status"); err != nil {
+		return nil, err
+	}
+	// Null moves the task out of its project, onto the default workflow
+	patchString(&dbTask.ProjectID, req.ProjectID)
+	if req.Priority.Set {
+		switch req.Priority.Value {
+		case "":
+			// Null resets the priority
+			dbTask.Priority = model.PriorityMedium
+		case model.PriorityLow, model.PriorityMedium, model.PriorityHigh, model.PriorityUrgent:
+			dbTask.Priority = req.Priority.Value
+		default:
+			return nil, fmt.Errorf("%w: unknown priority %q", ErrInvalidPatch, req.Priority.Value)
+		}
+	}
+	if req.DueDate.Set {
+		dbTask.DueDate = nil
+		if !req.DueDate.Null {
+			dbTask.DueDate = localTime(&req.DueDate.Value)
+		}
+	}
+	patchString(&dbTask.Description, req.Description)
+	patchString(&dbTask.Assignee, req.Assignee)
+	patchReference(&dbTask.AgentID, req.AgentID)
+	if req.Archived != nil {
+		dbTask.ArchivedAt = archivedAt(dbTask.ArchivedAt, *req.Archived)
+	}
+	if req.AgentID.Set {
+		if err := checkAgent(st, dbTask.AgentID); err != nil {
+			return nil, err
+		}
 	}
-	if req.Description != "" {
-		dbTask.Description = req.Description
+	if dbTask.ProjectID != previousProjectID {
+		if err := checkProject(st, dbTask.ProjectID); err != nil {
+			return nil, err
+		}
 	}
-	if req.Status != "" {
-		dbTask.Status = req.Status
+	if req.ParentID.Set {
+		if req.ParentID.Value == "" {
+			dbTask.ParentID = nil
+		} else {
+			parent, err := s.loadParent(st, id, req.ParentID.Value)
+			if err != nil {
+				return nil, err
+			}
+			dbTask.ParentID = &parent.ID
+		}
 	}
-	if req.Assignee != "" {
-		dbTask.Assignee = req.Assignee
+
+	if dbTask.ProjectID != previousProjectID && !req.Status.Set {
+		// The status must also exist in the workflow of the new project
+		if err := s.workflows.ValidateStatus(dbTask.ProjectID, dbTask.Status); err != nil {
+			return nil, err
+		}
+	} else if dbTask.Status != previousStatus {
+		if err := s.workflows.ValidateTransition(dbTask.ProjectID, previousStatus, dbTask.Status); err != nil {
+			return nil, err
+		}
 	}
-	if req.ProjectID != "" {
-		dbTask.ProjectID = req.ProjectID
+
+	if dbTask.Status != previousStatus && dbTask.Status == model.TaskStatusInProgress && !opts.Force {
+		blockedBy, err := s.blockers(st, id)
+		if err != nil {
+			return nil, err
+		}
+		if len(blockedBy) > 0 {
+			return nil, &BlockedError{BlockedBy: blockedBy}
+		}
 	}
-	if req.AgentID != nil {
-		dbTask.AgentID = req.AgentID
+
+	var spread []string
+	if req.Position != "" {
+		dbTask.Position = req.Position
+	} else if dbTask.Status != previousStatus || dbTask.ProjectID != previousProjectID {
+		if dbTask.Position, spread, err = lastPosition(st, dbTask.ProjectID, dbTask.Status); err != nil {
+			s.logger.Error("Failed to position task", zap.Error(err), zap.String("id", id))
+			return nil, err
+		}
 	}
 
-	dbTask.UpdatedAt = time.Now()
+	now := time.Now()
+	dbTask.UpdatedAt = now
+
+	// Finished tasks are archived some time after they were completed, so
+	// the clock starts over when a task is finished again or unarchived
+	final := s.workflows.IsFinal(dbTask.ProjectID, dbTask.Status)
+	finishedChanged := s.workflows.IsFinal(previousProjectID, previousStatus) != final
+	switch {
+	case !final:
+		dbTask.CompletedAt = nil
+	case finishedChanged, dbTask.CompletedAt == nil, previouslyArchived && dbTask.ArchivedAt == nil:
+		dbTask.CompletedAt = &now
+	}
 
-	if err := tx.Save(&dbTask).Error; err != nil {
-		tx.Rollback()
-		s.logger.Error("Failed to update task", zap.Error(err), zap.String("id", id))
+	if err := st.Tasks().Update(dbTask); err != nil {
+		if err != ErrVersionMismatch {
+			s.logger.Error("Failed to update task", zap.Error(err), zap.String("id", id))
+		}
 		return nil, err
 	}
 
 	// Handle tags if provided
-	if req.Tags != nil {
-		if err := s.handleTaskTags(tx, id, req.Tags); err != nil {
-			tx.Rollback()
+	if req.Tags.Set {
+		if err := st.Tasks().SetTags(id, req.Tags.Value); err != nil {
 			s.logger.Error("Failed to handle task tags", zap.Error(err))
 			return nil, err
 		}
+		if tags, err = st.Tasks().TagNames([]string{id}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := storeActivity(st, model.EntityTask, id, dbTask.ProjectID, model.ActivityUpdated, opts.Actor,
+		before, taskSnapshot(dbTask, tags[id])); err != nil {
+		s.logger.Error("Failed to record task activity", zap.Error(err))
+		return nil, err
 	}
 
-	if err := tx.Commit().Error; err != nil {
-		s.logger.Error("Failed to commit transaction", zap.Error(err))
+	// Parents count their finished subtasks, dependents list the unfinished
+	// tasks blocking them
+	var parents []string
+	for _, parentID := range []*string{previousParentID, dbTask.ParentID} {
+		if parentID == nil || (len(parents) > 0 && parents[0] == *parentID) {
+			continue
+		}
+		parents = append(parents, *parentID)
+	}
+	// A parent kept by a task whose finished-ness did not change is untouched
+	if len(parents) == 1 && previousParentID != nil && dbTask.ParentID != nil && !finishedChanged {
+		parents = nil
+	}
+	touched := parents
+	if finishedChanged {
+		dependents, err := st.Dependencies().Dependents([]string{id})
+		if err != nil {
+			return nil, err
+		}
+		touched = append(touched, dependents...)
+	}
+	if err := st.Tasks().BumpVersions(touched); err != nil {
 		return nil, err
 	}
 
-	return s.GetTaskByID(id)
+	return &taskUpdate{
+		task:              *dbTask,
+		previousStatus:    previousStatus,
+		previousProjectID: previousProjectID,
+		parents:           parents,
+		spread:            spread,
+	}, nil
+}
+
+// finishUpdate cleans up after a committed task update and publishes it.
+func (s *TaskService) finishUpdate(update *taskUpdate, opts MutationOptions) (*model.TaskResponse, error) {
+	dbTask := &update.task
+	id := dbTask.ID
+
+	// Finished tasks no longer need a checkout; the branch stays for review
+	if dbTask.Status != update.previousStatus && s.workflows.IsFinal(dbTask.ProjectID, dbTask.Status) {
+		if err := s.worktrees.RemoveWorktree(dbTask, false); err != nil {
+			s.logger.Warn("Failed to clean up task worktree", zap.Error(err), zap.String("id", id))
+		}
+	}
+
+	// Cancelling a task cancels the work broken out of it
+	if dbTask.Status != update.previousStatus && dbTask.Status == model.TaskStatusCancelled {
+		s.cancelSubtasks(id, opts.Actor)
+	}
+
+	task, err := s.GetTaskByID(id)
+	if err != nil || task == nil {
+		return task, err
+	}
+
+	event := events.Event{
+		Type:      events.TaskUpdated,
+		EntityID:  task.ID,
+		ProjectID: task.ProjectID,
+		Data:      task,
+	}
+	if update.previousProjectID != task.ProjectID {
+		event.PreviousProjectID = update.previousProjectID
+	}
+	s.events.Publish(event)
+
+	if dbTask.Status != update.previousStatus &&
+		s.workflows.IsFinal(dbTask.ProjectID, dbTask.Status) != s.workflows.IsFinal(dbTask.ProjectID, update.previousStatus) {
+		s.publishDependentsUpdated(id)
+	}
+	s.publishTasksUpdated(update.parents)
+	s.publishTasksUpdated(update.spread)
+	return task, nil
 }
 
-func (s *TaskService) DeleteTask(id string) error {
-	result := s.db.DB.Delete(&database.Task{}, "id = ?", id)
-	if result.Error != nil {
-		s.logger.Error("Failed to delete task", zap.Error(result.Error), zap.String("id", id))
-		return result.Error
+// advanceStatus moves a task along the workflow as a side effect of another
+// action, e.g. when an agent run starts or a branch is merged; actor is
+// recorded as the one who made the change. Nothing happens when the task is
+// no longer in the expected status or the project's workflow does not allow
+// the move. Dependencies are not checked; callers that start work check
+// them first.
+func (s *TaskService) advanceStatus(task *database.Task, from, to, actor string) (*model.TaskResponse, error) {
+	if task.Status != from || !s.workflows.CanTransition(task.ProjectID, from, to) {
+		s.logger.Debug("Skipping task status change",
+			zap.String("id", task.ID),
+			zap.String("status", task.Status),
+			zap.String("to", to))
+		return s.GetTaskByID(task.ID)
 	}
 
-	if result.RowsAffected == 0 {
-		return gorm.ErrRecordNotFound
+	response, err := s.UpdateTask(task.ID, &model.UpdateTaskRequest{Status: to}, MutationOptions{Force: true, Actor: actor})
+	if err == nil && response != nil {
+		task.Status = response.Status
+	}
+	return response, err
+}
+
+// DeleteTask moves a task together with all of its subtasks to the trash.
+func (s *TaskService) DeleteTask(id string, opts MutationOptions) error {
+	var deletion *taskDeletion
+	if err := s.stores.Transaction(func(st store.Store) error {
+		var err error
+		deletion, err = s.deleteTask(st, id, time.Now(), uuid.New().String(), opts)
+		return err
+	}); err != nil {
+		return err
 	}
 
+	s.finishDelete(deletion)
 	return nil
 }
 
-func (s *TaskService) dbTaskToResponse(dbTask *database.Task) *model.TaskResponse {
-	var tags []string
+// taskDeletion lists the tasks a committed delete removed and the tasks
+// that were waiting for them.
+type taskDeletion struct {
+	deleted    []database.Task
+	dependents []string
+	parentID   *string // Parent of the deleted task, which keeps its other subtasks
+}
 
-	// Get tags from TaskTags relation
-	if len(dbTask.TaskTags) > 0 {
-		tags = make([]string, len(dbTask.TaskTags))
-		for i, taskTag := range dbTask.TaskTags {
-			tags[i] = taskTag.Tag.Name
+// deleteTask moves a task and its subtasks to the trash in st. Everything
+// trashed by one delete shares batch, which lets a restore bring it back as
+// a whole. It returns store.ErrNotFound when the task does not exist.
+func (s *TaskService) deleteTask(st store.Store, id string, deletedAt time.Time, batch string, opts MutationOptions) (*taskDeletion, error) {
+	dbTask, err := st.Tasks().Get(id)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to get task for delete", zap.Error(err), zap.String("id", id))
 		}
-	} else {
-		tags = []string{}
+		return nil, err
+	}
+	if err := checkVersion(dbTask.Version, opts); err != nil {
+		return nil, err
+	}
+
+	subtasks, err := s.descendants(st, id)
+	if err != nil {
+		s.logger.Error("Failed to get subtasks for delete", zap.Error(err), zap.String("id", id))
+		return nil, err
+	}
+
+	ids := []string{id}
+	for _, subtask := range subtasks {
+		ids = append(ids, subtask.ID)
+	}
+
+	dependents, err := st.Dependencies().Dependents(ids)
+	if err != nil {
+		s.logger.Error("Failed to get dependent tasks", zap.Error(err), zap.String("id", id))
+		return nil, err
 	}
 
-	// Handle agent
-	var agent *model.Agent
-	if dbTask.Agent != nil {
-		agent = &model.Agent{
-			ID:          dbTask.Agent.ID,
-			Name:        dbTask.Agent.Name,
-			Type:        dbTask.Agent.Type,
-			Description: dbTask.Agent.Description,
-			CreatedAt:   dbTask.Agent.CreatedAt,
-			UpdatedAt:   dbTask.Agent.UpdatedAt,
+	deleted := append([]database.Task{*dbTask}, subtasks...)
+
+	tags, err := st.Tasks().TagNames(ids)
+	if err != nil {
+		return nil, err
+	}
+	for i := range deleted {
+		if err := storeActivity(st, model.EntityTask, deleted[i].ID, deleted[i].ProjectID, model.ActivityDeleted, opts.Actor,
+			taskSnapshot(&deleted[i], tags[deleted[i].ID]), nil); err != nil {
+			s.logger.Error("Failed to record task activity", zap.Error(err))
+			return nil, err
 		}
 	}
 
-	return &model.TaskResponse{
-		ID:          dbTask.ID,
-		Title:       dbTask.Title,
-		Description: dbTask.Description,
-		Status:      dbTask.Status,
-		Assignee:    dbTask.Assignee,
-		AgentID:     dbTask.AgentID,
-		Agent:       agent,
-		Tags:        tags,
-		ProjectID:   dbTask.ProjectID,
-		CreatedAt:   dbTask.CreatedAt,
-		UpdatedAt:   dbTask.UpdatedAt,
+	// Everything attached to the tasks stays until they are purged
+	if err := st.Tasks().Trash(ids, deletedAt, batch); err != nil {
+		s.logger.Error("Failed to delete task", zap.Error(err), zap.String("id", id))
+		return nil, err
 	}
-}
 
-// handleTaskTags manages the many-to-many relationship between tasks and tags
-func (s *TaskService) handleTaskTags(tx *gorm.DB, taskID string, tagNames []string) error {
-	// Remove existing task-tag associations
-	if err := tx.Where("task_id = ?", taskID).Delete(&database.TaskTag{}).Error; err != nil {
-		return err
+	// The parent loses a subtask, dependents a blocker
+	touched := dependents
+	if dbTask.ParentID != nil {
+		touched = append([]string{*dbTask.ParentID}, dependents...)
+	}
+	if err := st.Tasks().BumpVersions(touched); err != nil {
+		return nil, err
 	}
 
-	// If no tags provided, we're done
-	if len(tagNames) == 0 {
-		return nil
+	return &taskDeletion{
+		deleted:    deleted,
+		dependents: dependents,
+		parentID:   dbTask.ParentID,
+	}, nil
+}
+
+// finishDelete publishes committed task deletes. Worktrees are kept until
+// the tasks are purged from the trash.
+func (s *TaskService) finishDelete(deletion *taskDeletion) {
+	for i := range deletion.deleted {
+		s.events.Publish(events.Event{
+			Type:      events.TaskDeleted,
+			EntityID:  deletion.deleted[i].ID,
+			ProjectID: deletion.deleted[i].ProjectID,
+		})
 	}
 
-	// Get or create tags
-	var tagIDs []string
-	for _, tagName := range tagNames {
-		if tagName == "" {
-			continue
+	// Tasks waiting for a deleted task are no longer blocked by it
+	for _, dependent := range deletion.dependents {
+		if _, err := s.publishTaskUpdated(dependent); err != nil {
+			s.logger.Warn("Failed to publish dependent task", zap.Error(err), zap.String("id", dependent))
 		}
+	}
+	if deletion.parentID != nil {
+		s.publishTasksUpdated([]string{*deletion.parentID})
+	}
+}
 
-		var tag database.Tag
-		err := tx.Where("name = ?", tagName).First(&tag).Error
-		if err == gorm.ErrRecordNotFound {
-			// Create new tag
-			tag = database.Tag{
-				ID:        uuid.New().String(),
-				Name:      tagName,
-				CreatedAt: time.Now(),
-				UpdatedAt: time.Now(),
-			}
-			if err := tx.Create(&tag).Error; err != nil {
-				return err
-			}
-		} else if err != nil {
-			return err
-		}
+// enrichTasks adds the computed subtask rollups, comment counts and blockers
+// to task responses.
+func (s *TaskService) enrichTasks(tasks []model.TaskResponse) error {
+	if len(tasks) == 0 {
+		return nil
+	}
 
-		tagIDs = append(tagIDs, tag.ID)
+	if err := s.attachSubtaskRollups(tasks); err != nil {
+		return err
+	}
+	if err := s.attachCommentCounts(tasks); err != nil {
+		return err
 	}
 
-	// Create task-tag associations
-	for _, tagID := range tagIDs {
-		taskTag := database.TaskTag{
-			TaskID: taskID,
-			TagID:  tagID,
-		}
-		if err := tx.Create(&taskTag).Error; err != nil {
-			return err
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	blockedBy, err := s.blockedBy(s.stores, ids)
+	if err != nil {
+		return err
+	}
+	for i := range tasks {
+		tasks[i].BlockedBy = blockedBy[tasks[i].ID]
+	}
+	return nil
+}
+
+func (s *TaskService) dbTaskToResponse(dbTask *database.Task) *model.TaskResponse {
+	// Get tags from TaskTags relation
+	tags := make([]string, len(dbTask.TaskTags))
+	tagDetails := make([]model.TagRef, len(dbTask.TaskTags))
+	for i, taskTag := range dbTask.TaskTags {
+		tags[i] = taskTag.Tag.Name
+		tagDetails[i] = model.TagRef{
+			ID:    taskTag.Tag.ID,
+			Name:  taskTag.Tag.Name,
+			Color: taskTag.Tag.Color,
 		}
 	}
 
-	return nil
+	return &model.TaskResponse{
+		ID:           dbTask.ID,
+		Title:        dbTask.Title,
+		Description:  dbTask.Description,
+		Status:       dbTask.Status,
+		Priority:     dbTask.Priority,
+		DueDate:      dbTask.DueDate,
+		Assignee:     dbTask.Assignee,
+		AgentID:      dbTask.AgentID,
+		Agent:        agentModel(dbTask.Agent),
+		Tags:         tags,
+		TagDetails:   tagDetails,
+		ProjectID:    dbTask.ProjectID,
+		ParentID:     dbTask.ParentID,
+		Position:     dbTask.Position,
+		Branch:       dbTask.Branch,
+		BaseBranch:   dbTask.BaseBranch,
+		WorktreePath: dbTask.WorktreePath,
+		Version:      dbTask.Version,
+		CompletedAt:  dbTask.CompletedAt,
+		ArchivedAt:   dbTask.ArchivedAt,
+		CreatedAt:    dbTask.CreatedAt,
+		UpdatedAt:    dbTask.UpdatedAt,
+	}
 }
\ No newline at end of file
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+const (
+	defaultTaskSort = "position"
+	maxTaskLimit    = 500
+)
+
+var ErrInvalidTaskQuery = errors.New("invalid task query")
+
+// taskCursor marks the last task of a page. Sort is recorded so a cursor
+// cannot be replayed against a different ordering.
+type taskCursor struct {
+	Sort  string      `json:"s"`
+	Value interface{} `json:"v"`
+	ID    string      `json:"id"`
+}
+
+// taskSort is a parsed sort parameter.
+type taskSort struct {
+	key   string
+	name  string
+	field store.TaskSortField
+	desc  bool
+}
+
+func parseTaskSort(sort string) (taskSort, error) {
+	if sort == "" {
+		sort = defaultTaskSort
+	}
+
+	name := strings.TrimPrefix(sort, "-")
+	field, ok := store.TaskSortFields[name]
+	if !ok {
+		return taskSort{}, fmt.Errorf("%w: cannot sort by %q", ErrInvalidTaskQuery, name)
+	}
+
+	return taskSort{key: sort, name: name, field: field, desc: strings.HasPrefix(sort, "-")}, nil
+}
+
+// taskFilter turns the filters of a list query into a store filter.
+func (s *TaskService) taskFilter(query *model.TaskListQuery) (store.TaskFilter, error) {
+	filter := store.TaskFilter{
+		ProjectID:       query.ProjectID,
+		ParentID:        query.ParentID,
+		AgentID:         query.AgentID,
+		Assignee:        query.Assignee,
+		Statuses:        splitListParam(query.Status),
+		Tags:            splitListParam(query.Tag),
+		Text:            strings.TrimSpace(query.Q),
+		IncludeArchived: query.IncludeArchived,
+		Overdue:         query.Overdue,
+	}
+	if query.Overdue != nil {
+		final, err := s.finalStatuses()
+		if err != nil {
+			return store.TaskFilter{}, err
+		}
+		filter.Final = final
+	}
+	if query.CreatedAfter != "" {
+		after, err := parseQueryTime(query.CreatedAfter)
+		if err != nil {
+			return store.TaskFilter{}, fmt.Errorf("%w: created_after: %v", ErrInvalidTaskQuery, err)
+		}
+		filter.CreatedAfter = &after
+	}
+	if query.CreatedBefore != "" {
+		before, err := parseQueryTime(query.CreatedBefore)
+		if err != nil {
+			return store.TaskFilter{}, fmt.Errorf("%w: created_before: %v", ErrInvalidTaskQuery, err)
+		}
+		filter.CreatedBefore = &before
+	}
+	return filter, nil
+}
+
+// taskPage orders tasks by the sort and positions them after the cursor,
+// if any.
+func taskPage(sort taskSort, cursor string) (store.TaskPage, error) {
+	page := store.TaskPage{Sort: sort.name, Desc: sort.desc}
+	if cursor != "" {
+		after, err := decodeTaskCursor(cursor, sort)
+		if err != nil {
+			return store.TaskPage{}, err
+		}
+		page.After = after
+	}
+	return page, nil
+}
+
+func encodeTaskCursor(task *database.Task, sort taskSort) string {
+	value := sort.field.Value(task)
+	if t, ok := value.(time.Time); ok {
+		value = t.Format(time.RFC3339Nano)
+	}
+
+	data, _ := json.Marshal(taskCursor{Sort: sort.key, Value: value, ID: task.ID})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeTaskCursor(cursor string, sort taskSort) (*store.TaskCursor, error) {
+	invalid := fmt.Errorf("%w: malformed cursor", ErrInvalidTaskQuery)
+
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, invalid
+	}
+
+	var decoded taskCursor
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.ID == "" {
+		return nil, invalid
+	}
+	if decoded.Sort != sort.key {
+		return nil, fmt.Errorf("%w: cursor was issued for sort %q", ErrInvalidTaskQuery, decoded.Sort)
+	}
+
+	switch value := decoded.Value.(type) {
+	case string:
+		if !sort.field.Time {
+			return &store.TaskCursor{Value: value, ID: decoded.ID}, nil
+		}
+		t, err := time.Parse(time.RFC3339Nano, value)
+		if err != nil {
+			return nil, invalid
+		}
+		// Timestamps are stored in local time, which SQLite compares as text
+		return &store.TaskCursor{Value: t.Local(), ID: decoded.ID}, nil
+	case float64:
+		// Ranks are the only numbers
+		if sort.field.Time {
+			return nil, invalid
+		}
+		return &store.TaskCursor{Value: int(value), ID: decoded.ID}, nil
+	}
+	return nil, invalid
+}
+
+// finalStatuses resolves the statuses that finish tasks, for queries over
+// tasks of every project.
+func (s *TaskService) finalStatuses() (store.FinalStatuses, error) {
+	final, err := s.workflows.finalStatuses()
+	if err != nil {
+		s.logger.Error("Failed to resolve final statuses", zap.Error(err))
+	}
+	return final, err
+}
+
+// localTime converts timestamps from clients to local time, the zone every
+// other timestamp is stored in, so stored values stay comparable.
+func localTime(t *time.Time) *time.Time {
+	if t == nil {
+		return nil
+	}
+	local := t.Local()
+	return &local
+}
+
+func parseQueryTime(value string) (time.Time, error) {
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t.Local(), nil
+	}
+	return time.ParseInLocation("2006-01-02", value, time.Local)
+}
+
+// splitListParam flattens repeated and comma separated query values.
+func splitListParam(values []string) []string {
+	var result []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			if part = strings.TrimSpace(part); part != "" {
+				result = append(result, part)
+			}
+		}
+	}
+	return result
+}
@@ -0,0 +1,857 @@
+package service
+
+import (
+	"errors"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+func (s *testServices) project(t *testing.T, req *model.CreateProjectRequest) *model.ProjectResponse {
+	t.Helper()
+	project, err := s.projects.CreateProject(req, MutationOptions{})
+	if err != nil {
+		t.Fatalf("CreateProject: %v", err)
+	}
+	return project
+}
+
+func (s *testServices) task(t *testing.T, req *model.CreateTaskRequest) *model.TaskResponse {
+	t.Helper()
+	task, err := s.tasks.CreateTask(req, MutationOptions{})
+	if err != nil {
+		t.Fatalf("CreateTask: %v", err)
+	}
+	return task
+}
+
+func TestPatchTask(t *testing.T) {
+	tests := []struct {
+		name    string
+		patch   func(task, other *model.TaskResponse) *model.PatchTaskRequest
+		opts    MutationOptions
+		wantErr error
+		check   func(t *testing.T, task *model.TaskResponse)
+	}{
+		{
+			name: "fields and tags",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{
+					Title:    model.PatchValue("renamed"),
+					Priority: model.PatchValue(model.PriorityHigh),
+					Tags:     model.PatchValue([]string{"b", "a", "b"}),
+				}
+			},
+			check: func(t *testing.T, task *model.TaskResponse) {
+				if task.Title != "renamed" || task.Priority != model.PriorityHigh || task.Version != 3 {
+					t.Errorf("got %q, %s, version %d", task.Title, task.Priority, task.Version)
+				}
+				tags := append([]string(nil), task.Tags...)
+				sort.Strings(tags)
+				if !reflect.DeepEqual(tags, []string{"a", "b"}) {
+					t.Errorf("tags = %v, want a and b", task.Tags)
+				}
+			},
+		},
+		{
+			name: "null priority resets it",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{Priority: model.PatchNull[string]()}
+			},
+			check: func(t *testing.T, task *model.TaskResponse) {
+				if task.Priority != model.PriorityMedium {
+					t.Errorf("priority = %s, want %s", task.Priority, model.PriorityMedium)
+				}
+			},
+		},
+		{
+			name: "unknown priority",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{Priority: model.PatchValue("someday")}
+			},
+			wantErr: ErrInvalidPatch,
+		},
+		{
+			name: "matching version",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{Title: model.PatchValue("renamed")}
+			},
+			opts: MutationOptions{IfMatch: []int64{2}},
+		},
+		{
+			name: "stale version",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{Title: model.PatchValue("renamed")}
+			},
+			opts:    MutationOptions{IfMatch: []int64{1}},
+			wantErr: ErrVersionMismatch,
+		},
+		{
+			name: "parent",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{ParentID: model.PatchValue(other.ID)}
+			},
+			check: func(t *testing.T, task *model.TaskResponse) {
+				if task.ParentID == nil {
+					t.Error("parent not set")
+				}
+			},
+		},
+		{
+			name: "own parent",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{ParentID: model.PatchValue(task.ID)}
+			},
+			wantErr: ErrTaskCycle,
+		},
+		{
+			name: "missing parent",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{ParentID: model.PatchValue("missing")}
+			},
+			wantErr: ErrParentNotFound,
+		},
+		{
+			name: "missing agent",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{AgentID: model.PatchValue("missing")}
+			},
+			wantErr: ErrAgentNotFound,
+		},
+		{
+			name: "null project",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{ProjectID: model.PatchNull[string]()}
+			},
+			check: func(t *testing.T, task *model.TaskResponse) {
+				if task.ProjectID != "" {
+					t.Errorf("project = %q, want none", task.ProjectID)
+				}
+			},
+		},
+		{
+			name: "blocked start",
+			patch: func(task, other *model.TaskResponse) *model.PatchTaskRequest {
+				return &model.PatchTaskRequest{Status: model.PatchValue(model.TaskStatusInProgress)}
+			},
+			wantErr: &BlockedError{},
+		},
+	}
+
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := newServices(t)
+				project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+				task := s.task(t, &model.CreateTaskRequest{Title: "task", ProjectID: project.ID})
+				other := s.task(t, &model.CreateTaskRequest{Title: "other", ProjectID: project.ID})
+				// Adding the dependency moves the task to version 2
+				if _, err := s.tasks.AddDependency(task.ID, &model.AddDependencyRequest{DependsOnID: other.ID}, MutationOptions{}); err != nil {
+					t.Fatalf("AddDependency: %v", err)
+				}
+
+				got, err := s.tasks.PatchTask(task.ID, tt.patch(task, other), tt.opts)
+				var blocked *BlockedError
+				switch {
+				case errors.As(tt.wantErr, &blocked):
+					if !errors.As(err, &blocked) || !reflect.DeepEqual(blocked.BlockedBy, []string{other.ID}) {
+						t.Fatalf("err = %v, want blocked by %s", err, other.ID)
+					}
+					return
+				case tt.wantErr != nil:
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("err = %v, want %v", err, tt.wantErr)
+					}
+					return
+				case err != nil:
+					t.Fatalf("PatchTask: %v", err)
+				}
+				if tt.check != nil {
+					tt.check(t, got)
+				}
+			})
+		}
+	})
+}
+
+func TestLeaveProject(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		workflow := DefaultWorkflow()
+		workflow.Statuses = append(workflow.Statuses, model.WorkflowStatus{Key: "blocked", Name: "Blocked"})
+		if _, err := s.tasks.workflows.UpdateWorkflow(project.ID, &workflow, MutationOptions{}); err != nil {
+			t.Fatalf("UpdateWorkflow: %v", err)
+		}
+		task := s.task(t, &model.CreateTaskRequest{Title: "t", ProjectID: project.ID, Status: "blocked"})
+
+		// The default workflow has no blocked status
+		leave := &model.PatchTaskRequest{ProjectID: model.PatchNull[string]()}
+		var statusErr *StatusError
+		if _, err := s.tasks.PatchTask(task.ID, leave, MutationOptions{}); !errors.As(err, &statusErr) {
+			t.Fatalf("leaving with a status of the project only: err = %v, want a status error", err)
+		}
+
+		leave.Status = model.PatchValue(model.TaskStatusTodo)
+		got, err := s.tasks.PatchTask(task.ID, leave, MutationOptions{})
+		if err != nil {
+			t.Fatalf("PatchTask: %v", err)
+		}
+		if got.ProjectID != "" || got.Status != model.TaskStatusTodo {
+			t.Errorf("task in project %q, status %s; want none, %s", got.ProjectID, got.Status, model.TaskStatusTodo)
+		}
+	})
+}
+
+func TestAddDependency(t *testing.T) {
+	tests := []struct {
+		name    string
+		task    int
+		on      int
+		wantErr error
+	}{
+		{name: "new", task: 2, on: 0},
+		{name: "existing", task: 1, on: 0},
+		{name: "on itself", task: 0, on: 0, wantErr: ErrDependencyCycle},
+		{name: "cycle", task: 0, on: 1, wantErr: ErrDependencyCycle},
+		{name: "transitive cycle", task: 0, on: 2, wantErr: ErrDependencyCycle},
+	}
+
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := newServices(t)
+				project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+				var tasks []*model.TaskResponse
+				for _, title := range []string{"a", "b", "c"} {
+					tasks = append(tasks, s.task(t, &model.CreateTaskRequest{Title: title, ProjectID: project.ID}))
+				}
+				// b waits for a, c waits for b
+				for i := 1; i < len(tasks); i++ {
+					if _, err := s.tasks.AddDependency(tasks[i].ID, &model.AddDependencyRequest{DependsOnID: tasks[i-1].ID}, MutationOptions{}); err != nil {
+						t.Fatalf("AddDependency: %v", err)
+					}
+				}
+
+				task, err := s.tasks.AddDependency(tasks[tt.task].ID, &model.AddDependencyRequest{DependsOnID: tasks[tt.on].ID}, MutationOptions{})
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("err = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("AddDependency: %v", err)
+				}
+
+				var blockedBy []string
+				for _, dependency := range []int{tt.task - 1, tt.on} {
+					if dependency >= 0 && (len(blockedBy) == 0 || blockedBy[len(blockedBy)-1] != tasks[dependency].ID) {
+						blockedBy = append(blockedBy, tasks[dependency].ID)
+					}
+				}
+				if !reflect.DeepEqual(task.BlockedBy, blockedBy) {
+					t.Errorf("blocked by %v, want %v", task.BlockedBy, blockedBy)
+				}
+			})
+		}
+	})
+}
+
+func TestRelatedChangesMoveVersions(t *testing.T) {
+	done := &model.PatchTaskRequest{Status: model.PatchValue(model.TaskStatusDone)}
+	tests := []struct {
+		name   string
+		change func(s *testServices, tasks map[string]*model.TaskResponse) error
+		want   map[string]int64 // Version increments by task title
+	}{
+		{
+			name: "subtask created",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				_, err := s.tasks.CreateTask(&model.CreateTaskRequest{Title: "new", ParentID: &tasks["parent"].ID}, MutationOptions{})
+				return err
+			},
+			want: map[string]int64{"parent": 1},
+		},
+		{
+			name: "subtask finished",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				_, err := s.tasks.PatchTask(tasks["child"].ID, done, MutationOptions{})
+				return err
+			},
+			want: map[string]int64{"parent": 1, "child": 1},
+		},
+		{
+			name: "subtask renamed",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				_, err := s.tasks.PatchTask(tasks["child"].ID, &model.PatchTaskRequest{Title: model.PatchValue("renamed")}, MutationOptions{})
+				return err
+			},
+			want: map[string]int64{"child": 1},
+		},
+		{
+			name: "subtask moved out",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				_, err := s.tasks.PatchTask(tasks["child"].ID, &model.PatchTaskRequest{ParentID: model.PatchValue("")}, MutationOptions{})
+				return err
+			},
+			want: map[string]int64{"parent": 1, "child": 1},
+		},
+		{
+			name: "subtask deleted",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				return s.tasks.DeleteTask(tasks["child"].ID, MutationOptions{})
+			},
+			want: map[string]int64{"parent": 1},
+		},
+		{
+			name: "dependency added",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				_, err := s.tasks.AddDependency(tasks["parent"].ID, &model.AddDependencyRequest{DependsOnID: tasks["blocker"].ID}, MutationOptions{})
+				return err
+			},
+			want: map[string]int64{"parent": 1},
+		},
+		{
+			name: "dependency removed",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				return s.tasks.RemoveDependency(tasks["waiting"].ID, tasks["blocker"].ID, MutationOptions{})
+			},
+			want: map[string]int64{"waiting": 1},
+		},
+		{
+			name: "blocker finished",
+			change: func(s *testServices, tasks map[string]*model.TaskResponse) error {
+				_, err := s.tasks.PatchTask(tasks["blocker"].ID, done, MutationOptions{})
+				return err
+			},
+			want: map[string]int64{"blocker": 1, "waiting": 1},
+		},
+	}
+
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := newServices(t)
+				project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+				tasks := map[string]*model.TaskResponse{}
+				for _, title := range []string{"parent", "blocker", "waiting"} {
+					tasks[title] = s.task(t, &model.CreateTaskRequest{Title: title, ProjectID: project.ID})
+				}
+				tasks["child"] = s.task(t, &model.CreateTaskRequest{Title: "child", ParentID: &tasks["parent"].ID})
+				if _, err := s.tasks.AddDependency(tasks["waiting"].ID, &model.AddDependencyRequest{DependsOnID: tasks["blocker"].ID}, MutationOptions{}); err != nil {
+					t.Fatalf("AddDependency: %v", err)
+				}
+				versions := map[string]int64{}
+				for title, task := range tasks {
+					got, err := s.tasks.GetTaskByID(task.ID)
+					if err != nil {
+						t.Fatalf("GetTaskByID: %v", err)
+					}
+					versions[title] = got.Version
+				}
+
+				if err := tt.change(s, tasks); err != nil {
+					t.Fatalf("change: %v", err)
+				}
+
+				for title, task := range tasks {
+					got, err := s.tasks.GetTaskByID(task.ID)
+					if err != nil {
+						t.Fatalf("GetTaskByID: %v", err)
+					}
+					if got != nil && got.Version != versions[title]+tt.want[title] {
+						t.Errorf("%s version = %d, want %d", title, got.Version, versions[title]+tt.want[title])
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestSaveWorkflow(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+
+		workflow := DefaultWorkflow()
+		workflow.Statuses = append(workflow.Statuses, model.WorkflowStatus{Key: "blocked", Name: "Blocked"})
+		workflow.Transitions["blocked"] = []string{model.TaskStatusTodo}
+		if _, err := s.tasks.workflows.UpdateWorkflow(project.ID, &workflow, MutationOptions{Actor: "alice"}); err != nil {
+			t.Fatalf("UpdateWorkflow: %v", err)
+		}
+
+		task := s.task(t, &model.CreateTaskRequest{Title: "t", ProjectID: project.ID, Status: "blocked"})
+		var workflowErr *WorkflowError
+		_, err := s.tasks.workflows.ResetWorkflow(project.ID, MutationOptions{})
+		if !errors.As(err, &workflowErr) || !reflect.DeepEqual(workflowErr.InUse, map[string]int64{"blocked": 1}) {
+			t.Fatalf("dropping a status in use: err = %v, want statuses in use", err)
+		}
+		if _, err := s.tasks.PatchTask(task.ID, &model.PatchTaskRequest{Status: model.PatchValue(model.TaskStatusTodo)}, MutationOptions{}); err != nil {
+			t.Fatalf("PatchTask: %v", err)
+		}
+		if _, err := s.tasks.workflows.ResetWorkflow(project.ID, MutationOptions{}); err != nil {
+			t.Fatalf("ResetWorkflow: %v", err)
+		}
+
+		got, err := s.projects.GetProject(project.ID)
+		if err != nil {
+			t.Fatalf("GetProject: %v", err)
+		}
+		if got.Version != project.Version+2 {
+			t.Errorf("version = %d, want %d", got.Version, project.Version+2)
+		}
+	})
+}
+
+func TestArchiveFinishedTasks(t *testing.T) {
+	reopen := &model.PatchTaskRequest{Status: model.PatchValue(model.TaskStatusTodo)}
+	finish := &model.PatchTaskRequest{Status: model.PatchValue(model.TaskStatusDone)}
+	tests := []struct {
+		name         string
+		change       func(s *testServices, id string) error // After the task was finished 20 days ago
+		wantArchived bool
+	}{
+		{
+			name:         "untouched",
+			change:       func(s *testServices, id string) error { return nil },
+			wantArchived: true,
+		},
+		{
+			name: "edited",
+			change: func(s *testServices, id string) error {
+				_, err := s.tasks.PatchTask(id, &model.PatchTaskRequest{Title: model.PatchValue("renamed")}, MutationOptions{})
+				return err
+			},
+			wantArchived: true,
+		},
+		{
+			name: "finished again",
+			change: func(s *testServices, id string) error {
+				if _, err := s.tasks.PatchTask(id, reopen, MutationOptions{}); err != nil {
+					return err
+				}
+				_, err := s.tasks.PatchTask(id, finish, MutationOptions{})
+				return err
+			},
+		},
+		{
+			name: "unarchived",
+			change: func(s *testServices, id string) error {
+				if _, err := s.tasks.ArchiveTask(id, MutationOptions{}); err != nil {
+					return err
+				}
+				_, err := s.tasks.UnarchiveTask(id, MutationOptions{})
+				return err
+			},
+		},
+		{
+			name: "unarchived long ago",
+			change: func(s *testServices, id string) error {
+				if _, err := s.tasks.ArchiveTask(id, MutationOptions{}); err != nil {
+					return err
+				}
+				if _, err := s.tasks.UnarchiveTask(id, MutationOptions{}); err != nil {
+					return err
+				}
+				return backdateCompletion(s, id, 15)
+			},
+			wantArchived: true,
+		},
+		{
+			name: "reopened",
+			change: func(s *testServices, id string) error {
+				_, err := s.tasks.PatchTask(id, reopen, MutationOptions{})
+				return err
+			},
+		},
+	}
+
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := newServices(t)
+				project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+				task := s.task(t, &model.CreateTaskRequest{Title: "task", ProjectID: project.ID, Status: model.TaskStatusDone})
+				if task.CompletedAt == nil {
+					t.Fatal("task created done has no completed_at")
+				}
+
+				if err := backdateCompletion(s, task.ID, 20); err != nil {
+					t.Fatalf("backdating completion: %v", err)
+				}
+				if err := tt.change(s, task.ID); err != nil {
+					t.Fatalf("change: %v", err)
+				}
+
+				if _, err := s.tasks.ArchiveFinishedTasks(14); err != nil {
+					t.Fatalf("ArchiveFinishedTasks: %v", err)
+				}
+				got, err := s.tasks.GetTaskByID(task.ID)
+				if err != nil {
+					t.Fatalf("GetTaskByID: %v", err)
+				}
+				if archived := got.ArchivedAt != nil; archived != tt.wantArchived {
+					t.Errorf("archived = %v, want %v", archived, tt.wantArchived)
+				}
+			})
+		}
+	})
+}
+
+// backdateCompletion makes a task look finished the given number of days ago.
+func backdateCompletion(s *testServices, id string, days int) error {
+	dbTask, err := s.tasks.stores.Tasks().Get(id)
+	if err != nil {
+		return err
+	}
+	completedAt := time.Now().AddDate(0, 0, -days)
+	dbTask.CompletedAt = &completedAt
+	return s.tasks.stores.Tasks().Update(dbTask)
+}
+
+func TestDeleteTask(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		parent := s.task(t, &model.CreateTaskRequest{Title: "parent", ProjectID: project.ID})
+		child := s.task(t, &model.CreateTaskRequest{Title: "child", ParentID: &parent.ID})
+		grandchild := s.task(t, &model.CreateTaskRequest{Title: "grandchild", ParentID: &child.ID})
+		waiting := s.task(t, &model.CreateTaskRequest{Title: "waiting", ProjectID: project.ID})
+		if _, err := s.tasks.AddDependency(waiting.ID, &model.AddDependencyRequest{DependsOnID: grandchild.ID}, MutationOptions{}); err != nil {
+			t.Fatalf("AddDependency: %v", err)
+		}
+
+		// Creating the child moved the parent on from version 1
+		if err := s.tasks.DeleteTask(parent.ID, MutationOptions{IfMatch: []int64{1}}); err != ErrVersionMismatch {
+			t.Fatalf("DeleteTask with a stale version: err = %v, want %v", err, ErrVersionMismatch)
+		}
+		if err := s.tasks.DeleteTask(parent.ID, MutationOptions{}); err != nil {
+			t.Fatalf("DeleteTask: %v", err)
+		}
+
+		for _, task := range []*model.TaskResponse{parent, child, grandchild} {
+			if got, err := s.tasks.GetTaskByID(task.ID); err != nil || got != nil {
+				t.Errorf("%s after delete = %v, %v, want nil", task.Title, got, err)
+			}
+		}
+		got, err := s.tasks.GetTaskByID(waiting.ID)
+		if err != nil {
+			t.Fatalf("GetTaskByID: %v", err)
+		}
+		if len(got.BlockedBy) != 0 {
+			t.Errorf("waiting task still blocked by %v", got.BlockedBy)
+		}
+		if err := s.tasks.DeleteTask(parent.ID, MutationOptions{}); err != store.ErrNotFound {
+			t.Errorf("deleting again: err = %v, want %v", err, store.ErrNotFound)
+		}
+	})
+}
+
+func TestDeleteProject(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     func(target string) *model.DeleteProjectQuery
+		wantErr   error
+		wantTasks int // Left in the target project
+	}{
+		{
+			name:    "restrict",
+			query:   func(target string) *model.DeleteProjectQuery { return &model.DeleteProjectQuery{} },
+			wantErr: ErrProjectHasTasks,
+		},
+		{
+			name: "cascade",
+			query: func(target string) *model.DeleteProjectQuery {
+				return &model.DeleteProjectQuery{OnDelete: model.ProjectDeleteCascade}
+			},
+		},
+		{
+			name: "move",
+			query: func(target string) *model.DeleteProjectQuery {
+				return &model.DeleteProjectQuery{OnDelete: model.ProjectDeleteMove, TargetProjectID: target}
+			},
+			wantTasks: 2,
+		},
+		{
+			name: "move without target",
+			query: func(target string) *model.DeleteProjectQuery {
+				return &model.DeleteProjectQuery{OnDelete: model.ProjectDeleteMove}
+			},
+			wantErr: ErrInvalidProjectDelete,
+		},
+		{
+			name: "move to missing project",
+			query: func(target string) *model.DeleteProjectQuery {
+				return &model.DeleteProjectQuery{OnDelete: model.ProjectDeleteMove, TargetProjectID: "missing"}
+			},
+			wantErr: ErrProjectNotFound,
+		},
+	}
+
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := newServices(t)
+				project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+				target := s.project(t, &model.CreateProjectRequest{Name: "target", Directory: "/tmp"})
+				parent := s.task(t, &model.CreateTaskRequest{Title: "parent", ProjectID: project.ID})
+				s.task(t, &model.CreateTaskRequest{Title: "child", ParentID: &parent.ID})
+
+				err := s.projects.DeleteProject(project.ID, tt.query(target.ID), MutationOptions{})
+				if tt.wantErr != nil {
+					if !errors.Is(err, tt.wantErr) {
+						t.Fatalf("err = %v, want %v", err, tt.wantErr)
+					}
+					return
+				}
+				if err != nil {
+					t.Fatalf("DeleteProject: %v", err)
+				}
+
+				if _, err := s.projects.GetProject(project.ID); err != store.ErrNotFound {
+					t.Errorf("GetProject after delete: err = %v, want %v", err, store.ErrNotFound)
+				}
+				list, err := s.tasks.GetTasks(&model.TaskListQuery{})
+				if err != nil {
+					t.Fatalf("GetTasks: %v", err)
+				}
+				if int(list.Total) != tt.wantTasks {
+					t.Errorf("%d tasks left, want %d", list.Total, tt.wantTasks)
+				}
+				for _, task := range list.Tasks {
+					if task.ProjectID != target.ID {
+						t.Errorf("task %s is in project %s, want %s", task.Title, task.ProjectID, target.ID)
+					}
+				}
+			})
+		}
+	})
+}
+
+func TestDeleteAgent(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		agent, err := s.agents.CreateAgent(&model.CreateAgentRequest{Name: "agent", Type: "claude"}, MutationOptions{})
+		if err != nil {
+			t.Fatalf("CreateAgent: %v", err)
+		}
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp", AgentID: &agent.ID})
+		task := s.task(t, &model.CreateTaskRequest{Title: "task", ProjectID: project.ID, AgentID: &agent.ID})
+		trashed := s.task(t, &model.CreateTaskRequest{Title: "trashed", ProjectID: project.ID, AgentID: &agent.ID})
+		if err := s.tasks.DeleteTask(trashed.ID, MutationOptions{}); err != nil {
+			t.Fatalf("DeleteTask: %v", err)
+		}
+
+		if err := s.agents.DeleteAgent(agent.ID, MutationOptions{}); err != nil {
+			t.Fatalf("DeleteAgent: %v", err)
+		}
+
+		if _, err := s.agents.GetAgent(agent.ID); err != store.ErrNotFound {
+			t.Errorf("GetAgent after delete: err = %v, want %v", err, store.ErrNotFound)
+		}
+		gotTask, err := s.tasks.GetTaskByID(task.ID)
+		if err != nil {
+			t.Fatalf("GetTaskByID: %v", err)
+		}
+		if gotTask.AgentID != nil || gotTask.Version != task.Version+1 {
+			t.Errorf("task agent = %v, version %d, want unassigned at version %d", gotTask.AgentID, gotTask.Version, task.Version+1)
+		}
+		gotProject, err := s.projects.GetProject(project.ID)
+		if err != nil {
+			t.Fatalf("GetProject: %v", err)
+		}
+		if gotProject.AgentID != nil || gotProject.Version != project.Version+1 {
+			t.Errorf("project agent = %v, version %d, want unassigned at version %d", gotProject.AgentID, gotProject.Version, project.Version+1)
+		}
+	})
+}
+
+func TestPatchAgentMovesVersions(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		agent, err := s.agents.CreateAgent(&model.CreateAgentRequest{Name: "agent", Type: "claude"}, MutationOptions{})
+		if err != nil {
+			t.Fatalf("CreateAgent: %v", err)
+		}
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp", AgentID: &agent.ID})
+		task := s.task(t, &model.CreateTaskRequest{Title: "task", ProjectID: project.ID, AgentID: &agent.ID})
+		other := s.task(t, &model.CreateTaskRequest{Title: "other", ProjectID: project.ID})
+
+		if _, err := s.agents.PatchAgent(agent.ID, &model.PatchAgentRequest{Name: model.PatchValue("renamed")}, MutationOptions{}); err != nil {
+			t.Fatalf("PatchAgent: %v", err)
+		}
+
+		// Responses showing the agent changed, the others did not
+		for _, want := range []*model.TaskResponse{task, other} {
+			got, err := s.tasks.GetTaskByID(want.ID)
+			if err != nil {
+				t.Fatalf("GetTaskByID: %v", err)
+			}
+			version := want.Version
+			if want.AgentID != nil {
+				version++
+			}
+			if got.Version != version {
+				t.Errorf("%s version = %d, want %d", want.Title, got.Version, version)
+			}
+		}
+		gotProject, err := s.projects.GetProject(project.ID)
+		if err != nil {
+			t.Fatalf("GetProject: %v", err)
+		}
+		if gotProject.Version != project.Version+1 || gotProject.Agent == nil || gotProject.Agent.Name != "renamed" {
+			t.Errorf("project at version %d with agent %+v, want version %d with the renamed agent", gotProject.Version, gotProject.Agent, project.Version+1)
+		}
+	})
+}
+
+func TestGetTasksPages(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		// Due dates repeat and are missing, so pages break inside ties
+		day := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+		later := day.AddDate(0, 0, 1)
+		tasks := []struct {
+			priority string
+			due      *time.Time
+		}{
+			{model.PriorityLow, &later},
+			{model.PriorityUrgent, nil},
+			{model.PriorityMedium, &day},
+			{model.PriorityHigh, nil},
+			{model.PriorityUrgent, &day},
+		}
+		for i, task := range tasks {
+			s.task(t, &model.CreateTaskRequest{Title: "abcde"[i : i+1], Priority: task.priority, DueDate: task.due, ProjectID: project.ID})
+		}
+
+		for _, order := range []string{"created_at", "-priority", "title", "due_date", "-due_date", "-updated_at", "position"} {
+			t.Run(order, func(t *testing.T) {
+				all, err := s.tasks.GetTasks(&model.TaskListQuery{Sort: order})
+				if err != nil {
+					t.Fatalf("GetTasks: %v", err)
+				}
+
+				var paged []string
+				query := &model.TaskListQuery{Sort: order, Limit: 2}
+				for {
+					page, err := s.tasks.GetTasks(query)
+					if err != nil {
+						t.Fatalf("GetTasks: %v", err)
+					}
+					if page.Total != int64(len(tasks)) {
+						t.Errorf("total = %d, want %d", page.Total, len(tasks))
+					}
+					for _, task := range page.Tasks {
+						paged = append(paged, task.Title)
+					}
+					if page.NextCursor == "" {
+						break
+					}
+					query.Cursor = page.NextCursor
+				}
+
+				var want []string
+				for _, task := range all.Tasks {
+					want = append(want, task.Title)
+				}
+				if !reflect.DeepEqual(paged, want) {
+					t.Errorf("pages = %v, want %v", paged, want)
+				}
+			})
+		}
+	})
+}
+
+func TestTagChanges(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(s *testServices, ids map[string]string) error
+		want   map[string][]string // Tags by task title
+	}{
+		{
+			name: "rename",
+			change: func(s *testServices, ids map[string]string) error {
+				_, err := s.tags.UpdateTag(ids["defect"], &model.UpdateTagRequest{Name: "fault"}, MutationOptions{})
+				return err
+			},
+			want: map[string][]string{"both": {"bug", "fault"}, "one": {"fault"}},
+		},
+		{
+			name: "merge",
+			change: func(s *testServices, ids map[string]string) error {
+				_, err := s.tags.MergeTag(ids["defect"], &model.MergeTagRequest{TargetID: ids["bug"]}, MutationOptions{})
+				return err
+			},
+			want: map[string][]string{"both": {"bug"}, "one": {"bug"}},
+		},
+		{
+			name: "recolor",
+			change: func(s *testServices, ids map[string]string) error {
+				color := "#ff0000"
+				_, err := s.tags.UpdateTag(ids["defect"], &model.UpdateTagRequest{Color: &color}, MutationOptions{})
+				return err
+			},
+			want: map[string][]string{"both": {"bug", "defect"}, "one": {"defect"}},
+		},
+		{
+			name: "delete",
+			change: func(s *testServices, ids map[string]string) error {
+				return s.tags.DeleteTag(ids["defect"], MutationOptions{})
+			},
+			want: map[string][]string{"both": {"bug"}, "one": nil},
+		},
+	}
+
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		for _, tt := range tests {
+			t.Run(tt.name, func(t *testing.T) {
+				s := newServices(t)
+				project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+				tasks := []*model.TaskResponse{
+					s.task(t, &model.CreateTaskRequest{Title: "both", ProjectID: project.ID, Tags: []string{"bug", "defect"}}),
+					s.task(t, &model.CreateTaskRequest{Title: "one", ProjectID: project.ID, Tags: []string{"defect"}}),
+				}
+
+				tags, err := s.tags.GetTags()
+				if err != nil {
+					t.Fatalf("GetTags: %v", err)
+				}
+				ids := map[string]string{}
+				for _, tag := range tags.Tags {
+					ids[tag.Name] = tag.ID
+				}
+				if _, err := s.tags.MergeTag(ids["bug"], &model.MergeTagRequest{TargetID: ids["bug"]}, MutationOptions{}); err != ErrTagMergeSelf {
+					t.Errorf("merging into itself: err = %v, want %v", err, ErrTagMergeSelf)
+				}
+
+				if err := tt.change(s, ids); err != nil {
+					t.Fatalf("changing tag: %v", err)
+				}
+
+				for _, task := range tasks {
+					got, err := s.tasks.GetTaskByID(task.ID)
+					if err != nil {
+						t.Fatalf("GetTaskByID: %v", err)
+					}
+					sort.Strings(got.Tags)
+					if len(got.Tags) != 0 || len(tt.want[task.Title]) != 0 {
+						if !reflect.DeepEqual(got.Tags, tt.want[task.Title]) {
+							t.Errorf("%s tags = %v, want %v", task.Title, got.Tags, tt.want[task.Title])
+						}
+					}
+					// The change counts as an update of the task
+					if got.Version != task.Version+1 {
+						t.Errorf("%s version = %d, want %d", task.Title, got.Version, task.Version+1)
+					}
+				}
+			})
+		}
+	})
+}
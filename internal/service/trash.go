@@ -0,0 +1,500 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/config"
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+// trashPurgeInterval is how often trashed items past their retention are
+// looked for.
+const trashPurgeInterval = time.Hour
+
+var ErrRestoreBlocked = errors.New("cannot restore")
+
+// TrashService lists, restores and purges trashed tasks and projects. A
+// delete trashes an item together with its subtasks or tasks; restoring the
+// item brings all of them back. Items missing from the trash are reported
+// as store.ErrNotFound.
+type TrashService struct {
+	stores         store.Store
+	config         *config.TrashConfig
+	taskService    *TaskService
+	projectService *ProjectService
+	events         *events.Bus
+	logger         *zap.Logger
+}
+
+func NewTrashService(stores store.Store, cfg *config.TrashConfig, taskService *TaskService, projectService *ProjectService, bus *events.Bus, logger *zap.Logger) *TrashService {
+	return &TrashService{
+		stores:         stores,
+		config:         cfg,
+		taskService:    taskService,
+		projectService: projectService,
+		events:         bus,
+		logger:         logger,
+	}
+}
+
+// GetTrash lists the trashed tasks and projects, most recently deleted
+// first. Tasks trashed along with a parent or project are counted with it.
+func (s *TrashService) GetTrash(query *model.TrashQuery) (*model.TrashListResponse, error) {
+	tasks, projects, err := loadTrash(s.stores)
+	if err != nil {
+		s.logger.Error("Failed to get trash", zap.Error(err))
+		return nil, err
+	}
+
+	roots := trashRoots(tasks, projects)
+	counts := map[string]int64{}
+	for _, task := range tasks {
+		if root := roots[task.ID]; root != task.ID {
+			counts[root]++
+		}
+	}
+
+	items := []model.TrashItem{}
+	if query.Type != model.EntityTask {
+		for _, project := range projects {
+			items = append(items, model.TrashItem{
+				Type:      model.EntityProject,
+				ID:        project.ID,
+				Title:     project.Name,
+				TaskCount: counts[project.ID],
+				DeletedAt: project.DeletedAt.Time,
+				PurgeAt:   s.purgeAt(project.DeletedAt.Time),
+			})
+		}
+	}
+	if query.Type != model.EntityProject {
+		for _, task := range tasks {
+			if roots[task.ID] != task.ID {
+				continue
+			}
+			items = append(items, model.TrashItem{
+				Type:      model.EntityTask,
+				ID:        task.ID,
+				Title:     task.Title,
+				ProjectID: task.ProjectID,
+				TaskCount: counts[task.ID],
+				DeletedAt: task.DeletedAt.Time,
+				PurgeAt:   s.purgeAt(task.DeletedAt.Time),
+			})
+		}
+	}
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+
+	return &model.TrashListResponse{
+		Items: items,
+		Total: int64(len(items)),
+	}, nil
+}
+
+// Restore brings a trashed task or project back together with everything
+// trashed by the same delete. A task whose parent or project is still in
+// the trash cannot be restored on its own.
+func (s *TrashService) Restore(id string, opts MutationOptions) (*model.TrashRestoreResponse, error) {
+	task, err := s.stores.Trash().Task(id)
+	if err == nil {
+		return s.restoreTask(task, opts)
+	}
+	if err != store.ErrNotFound {
+		s.logger.Error("Failed to get trashed task", zap.Error(err), zap.String("id", id))
+		return nil, err
+	}
+
+	project, err := s.stores.Trash().Project(id)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to get trashed project", zap.Error(err), zap.String("id", id))
+		}
+		return nil, err
+	}
+	return s.restoreProject(project, opts)
+}
+
+func (s *TrashService) restoreTask(task *database.Task, opts MutationOptions) (*model.TrashRestoreResponse, error) {
+	var restored, spread []string
+	if err := s.stores.Transaction(func(st store.Store) error {
+		if task.ParentID != nil {
+			if trashed, err := inTrash(st.Trash().Task(*task.ParentID)); err != nil || trashed {
+				if err != nil {
+					return err
+				}
+				return fmt.Errorf("%w: parent task %s is in the trash, restore it first", ErrRestoreBlocked, *task.ParentID)
+			}
+		}
+		if task.ProjectID != "" {
+			if trashed, err := inTrash(st.Trash().Project(task.ProjectID)); err != nil || trashed {
+				if err != nil {
+					return err
+				}
+				return fmt.Errorf("%w: project %s is in the trash, restore it first", ErrRestoreBlocked, task.ProjectID)
+			}
+		}
+
+		var err error
+		restored, spread, err = s.restoreTasks(st, []database.Task{*task}, opts)
+		return err
+	}); err != nil {
+		if !errors.Is(err, ErrRestoreBlocked) {
+			s.logger.Error("Failed to restore task", zap.Error(err), zap.String("id", task.ID))
+		}
+		return nil, err
+	}
+
+	s.logger.Info("Task restored", zap.String("id", task.ID), zap.Int("tasks", len(restored)))
+	s.publishRestoredTasks(restored)
+	s.taskService.publishTasksUpdated(spread)
+
+	response, err := s.taskService.GetTaskByID(task.ID)
+	if err != nil {
+		return nil, err
+	}
+	return &model.TrashRestoreResponse{
+		Type:          model.EntityTask,
+		Task:          response,
+		RestoredTasks: len(restored),
+	}, nil
+}
+
+func (s *TrashService) restoreProject(project *database.Project, opts MutationOptions) (*model.TrashRestoreResponse, error) {
+	var restored, spread []string
+	if err := s.stores.Transaction(func(st store.Store) error {
+		if err := st.Trash().RestoreProject(project.ID, time.Now()); err != nil {
+			return err
+		}
+		if err := storeActivity(st, model.EntityProject, project.ID, project.ID, model.ActivityRestored, opts.Actor,
+			nil, nil); err != nil {
+			return err
+		}
+
+		// Tasks trashed along with the project come back with it
+		trashed, err := st.Trash().Tasks()
+		if err != nil {
+			return err
+		}
+		var tasks []database.Task
+		batch := map[string]bool{}
+		for _, task := range trashed {
+			if task.ProjectID == project.ID {
+				tasks = append(tasks, task)
+			}
+			if task.ProjectID == project.ID && task.TrashBatch == project.TrashBatch {
+				batch[task.ID] = true
+			}
+		}
+		var roots []database.Task
+		for _, task := range tasks {
+			if batch[task.ID] && (task.ParentID == nil || !batch[*task.ParentID]) {
+				roots = append(roots, task)
+			}
+		}
+
+		restored, spread, err = s.restoreTasks(st, roots, opts)
+		return err
+	}); err != nil {
+		s.logger.Error("Failed to restore project", zap.Error(err), zap.String("id", project.ID))
+		return nil, err
+	}
+
+	s.logger.Info("Project restored", zap.String("id", project.ID), zap.Int("tasks", len(restored)))
+	response, err := s.projectService.GetProject(project.ID)
+	if err != nil {
+		return nil, err
+	}
+	s.events.Publish(events.Event{
+		Type:      events.ProjectRestored,
+		EntityID:  response.ID,
+		ProjectID: response.ID,
+		Data:      response,
+	})
+	s.publishRestoredTasks(restored)
+	s.taskService.publishTasksUpdated(spread)
+
+	return &model.TrashRestoreResponse{
+		Type:          model.EntityProject,
+		Project:       response,
+		RestoredTasks: len(restored),
+	}, nil
+}
+
+// restoreTasks brings trashed tasks back in st along with the subtasks
+// trashed by the same delete, parents first. Restored tasks go to the end
+// of their column; a status the project's workflow no longer has falls
+// back to its initial status. It returns the IDs of the restored tasks and
+// of the tasks spread out to make room for them.
+func (s *TrashService) restoreTasks(st store.Store, tasks []database.Task, opts MutationOptions) ([]string, []string, error) {
+	trashed, err := st.Trash().Tasks()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	batch := append([]database.Task(nil), tasks...)
+	for frontier := tasks; len(frontier) > 0; {
+		batches := make(map[string]string, len(frontier))
+		for i := range frontier {
+			batches[frontier[i].ID] = frontier[i].TrashBatch
+		}
+
+		frontier = nil
+		for _, child := range trashed {
+			if child.ParentID == nil {
+				continue
+			}
+			if batch, ok := batches[*child.ParentID]; ok && child.TrashBatch == batch {
+				frontier = append(frontier, child)
+			}
+		}
+		batch = append(batch, frontier...)
+	}
+
+	ids := make([]string, len(batch))
+	for i := range batch {
+		ids[i] = batch[i].ID
+	}
+	tags, err := st.Tasks().TagNames(ids)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var spread []string
+	now := time.Now()
+	for i := range batch {
+		task := &batch[i]
+		before := taskSnapshot(task, tags[task.ID])
+
+		if err := s.taskService.workflows.ValidateStatus(task.ProjectID, task.Status); err != nil {
+			if task.Status, err = s.taskService.workflows.InitialStatus(task.ProjectID); err != nil {
+				return nil, nil, err
+			}
+		}
+		var moved []string
+		if task.Position, moved, err = lastPosition(st, task.ProjectID, task.Status); err != nil {
+			return nil, nil, err
+		}
+		spread = append(spread, moved...)
+
+		task.UpdatedAt = now
+		if err := st.Trash().RestoreTask(task); err != nil {
+			return nil, nil, err
+		}
+		if err := storeActivity(st, model.EntityTask, task.ID, task.ProjectID, model.ActivityRestored, opts.Actor,
+			before, taskSnapshot(task, tags[task.ID])); err != nil {
+			return nil, nil, err
+		}
+	}
+	return ids, spread, nil
+}
+
+func (s *TrashService) publishRestoredTasks(ids []string) {
+	for _, id := range ids {
+		task, err := s.taskService.GetTaskByID(id)
+		if err != nil || task == nil {
+			s.logger.Warn("Failed to reload restored task", zap.Error(err), zap.String("id", id))
+			continue
+		}
+		s.events.Publish(events.Event{
+			Type:      events.TaskRestored,
+			EntityID:  task.ID,
+			ProjectID: task.ProjectID,
+			Data:      task,
+		})
+
+		// Tasks waiting for the restored task are blocked by it again
+		s.taskService.publishDependentsUpdated(id)
+	}
+}
+
+// StartPurging purges trashed items past their retention now and then
+// periodically in the background. Nothing is purged when the retention is
+// zero.
+func (s *TrashService) StartPurging() {
+	if s.config.RetentionDays <= 0 {
+		s.logger.Info("Trash retention disabled, trashed items are kept")
+		return
+	}
+
+	go func() {
+		ticker := time.NewTicker(trashPurgeInterval)
+		defer ticker.Stop()
+		for {
+			if _, err := s.PurgeExpired(); err != nil {
+				s.logger.Error("Failed to purge trash", zap.Error(err))
+			}
+			<-ticker.C
+		}
+	}()
+}
+
+// PurgeExpired removes tasks and projects from the trash for good once they
+// have been there longer than the retention. It returns how many tasks and
+// projects were purged.
+func (s *TrashService) PurgeExpired() (int, error) {
+	if s.config.RetentionDays <= 0 {
+		return 0, nil
+	}
+	cutoff := time.Now().AddDate(0, 0, -s.config.RetentionDays)
+
+	tasks, projects, err := loadTrash(s.stores)
+	if err != nil {
+		return 0, err
+	}
+
+	var taskIDs, projectIDs []string
+	for _, task := range tasks {
+		if task.DeletedAt.Time.Before(cutoff) {
+			taskIDs = append(taskIDs, task.ID)
+		}
+	}
+	for _, project := range projects {
+		if project.DeletedAt.Time.Before(cutoff) {
+			projectIDs = append(projectIDs, project.ID)
+		}
+	}
+	if len(taskIDs) == 0 && len(projectIDs) == 0 {
+		return 0, nil
+	}
+	return s.purge(taskIDs, projectIDs)
+}
+
+// purge deletes trashed tasks and projects with everything attached to
+// them. The subtasks of purged tasks and the tasks of purged projects go
+// along.
+func (s *TrashService) purge(taskIDs, projectIDs []string) (int, error) {
+	trashed, err := s.stores.Trash().Tasks()
+	if err != nil {
+		return 0, err
+	}
+
+	pickedTasks, pickedProjects := map[string]bool{}, map[string]bool{}
+	for _, id := range taskIDs {
+		pickedTasks[id] = true
+	}
+	for _, id := range projectIDs {
+		pickedProjects[id] = true
+	}
+	var tasks []database.Task
+	seen := map[string]bool{}
+	for _, task := range trashed {
+		if pickedTasks[task.ID] || pickedProjects[task.ProjectID] {
+			seen[task.ID] = true
+			tasks = append(tasks, task)
+		}
+	}
+	for frontier := tasks; len(frontier) > 0; {
+		parents := map[string]bool{}
+		for _, task := range frontier {
+			parents[task.ID] = true
+		}
+		frontier = nil
+		for _, child := range trashed {
+			if child.ParentID != nil && parents[*child.ParentID] && !seen[child.ID] {
+				seen[child.ID] = true
+				frontier = append(frontier, child)
+			}
+		}
+		tasks = append(tasks, frontier...)
+	}
+
+	// Worktrees go first, removing them needs the project's directory
+	for i := range tasks {
+		if err := s.taskService.worktrees.RemoveWorktree(&tasks[i], true); err != nil {
+			s.logger.Warn("Failed to clean up task worktree", zap.Error(err), zap.String("id", tasks[i].ID))
+		}
+	}
+
+	ids := make([]string, len(tasks))
+	for i := range tasks {
+		ids[i] = tasks[i].ID
+	}
+	if err := s.stores.Transaction(func(st store.Store) error {
+		for i := range tasks {
+			if err := storeActivity(st, model.EntityTask, tasks[i].ID, tasks[i].ProjectID, model.ActivityPurged, ActorSystem,
+				nil, nil); err != nil {
+				return err
+			}
+		}
+		for _, id := range projectIDs {
+			if err := storeActivity(st, model.EntityProject, id, id, model.ActivityPurged, ActorSystem, nil, nil); err != nil {
+				return err
+			}
+		}
+		return st.Trash().Purge(ids, projectIDs)
+	}); err != nil {
+		return 0, err
+	}
+
+	s.logger.Info("Purged trash", zap.Int("tasks", len(ids)), zap.Int("projects", len(projectIDs)))
+	return len(ids) + len(projectIDs), nil
+}
+
+func (s *TrashService) purgeAt(deletedAt time.Time) *time.Time {
+	if s.config.RetentionDays <= 0 {
+		return nil
+	}
+	purgeAt := deletedAt.AddDate(0, 0, s.config.RetentionDays)
+	return &purgeAt
+}
+
+func loadTrash(st store.Store) ([]database.Task, []database.Project, error) {
+	tasks, err := st.Trash().Tasks()
+	if err != nil {
+		return nil, nil, err
+	}
+	projects, err := st.Trash().Projects()
+	if err != nil {
+		return nil, nil, err
+	}
+	return tasks, projects, nil
+}
+
+// inTrash reports whether a trash lookup found the item. Items that are not
+// in the trash are live.
+func inTrash[T any](item *T, err error) (bool, error) {
+	if err == store.ErrNotFound {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// trashRoots maps each trashed task to the item it was trashed with: the
+// parent or project trashed by the same delete, otherwise the task itself.
+func trashRoots(tasks []database.Task, projects []database.Project) map[string]string {
+	byID := make(map[string]*database.Task, len(tasks))
+	for i := range tasks {
+		byID[tasks[i].ID] = &tasks[i]
+	}
+	projectBatches := make(map[string]string, len(projects))
+	for _, project := range projects {
+		projectBatches[project.ID] = project.TrashBatch
+	}
+
+	roots := make(map[string]string, len(tasks))
+	for i := range tasks {
+		root := &tasks[i]
+		for root.ParentID != nil {
+			parent, ok := byID[*root.ParentID]
+			if !ok || parent.TrashBatch != root.TrashBatch {
+				break
+			}
+			root = parent
+		}
+
+		if batch, ok := projectBatches[root.ProjectID]; ok && batch == root.TrashBatch {
+			roots[tasks[i].ID] = root.ProjectID
+		} else {
+			roots[tasks[i].ID] = root.ID
+		}
+	}
+	return roots
+}
@@ -0,0 +1,114 @@
+package service
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+func TestRestore(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		parent := s.task(t, &model.CreateTaskRequest{Title: "parent", ProjectID: project.ID})
+		child := s.task(t, &model.CreateTaskRequest{Title: "child", ParentID: &parent.ID})
+		other := s.task(t, &model.CreateTaskRequest{Title: "other", ProjectID: project.ID})
+		for _, task := range []*model.TaskResponse{parent, other} {
+			if err := s.tasks.DeleteTask(task.ID, MutationOptions{}); err != nil {
+				t.Fatalf("DeleteTask %s: %v", task.Title, err)
+			}
+		}
+
+		if _, err := s.trash.Restore(child.ID, MutationOptions{}); !errors.Is(err, ErrRestoreBlocked) {
+			t.Errorf("restoring a subtask of a trashed task: err = %v, want %v", err, ErrRestoreBlocked)
+		}
+		restored, err := s.trash.Restore(parent.ID, MutationOptions{})
+		if err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if restored.RestoredTasks != 2 {
+			t.Errorf("restored %d tasks, want 2", restored.RestoredTasks)
+		}
+		for _, task := range []*model.TaskResponse{parent, child} {
+			if got, err := s.tasks.GetTaskByID(task.ID); err != nil || got == nil {
+				t.Errorf("%s after restore = %v, %v", task.Title, got, err)
+			}
+		}
+		if got, err := s.tasks.GetTaskByID(other.ID); err != nil || got != nil {
+			t.Errorf("other after restore = %v, %v, want it left in the trash", got, err)
+		}
+		if _, err := s.trash.Restore(parent.ID, MutationOptions{}); err != store.ErrNotFound {
+			t.Errorf("restoring again: err = %v, want %v", err, store.ErrNotFound)
+		}
+	})
+}
+
+func TestRestoreKeepsBatchesApart(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		parent := s.task(t, &model.CreateTaskRequest{Title: "parent", ProjectID: project.ID})
+		child := s.task(t, &model.CreateTaskRequest{Title: "child", ParentID: &parent.ID})
+
+		// Two deletes the database recorded at the same time, as one that
+		// rounds deletion times may
+		deletedAt := time.Now().Truncate(time.Second)
+		if err := s.tasks.stores.Tasks().Trash([]string{child.ID}, deletedAt, "first"); err != nil {
+			t.Fatalf("Trash: %v", err)
+		}
+		if err := s.tasks.stores.Tasks().Trash([]string{parent.ID}, deletedAt, "second"); err != nil {
+			t.Fatalf("Trash: %v", err)
+		}
+
+		trash, err := s.trash.GetTrash(&model.TrashQuery{})
+		if err != nil {
+			t.Fatalf("GetTrash: %v", err)
+		}
+		if len(trash.Items) != 2 {
+			t.Errorf("trash has %d items, want 2", len(trash.Items))
+		}
+		restored, err := s.trash.Restore(parent.ID, MutationOptions{})
+		if err != nil {
+			t.Fatalf("Restore: %v", err)
+		}
+		if restored.RestoredTasks != 1 {
+			t.Errorf("restored %d tasks, want only the parent", restored.RestoredTasks)
+		}
+		if got, err := s.tasks.GetTaskByID(child.ID); err != nil || got != nil {
+			t.Errorf("child after restoring its parent = %v, %v, want it left in the trash", got, err)
+		}
+	})
+}
+
+func TestPurge(t *testing.T) {
+	forEachStore(t, func(t *testing.T, newServices func(t *testing.T) *testServices) {
+		s := newServices(t)
+		project := s.project(t, &model.CreateProjectRequest{Name: "p", Directory: "/tmp"})
+		parent := s.task(t, &model.CreateTaskRequest{Title: "parent", ProjectID: project.ID})
+		s.task(t, &model.CreateTaskRequest{Title: "child", ParentID: &parent.ID})
+		other := s.task(t, &model.CreateTaskRequest{Title: "other", ProjectID: project.ID})
+		for _, task := range []*model.TaskResponse{parent, other} {
+			if err := s.tasks.DeleteTask(task.ID, MutationOptions{}); err != nil {
+				t.Fatalf("DeleteTask %s: %v", task.Title, err)
+			}
+		}
+
+		purged, err := s.trash.purge([]string{parent.ID}, nil)
+		if err != nil {
+			t.Fatalf("purge: %v", err)
+		}
+		if purged != 2 {
+			t.Errorf("purged %d items, want 2", purged)
+		}
+		trash, err := s.trash.GetTrash(&model.TrashQuery{})
+		if err != nil {
+			t.Fatalf("GetTrash: %v", err)
+		}
+		if len(trash.Items) != 1 || trash.Items[0].ID != other.ID {
+			t.Errorf("trash after purge = %+v, want only %s", trash.Items, other.ID)
+		}
+	})
+}
@@ -0,0 +1,23 @@
+package service
+
+import "github.com/amoylab/solo-api/internal/store"
+
+// Tasks, projects and agents carry a version that every change increments.
+// Clients send the version they last saw in If-Match to make sure they do
+// not overwrite a change they have not seen.
+
+var ErrVersionMismatch = store.ErrVersionMismatch
+
+// checkVersion fails when the caller named the versions a change may apply
+// to and the current one is not among them.
+func checkVersion(current int64, opts MutationOptions) error {
+	if opts.IfMatch == nil {
+		return nil
+	}
+	for _, version := range opts.IfMatch {
+		if version == current {
+			return nil
+		}
+	}
+	return ErrVersionMismatch
+}
@@ -0,0 +1,421 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/events"
+	"github.com/amoylab/solo-api/internal/model"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+var statusKeyPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]*$`)
+
+// StatusError reports a task status the workflow does not accept: either a
+// status it does not declare or a move it does not allow.
+type StatusError struct {
+	Status  string
+	From    string   // Current status when the move is not allowed, empty for unknown statuses
+	Allowed []string // Statuses the task may be set to instead
+}
+
+func (e *StatusError) Error() string {
+	if e.From != "" {
+		return fmt.Sprintf("cannot move task from %q to %q; allowed: %s", e.From, e.Status, strings.Join(e.Allowed, ", "))
+	}
+	return fmt.Sprintf("unknown status %q; valid statuses: %s", e.Status, strings.Join(e.Allowed, ", "))
+}
+
+// IsTransition reports whether the error is about an illegal move rather
+// than an unknown status.
+func (e *StatusError) IsTransition() bool {
+	return e.From != ""
+}
+
+// WorkflowError reports an invalid workflow definition.
+type WorkflowError struct {
+	Message string
+	InUse   map[string]int64 // Statuses dropped by the update that tasks still use
+}
+
+func (e *WorkflowError) Error() string {
+	return e.Message
+}
+
+// DefaultWorkflow is used by projects that do not declare their own.
+func DefaultWorkflow() model.Workflow {
+	return model.Workflow{
+		Statuses: []model.WorkflowStatus{
+			{Key: model.TaskStatusTodo, Name: "To Do"},
+			{Key: model.TaskStatusInProgress, Name: "In Progress"},
+			{Key: model.TaskStatusInReview, Name: "In Review"},
+			{Key: model.TaskStatusDone, Name: "Done", Final: true},
+			{Key: model.TaskStatusCancelled, Name: "Cancelled", Final: true},
+		},
+		Transitions: map[string][]string{
+			model.TaskStatusTodo:       {model.TaskStatusInProgress, model.TaskStatusDone, model.TaskStatusCancelled},
+			model.TaskStatusInProgress: {model.TaskStatusTodo, model.TaskStatusInReview, model.TaskStatusDone, model.TaskStatusCancelled},
+			model.TaskStatusInReview:   {model.TaskStatusInProgress, model.TaskStatusDone, model.TaskStatusCancelled},
+			model.TaskStatusDone:       {model.TaskStatusTodo, model.TaskStatusInProgress},
+			model.TaskStatusCancelled:  {model.TaskStatusTodo},
+		},
+	}
+}
+
+// WorkflowService resolves the status workflow of a project and validates
+// task status changes against it.
+type WorkflowService struct {
+	stores store.Store
+	events *events.Bus
+	logger *zap.Logger
+}
+
+func NewWorkflowService(stores store.Store, bus *events.Bus, logger *zap.Logger) *WorkflowService {
+	return &WorkflowService{
+		stores: stores,
+		events: bus,
+		logger: logger,
+	}
+}
+
+// GetWorkflow returns the workflow of a project, falling back to the default.
+func (s *WorkflowService) GetWorkflow(projectID string) (*model.WorkflowResponse, error) {
+	project, err := s.stores.Projects().Get(projectID)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to get project workflow", zap.Error(err), zap.String("project_id", projectID))
+		}
+		return nil, err
+	}
+
+	workflow, custom, err := decodeWorkflow(project.Workflow)
+	if err != nil {
+		s.logger.Error("Failed to decode project workflow", zap.Error(err), zap.String("project_id", projectID))
+		return nil, err
+	}
+
+	return &model.WorkflowResponse{
+		ProjectID: projectID,
+		Custom:    custom,
+		Workflow:  workflow,
+	}, nil
+}
+
+// UpdateWorkflow replaces the workflow of a project. Statuses still used by
+// tasks of the project cannot be dropped.
+func (s *WorkflowService) UpdateWorkflow(projectID string, req *model.UpdateWorkflowRequest, opts MutationOptions) (*model.WorkflowResponse, error) {
+	s.logger.Info("Updating project workflow", zap.String("project_id", projectID))
+
+	workflow, err := normalizeWorkflow(req)
+	if err != nil {
+		return nil, err
+	}
+
+	encoded, err := json.Marshal(workflow)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.saveWorkflow(projectID, workflow, string(encoded), opts)
+}
+
+// ResetWorkflow makes a project use the default workflow again.
+func (s *WorkflowService) ResetWorkflow(projectID string, opts MutationOptions) (*model.WorkflowResponse, error) {
+	s.logger.Info("Resetting project workflow", zap.String("project_id", projectID))
+
+	return s.saveWorkflow(projectID, DefaultWorkflow(), "", opts)
+}
+
+// saveWorkflow stores the workflow of a project, moving the project's
+// version on and recording the change in its activity.
+func (s *WorkflowService) saveWorkflow(projectID string, workflow model.Workflow, encoded string, opts MutationOptions) (*model.WorkflowResponse, error) {
+	project, err := s.stores.Projects().Get(projectID)
+	if err != nil {
+		if err != store.ErrNotFound {
+			s.logger.Error("Failed to find project", zap.Error(err), zap.String("project_id", projectID))
+		}
+		return nil, err
+	}
+
+	previous, _, err := decodeWorkflow(project.Workflow)
+	if err != nil {
+		s.logger.Error("Failed to decode project workflow", zap.Error(err), zap.String("project_id", projectID))
+		return nil, err
+	}
+
+	project.Workflow = encoded
+	project.UpdatedAt = time.Now()
+	if err := s.stores.Transaction(func(st store.Store) error {
+		// Checked along with the write so no task moves into a dropped
+		// status in between
+		if err := checkStatusesInUse(st, projectID, &workflow); err != nil {
+			return err
+		}
+		if err := st.Projects().Update(project); err != nil {
+			return err
+		}
+		return storeActivity(st, model.EntityProject, projectID, projectID, model.ActivityUpdated, opts.Actor,
+			snapshot{"workflow": previous}, snapshot{"workflow": workflow})
+	}); err != nil {
+		var workflowErr *WorkflowError
+		if err != ErrVersionMismatch && !errors.As(err, &workflowErr) {
+			s.logger.Error("Failed to update project workflow", zap.Error(err), zap.String("project_id", projectID))
+		}
+		return nil, err
+	}
+
+	response := &model.WorkflowResponse{
+		ProjectID: projectID,
+		Custom:    encoded != "",
+		Workflow:  workflow,
+	}
+
+	s.logger.Info("Project workflow updated", zap.String("project_id", projectID))
+	s.events.Publish(events.Event{
+		Type:      events.WorkflowUpdated,
+		EntityID:  projectID,
+		ProjectID: projectID,
+		Data:      response,
+	})
+	return response, nil
+}
+
+// checkStatusesInUse fails with a WorkflowError when tasks of the project
+// are in statuses the workflow no longer has.
+func checkStatusesInUse(st store.Store, projectID string, workflow *model.Workflow) error {
+	counts, err := st.Tasks().StatusCounts(projectID)
+	if err != nil {
+		return err
+	}
+
+	inUse := make(map[string]int64)
+	for status, count := range counts {
+		if findStatus(workflow, status) == nil {
+			inUse[status] = count
+		}
+	}
+	if len(inUse) == 0 {
+		return nil
+	}
+
+	dropped := make([]string, 0, len(inUse))
+	for status := range inUse {
+		dropped = append(dropped, status)
+	}
+	sort.Strings(dropped)
+	return &WorkflowError{
+		Message: fmt.Sprintf("statuses still used by tasks: %s", strings.Join(dropped, ", ")),
+		InUse:   inUse,
+	}
+}
+
+// workflowFor returns the workflow that applies to tasks of a project. Tasks
+// without a project follow the default workflow.
+func (s *WorkflowService) workflowFor(projectID string) (model.Workflow, error) {
+	if projectID == "" {
+		return DefaultWorkflow(), nil
+	}
+
+	project, err := s.stores.Projects().Get(projectID)
+	if err == store.ErrNotFound {
+		return DefaultWorkflow(), nil
+	}
+	if err != nil {
+		return model.Workflow{}, err
+	}
+
+	workflow, _, err := decodeWorkflow(project.Workflow)
+	return workflow, err
+}
+
+// InitialStatus returns the status new tasks of a project start in.
+func (s *WorkflowService) InitialStatus(projectID string) (string, error) {
+	workflow, err := s.workflowFor(projectID)
+	if err != nil {
+		return "", err
+	}
+	return workflow.Statuses[0].Key, nil
+}
+
+// ValidateStatus checks that a status is declared by the project's workflow.
+func (s *WorkflowService) ValidateStatus(projectID, status string) error {
+	workflow, err := s.workflowFor(projectID)
+	if err != nil {
+		return err
+	}
+
+	if findStatus(&workflow, status) == nil {
+		return &StatusError{Status: status, Allowed: statusKeys(&workflow)}
+	}
+	return nil
+}
+
+// ValidateTransition checks that a task may move from one status to another.
+// Tasks stuck in a status the workflow no longer declares may move to any
+// declared status.
+func (s *WorkflowService) ValidateTransition(projectID, from, to string) error {
+	if err := s.ValidateStatus(projectID, to); err != nil || from == to {
+		return err
+	}
+
+	workflow, err := s.workflowFor(projectID)
+	if err != nil {
+		return err
+	}
+
+	if findStatus(&workflow, from) == nil {
+		return nil
+	}
+
+	allowed := workflow.Transitions[from]
+	for _, status := range allowed {
+		if status == to {
+			return nil
+		}
+	}
+	if allowed == nil {
+		allowed = []string{}
+	}
+	return &StatusError{Status: to, From: from, Allowed: allowed}
+}
+
+// CanTransition reports whether a task may move from one status to another.
+func (s *WorkflowService) CanTransition(projectID, from, to string) bool {
+	return s.ValidateTransition(projectID, from, to) == nil
+}
+
+// IsFinal reports whether a status ends the work on a task.
+func (s *WorkflowService) IsFinal(projectID, status string) bool {
+	workflow, err := s.workflowFor(projectID)
+	if err != nil {
+		s.logger.Warn("Failed to resolve project workflow", zap.Error(err), zap.String("project_id", projectID))
+		return status == model.TaskStatusDone || status == model.TaskStatusCancelled
+	}
+
+	if found := findStatus(&workflow, status); found != nil {
+		return found.Final
+	}
+	return false
+}
+
+// finalStatuses returns the final statuses of the default workflow and of
+// every project declaring its own workflow.
+func (s *WorkflowService) finalStatuses() (store.FinalStatuses, error) {
+	defaultWorkflow := DefaultWorkflow()
+	final := store.FinalStatuses{
+		Default: finalStatusKeys(&defaultWorkflow),
+		Custom:  map[string][]string{},
+	}
+
+	projects, err := s.stores.Projects().List(true)
+	if err != nil {
+		return store.FinalStatuses{}, err
+	}
+	for _, project := range projects {
+		workflow, isCustom, err := decodeWorkflow(project.Workflow)
+		if err != nil {
+			return store.FinalStatuses{}, err
+		}
+		if isCustom {
+			final.Custom[project.ID] = finalStatusKeys(&workflow)
+		}
+	}
+	return final, nil
+}
+
+func decodeWorkflow(encoded string) (model.Workflow, bool, error) {
+	if encoded == "" {
+		return DefaultWorkflow(), false, nil
+	}
+
+	var workflow model.Workflow
+	if err := json.Unmarshal([]byte(encoded), &workflow); err != nil {
+		return model.Workflow{}, false, err
+	}
+	if len(workflow.Statuses) == 0 {
+		return DefaultWorkflow(), false, nil
+	}
+	return workflow, true, nil
+}
+
+// normalizeWorkflow validates a workflow definition and fills in defaults.
+func normalizeWorkflow(req *model.Workflow) (model.Workflow, error) {
+	if len(req.Statuses) == 0 {
+		return model.Workflow{}, &WorkflowError{Message: "workflow must declare at least one status"}
+	}
+
+	workflow := model.Workflow{
+		Statuses:    make([]model.WorkflowStatus, len(req.Statuses)),
+		Transitions: make(map[string][]string, len(req.Statuses)),
+	}
+
+	seen := make(map[string]bool, len(req.Statuses))
+	for i, status := range req.Statuses {
+		key := strings.TrimSpace(status.Key)
+		if !statusKeyPattern.MatchString(key) {
+			return model.Workflow{}, &WorkflowError{Message: fmt.Sprintf("invalid status key %q: use lowercase letters, digits, '-' and '_'", status.Key)}
+		}
+		if seen[key] {
+			return model.Workflow{}, &WorkflowError{Message: fmt.Sprintf("duplicate status %q", key)}
+		}
+		seen[key] = true
+
+		name := strings.TrimSpace(status.Name)
+		if name == "" {
+			name = key
+		}
+		workflow.Statuses[i] = model.WorkflowStatus{Key: key, Name: name, Final: status.Final}
+	}
+
+	for from, targets := range req.Transitions {
+		if !seen[from] {
+			return model.Workflow{}, &WorkflowError{Message: fmt.Sprintf("transition from undeclared status %q", from)}
+		}
+		allowed := make([]string, 0, len(targets))
+		for _, to := range targets {
+			if !seen[to] {
+				return model.Workflow{}, &WorkflowError{Message: fmt.Sprintf("transition from %q to undeclared status %q", from, to)}
+			}
+			if to != from {
+				allowed = append(allowed, to)
+			}
+		}
+		workflow.Transitions[from] = allowed
+	}
+
+	return workflow, nil
+}
+
+func findStatus(workflow *model.Workflow, key string) *model.WorkflowStatus {
+	for i := range workflow.Statuses {
+		if workflow.Statuses[i].Key == key {
+			return &workflow.Statuses[i]
+		}
+	}
+	return nil
+}
+
+func finalStatusKeys(workflow *model.Workflow) []string {
+	var keys []string
+	for _, status := range workflow.Statuses {
+		if status.Final {
+			keys = append(keys, status.Key)
+		}
+	}
+	return keys
+}
+
+func statusKeys(workflow *model.Workflow) []string {
+	keys := make([]string, len(workflow.Statuses))
+	for i, status := range workflow.Statuses {
+		keys[i] = status.Key
+	}
+	return keys
+}
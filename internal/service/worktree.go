@@ -0,0 +1,229 @@
+package service
+
+import (
+	"path/filepath"
+	"sync"
+
+	"go.uber.org/zap"
+
+	"github.com/amoylab/solo-api/internal/config"
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/git"
+	"github.com/amoylab/solo-api/internal/store"
+)
+
+// WorktreeService gives each task its own git worktree and branch so agents
+// working on parallel tasks of the same project never share a checkout.
+type WorktreeService struct {
+	stores store.Store
+	cfg    *config.GitConfig
+	logger *zap.Logger
+
+	// repositories serializes git operations per repository; concurrent
+	// worktree, merge and rebase commands on one repository race on its lock
+	// files. Reviews take the same locks.
+	repositories repositoryLocks
+}
+
+// repositoryLocks hands out one mutex per repository directory.
+type repositoryLocks struct {
+	mu    sync.Mutex
+	locks map[string]*sync.Mutex
+}
+
+// lock blocks until the repository in dir is free and returns the function
+// releasing it.
+func (l *repositoryLocks) lock(dir string) func() {
+	if abs, err := filepath.Abs(dir); err == nil {
+		dir = abs
+	}
+
+	l.mu.Lock()
+	if l.locks == nil {
+		l.locks = make(map[string]*sync.Mutex)
+	}
+	repository, ok := l.locks[dir]
+	if !ok {
+		repository = &sync.Mutex{}
+		l.locks[dir] = repository
+	}
+	l.mu.Unlock()
+
+	repository.Lock()
+	return repository.Unlock
+}
+
+func NewWorktreeService(stores store.Store, cfg *config.GitConfig, logger *zap.Logger) *WorktreeService {
+	return &WorktreeService{
+		stores: stores,
+		cfg:    cfg,
+		logger: logger,
+	}
+}
+
+// EnsureWorktree returns the path of the task's worktree, creating the
+// worktree and its branch first if needed. Projects whose directory is not a
+// git repository run in place, so their directory is returned unchanged.
+func (s *WorktreeService) EnsureWorktree(task *database.Task, project *database.Project) (string, error) {
+	if !git.IsRepository(project.Directory) {
+		return project.Directory, nil
+	}
+
+	defer s.lockRepository(project.Directory)()
+	return s.ensureWorktree(task, project)
+}
+
+// lockRepository takes the git lock of the repository in dir and returns the
+// function releasing it. Callers holding it use the unlocked worktree helpers.
+func (s *WorktreeService) lockRepository(dir string) func() {
+	return s.repositories.lock(dir)
+}
+
+// ensureWorktree is EnsureWorktree for callers holding the repository lock.
+func (s *WorktreeService) ensureWorktree(task *database.Task, project *database.Project) (string, error) {
+	if !git.IsRepository(project.Directory) {
+		return project.Directory, nil
+	}
+
+	if task.WorktreePath != "" && git.IsRepository(task.WorktreePath) {
+		return task.WorktreePath, nil
+	}
+
+	branch := task.Branch
+	if branch == "" {
+		branch = git.TaskBranch(s.cfg.BranchPrefix, task.ID, task.Title)
+	}
+
+	baseBranch := task.BaseBranch
+	if baseBranch == "" {
+		baseBranch = project.BaseBranch
+	}
+	if baseBranch == "" {
+		current, err := git.CurrentBranch(project.Directory)
+		if err != nil {
+			return "", err
+		}
+		baseBranch = current
+	}
+
+	path, err := s.worktreePath(task.ID)
+	if err != nil {
+		return "", err
+	}
+
+	s.logger.Info("Creating task worktree",
+		zap.String("task_id", task.ID),
+		zap.String("branch", branch),
+		zap.String("base_branch", baseBranch),
+		zap.String("path", path))
+
+	if err := git.AddWorktree(project.Directory, path, branch, baseBranch); err != nil {
+		s.logger.Error("Failed to create worktree", zap.Error(err), zap.String("task_id", task.ID))
+		return "", err
+	}
+
+	task.Branch = branch
+	task.BaseBranch = baseBranch
+	task.WorktreePath = path
+	if err := s.stores.Tasks().SetBranch(task.ID, branch, baseBranch, path); err != nil {
+		s.logger.Error("Failed to record task worktree", zap.Error(err), zap.String("task_id", task.ID))
+		return "", err
+	}
+
+	return path, nil
+}
+
+// RemoveWorktree deletes the task's worktree. The branch is kept so the work
+// can still be reviewed or merged unless deleteBranch is set.
+func (s *WorktreeService) RemoveWorktree(task *database.Task, deleteBranch bool) error {
+	if task.WorktreePath == "" && (!deleteBranch || task.Branch == "") {
+		return nil
+	}
+
+	project, err := s.taskProject(task)
+	if err != nil {
+		s.logger.Error("Failed to get project for worktree cleanup", zap.Error(err), zap.String("task_id", task.ID))
+		return err
+	}
+
+	defer s.lockRepository(project.Directory)()
+	return s.removeWorktree(task, project, deleteBranch)
+}
+
+// removeWorktree is RemoveWorktree for callers holding the repository lock.
+func (s *WorktreeService) removeWorktree(task *database.Task, project *database.Project, deleteBranch bool) error {
+	if task.WorktreePath == "" && (!deleteBranch || task.Branch == "") {
+		return nil
+	}
+
+	if task.WorktreePath != "" {
+		s.logger.Info("Removing task worktree", zap.String("task_id", task.ID), zap.String("path", task.WorktreePath))
+		if err := git.RemoveWorktree(project.Directory, task.WorktreePath); err != nil {
+			s.logger.Error("Failed to remove worktree", zap.Error(err), zap.String("task_id", task.ID))
+			return err
+		}
+		task.WorktreePath = ""
+	}
+
+	if deleteBranch && task.Branch != "" {
+		s.logger.Info("Deleting task branch", zap.String("task_id", task.ID), zap.String("branch", task.Branch))
+		if err := git.DeleteBranch(project.Directory, task.Branch); err != nil {
+			s.logger.Error("Failed to delete branch", zap.Error(err), zap.String("task_id", task.ID))
+			// The worktree is gone either way
+			if err := s.stores.Tasks().SetBranch(task.ID, task.Branch, task.BaseBranch, ""); err != nil {
+				s.logger.Error("Failed to record removed worktree", zap.Error(err), zap.String("task_id", task.ID))
+			}
+			return err
+		}
+	}
+
+	if deleteBranch {
+		task.Branch = ""
+		task.BaseBranch = ""
+	}
+
+	// The task row may already be gone when cleaning up after a delete.
+	return s.stores.Tasks().SetBranch(task.ID, task.Branch, task.BaseBranch, "")
+}
+
+// CommitWorktree commits everything the agent left behind in the task's worktree.
+func (s *WorktreeService) CommitWorktree(task *database.Task, message string) (bool, error) {
+	if task.WorktreePath == "" || !git.IsRepository(task.WorktreePath) {
+		return false, nil
+	}
+
+	project, err := s.taskProject(task)
+	if err != nil {
+		s.logger.Error("Failed to get project for worktree commit", zap.Error(err), zap.String("task_id", task.ID))
+		return false, err
+	}
+
+	defer s.lockRepository(project.Directory)()
+	return s.commitWorktree(task, message)
+}
+
+// commitWorktree is CommitWorktree for callers holding the repository lock.
+func (s *WorktreeService) commitWorktree(task *database.Task, message string) (bool, error) {
+	if task.WorktreePath == "" || !git.IsRepository(task.WorktreePath) {
+		return false, nil
+	}
+	return git.CommitAll(task.WorktreePath, message)
+}
+
+// taskProject loads the project whose repository holds the task's worktree.
+// Trashed tasks are cleaned up when purged, possibly with their project.
+func (s *WorktreeService) taskProject(task *database.Task) (*database.Project, error) {
+	project, err := s.stores.Projects().Get(task.ProjectID)
+	if err == store.ErrNotFound {
+		return s.stores.Trash().Project(task.ProjectID)
+	}
+	return project, err
+}
+
+func (s *WorktreeService) worktreePath(taskID string) (string, error) {
+	root := s.cfg.WorktreeRoot
+	if root == "" {
+		root = "worktrees"
+	}
+	return filepath.Abs(filepath.Join(root, taskID))
+}
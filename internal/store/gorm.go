@@ -0,0 +1,800 @@
+package store
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+// GormStore keeps entities in the database. Created on a transaction, it
+// works within the transaction.
+type GormStore struct {
+	db *gorm.DB
+}
+
+func NewGormStore(db *gorm.DB) *GormStore {
+	return &GormStore{db: db}
+}
+
+func (s *GormStore) Agents() AgentStore     { return gormAgents{s.db} }
+func (s *GormStore) Projects() ProjectStore { return gormProjects{s.db} }
+func (s *GormStore) Tasks() TaskStore       { return gormTasks{s.db} }
+func (s *GormStore) Tags() TagStore         { return gormTags{s.db} }
+func (s *GormStore) Runs() RunStore         { return gormRuns{s.db} }
+func (s *GormStore) Trash() TrashStore      { return gormTrash{s.db} }
+
+func (s *GormStore) Dependencies() DependencyStore { return gormDependencies{s.db} }
+
+func (s *GormStore) RecordActivity(activity *database.Activity) error {
+	return s.db.Create(activity).Error
+}
+
+func (s *GormStore) Transaction(fn func(tx Store) error) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		return fn(NewGormStore(tx))
+	})
+}
+
+// updateVersioned moves a row on from the version it was read at and saves
+// it. A change committed by someone else since the row was read makes it
+// fail instead of being overwritten.
+func updateVersioned(db *gorm.DB, table interface{}, id string, version *int64, value interface{}) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(table).Where("id = ? AND version = ?", id, *version).UpdateColumn("version", *version+1)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrVersionMismatch
+		}
+		*version++
+		return tx.Omit(clause.Associations).Save(value).Error
+	})
+}
+
+type gormAgents struct {
+	db *gorm.DB
+}
+
+func (s gormAgents) Get(id string) (*database.Agent, error) {
+	var agent database.Agent
+	if err := s.db.First(&agent, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &agent, nil
+}
+
+func (s gormAgents) List() ([]database.Agent, error) {
+	var agents []database.Agent
+	err := s.db.Order("created_at, id").Find(&agents).Error
+	return agents, err
+}
+
+func (s gormAgents) Create(agent *database.Agent) error {
+	return s.db.Create(agent).Error
+}
+
+func (s gormAgents) Update(agent *database.Agent) error {
+	return updateVersioned(s.db, &database.Agent{}, agent.ID, &agent.Version, agent)
+}
+
+func (s gormAgents) Delete(id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Unscoped().Model(&database.Task{}).Where("agent_id = ?", id).UpdateColumn("agent_id", nil).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Model(&database.Project{}).Where("agent_id = ?", id).UpdateColumn("agent_id", nil).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&database.Agent{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+type gormProjects struct {
+	db *gorm.DB
+}
+
+func (s gormProjects) Get(id string) (*database.Project, error) {
+	var project database.Project
+	if err := s.db.Preload("Agent").First(&project, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s gormProjects) List(includeArchived bool) ([]database.Project, error) {
+	db := s.db
+	if !includeArchived {
+		db = db.Where("archived_at IS NULL")
+	}
+
+	var projects []database.Project
+	err := db.Preload("Agent").Order("created_at, id").Find(&projects).Error
+	return projects, err
+}
+
+func (s gormProjects) Create(project *database.Project) error {
+	return s.db.Omit(clause.Associations).Create(project).Error
+}
+
+func (s gormProjects) Update(project *database.Project) error {
+	return updateVersioned(s.db, &database.Project{}, project.ID, &project.Version, project)
+}
+
+func (s gormProjects) BumpVersions(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.Model(&database.Project{}).Where("id IN ?", ids).UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+func (s gormProjects) Trash(id string, deletedAt time.Time, batch string) error {
+	return s.db.Model(&database.Project{}).Where("id = ?", id).UpdateColumns(map[string]interface{}{
+		"deleted_at":  deletedAt,
+		"trash_batch": batch,
+	}).Error
+}
+
+type gormTasks struct {
+	db *gorm.DB
+}
+
+func (s gormTasks) Get(id string) (*database.Task, error) {
+	var task database.Task
+	if err := s.db.Preload("TaskTags.Tag").Preload("Agent").First(&task, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// taskSortColumns are the expressions behind TaskSortFields.
+var taskSortColumns = map[string]clause.Expr{
+	"created_at": {SQL: "tasks.created_at"},
+	"updated_at": {SQL: "tasks.updated_at"},
+	"title":      {SQL: "tasks.title"},
+	"status":     {SQL: "tasks.status"},
+	"assignee":   {SQL: "tasks.assignee"},
+	"priority":   {SQL: "CASE tasks.priority WHEN 'urgent' THEN 3 WHEN 'high' THEN 2 WHEN 'medium' THEN 1 ELSE 0 END"},
+	"due_date":   {SQL: "COALESCE(tasks.due_date, ?)", Vars: []interface{}{noDueDate.Local()}},
+	"position":   {SQL: "tasks.position"},
+}
+
+func (s gormTasks) Find(filter TaskFilter, page TaskPage) ([]database.Task, error) {
+	key := page.Sort
+	if key == "" {
+		key = "created_at"
+	}
+	column, ok := taskSortColumns[key]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort tasks by %q", page.Sort)
+	}
+
+	direction, comparison := "ASC", ">"
+	if page.Desc {
+		direction, comparison = "DESC", "<"
+	}
+
+	db := s.filter(filter)
+	if page.After != nil {
+		// The column appears twice, each time with its own variables
+		var vars []interface{}
+		vars = append(vars, column.Vars...)
+		vars = append(vars, page.After.Value)
+		vars = append(vars, column.Vars...)
+		vars = append(vars, page.After.Value, page.After.ID)
+		db = db.Where(fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND tasks.id %[2]s ?))", column.SQL, comparison), vars...)
+	}
+	db = db.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                fmt.Sprintf("%s %s, tasks.id %s", column.SQL, direction, direction),
+		Vars:               column.Vars,
+		WithoutParentheses: true,
+	}})
+	if page.Limit > 0 {
+		db = db.Limit(page.Limit)
+	}
+
+	var tasks []database.Task
+	err := db.Preload("TaskTags.Tag").Preload("Agent").Find(&tasks).Error
+	return tasks, err
+}
+
+func (s gormTasks) Count(filter TaskFilter) (int64, error) {
+	var count int64
+	err := s.filter(filter).Count(&count).Error
+	return count, err
+}
+
+func (s gormTasks) filter(filter TaskFilter) *gorm.DB {
+	db := s.db.Model(&database.Task{})
+	if !filter.IncludeArchived {
+		db = db.Where("tasks.archived_at IS NULL")
+	}
+	if filter.ProjectID != "" {
+		db = db.Where("tasks.project_id = ?", filter.ProjectID)
+	}
+	if filter.ParentID != "" {
+		db = db.Where("tasks.parent_id = ?", filter.ParentID)
+	}
+	if len(filter.Statuses) > 0 {
+		db = db.Where("tasks.status IN ?", filter.Statuses)
+	}
+	for _, tag := range filter.Tags {
+		db = db.Where("tasks.id IN (?)", s.db.Table("task_tags").
+			Select("task_tags.task_id").
+			Joins("JOIN tags ON tags.id = task_tags.tag_id").
+			Where("tags.name = ?", tag))
+	}
+	if filter.AgentID != "" {
+		db = db.Where("tasks.agent_id = ?", filter.AgentID)
+	}
+	if filter.Assignee != "" {
+		db = db.Where("tasks.assignee = ?", filter.Assignee)
+	}
+	if filter.Text != "" {
+		pattern := LikePattern(filter.Text)
+		db = db.Where("(LOWER(tasks.title) LIKE ? ESCAPE '!' OR LOWER(tasks.description) LIKE ? ESCAPE '!')", pattern, pattern)
+	}
+	if filter.Finished != nil {
+		open, args := openCondition(filter.Final)
+		if *filter.Finished {
+			open = "NOT " + open
+		}
+		db = db.Where(open, args...)
+	}
+	if filter.Overdue != nil {
+		open, args := openCondition(filter.Final)
+		overdue := "(tasks.due_date IS NOT NULL AND tasks.due_date < ? AND " + open + ")"
+		args = append([]interface{}{time.Now()}, args...)
+		if !*filter.Overdue {
+			overdue = "NOT " + overdue
+		}
+		db = db.Where(overdue, args...)
+	}
+	if filter.CreatedAfter != nil {
+		db = db.Where("tasks.created_at >= ?", *filter.CreatedAfter)
+	}
+	if filter.CreatedBefore != nil {
+		db = db.Where("tasks.created_at < ?", *filter.CreatedBefore)
+	}
+	if filter.CompletedBefore != nil {
+		db = db.Where("COALESCE(tasks.completed_at, tasks.updated_at) < ?", *filter.CompletedBefore)
+	}
+	return db
+}
+
+// openCondition matches tasks that are not in a final status of their
+// project's workflow.
+func openCondition(final FinalStatuses) (string, []interface{}) {
+	var conditions []string
+	var args []interface{}
+	customProjects := make([]string, 0, len(final.Custom))
+	for projectID, statuses := range final.Custom {
+		customProjects = append(customProjects, projectID)
+		conditions = append(conditions, "(tasks.project_id = ? AND tasks.status NOT IN ?)")
+		args = append(args, projectID, nonEmpty(statuses))
+	}
+
+	if len(customProjects) > 0 {
+		conditions = append(conditions, "(tasks.project_id NOT IN ? AND tasks.status NOT IN ?)")
+		args = append(args, customProjects, nonEmpty(final.Default))
+	} else {
+		conditions = append(conditions, "tasks.status NOT IN ?")
+		args = append(args, nonEmpty(final.Default))
+	}
+
+	return "(" + strings.Join(conditions, " OR ") + ")", args
+}
+
+// nonEmpty keeps NOT IN conditions valid for workflows without final statuses.
+func nonEmpty(values []string) []string {
+	if len(values) == 0 {
+		return []string{""}
+	}
+	return values
+}
+
+// LikePattern matches text containing value, ignoring case, when compared
+// as LOWER(column) LIKE pattern ESCAPE '!'. The escape character reads the
+// same in every database, unlike a backslash.
+func LikePattern(value string) string {
+	escaped := strings.NewReplacer(`!`, `!!`, `%`, `!%`, `_`, `!_`).Replace(strings.ToLower(value))
+	return "%" + escaped + "%"
+}
+
+func (s gormTasks) Subtasks(parentIDs []string) ([]database.Task, error) {
+	var tasks []database.Task
+	err := s.db.Where("parent_id IN ?", parentIDs).Order("created_at, id").Find(&tasks).Error
+	return tasks, err
+}
+
+func (s gormTasks) Create(task *database.Task) error {
+	return s.db.Omit(clause.Associations).Create(task).Error
+}
+
+func (s gormTasks) Update(task *database.Task) error {
+	return updateVersioned(s.db, &database.Task{}, task.ID, &task.Version, task)
+}
+
+func (s gormTasks) BumpVersions(ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	return s.db.Model(&database.Task{}).Where("id IN ?", ids).UpdateColumn("version", gorm.Expr("version + 1")).Error
+}
+
+func (s gormTasks) Trash(ids []string, deletedAt time.Time, batch string) error {
+	return s.db.Model(&database.Task{}).Where("id IN ?", ids).UpdateColumns(map[string]interface{}{
+		"deleted_at":  deletedAt,
+		"trash_batch": batch,
+	}).Error
+}
+
+func (s gormTasks) SetBranch(id, branch, baseBranch, worktreePath string) error {
+	return s.db.Model(&database.Task{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"branch":        branch,
+		"base_branch":   baseBranch,
+		"worktree_path": worktreePath,
+		"version":       gorm.Expr("version + 1"),
+		"updated_at":    time.Now(),
+	}).Error
+}
+
+func (s gormTasks) SetTags(taskID string, names []string) error {
+	if err := s.db.Where("task_id = ?", taskID).Delete(&database.TaskTag{}).Error; err != nil {
+		return err
+	}
+
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		var tag database.Tag
+		err := s.db.Where("name = ?", name).First(&tag).Error
+		if err == gorm.ErrRecordNotFound {
+			// Create the tag, unless a concurrent request just did
+			tag = database.Tag{
+				ID:        uuid.New().String(),
+				Name:      name,
+				CreatedAt: time.Now(),
+				UpdatedAt: time.Now(),
+			}
+			result := s.db.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "name"}}, DoNothing: true}).Create(&tag)
+			if result.Error != nil {
+				return result.Error
+			}
+			if result.RowsAffected == 0 {
+				tag = database.Tag{}
+				if err := s.db.Where("name = ?", name).First(&tag).Error; err != nil {
+					return err
+				}
+			}
+		} else if err != nil {
+			return err
+		}
+
+		if err := s.db.Create(&database.TaskTag{TaskID: taskID, TagID: tag.ID}).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s gormTasks) TagNames(taskIDs []string) (map[string][]string, error) {
+	var rows []struct {
+		TaskID string
+		Name   string
+	}
+	if err := s.db.Table("task_tags").
+		Select("task_tags.task_id, tags.name").
+		Joins("JOIN tags ON tags.id = task_tags.tag_id").
+		Where("task_tags.task_id IN ?", taskIDs).
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	tags := make(map[string][]string, len(taskIDs))
+	for _, row := range rows {
+		tags[row.TaskID] = append(tags[row.TaskID], row.Name)
+	}
+	return tags, nil
+}
+
+func (s gormTasks) LastPosition(projectID, status string) (string, error) {
+	var positions []string
+	if err := s.db.Model(&database.Task{}).
+		Where("project_id = ? AND status = ?", projectID, status).
+		Where("position IS NOT NULL AND position <> ''").
+		Order("position DESC").Limit(1).
+		Pluck("position", &positions).Error; err != nil {
+		return "", err
+	}
+	if len(positions) == 0 {
+		return "", nil
+	}
+	return positions[0], nil
+}
+
+func (s gormTasks) Column(projectID, status string) ([]database.Task, error) {
+	var tasks []database.Task
+	err := s.db.Select("id", "project_id", "status", "position").
+		Where("project_id = ? AND status = ?", projectID, status).
+		Where("position IS NOT NULL AND position <> ''").
+		Order("position, id").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (s gormTasks) Unpositioned() ([]database.Task, error) {
+	var tasks []database.Task
+	err := s.db.Select("id", "project_id", "status").
+		Where("position = '' OR position IS NULL").
+		Order("project_id, status, created_at, id").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (s gormTasks) SetPosition(id, position string) error {
+	return s.db.Model(&database.Task{}).Where("id = ?", id).UpdateColumn("position", position).Error
+}
+
+func (s gormTasks) StatusCounts(projectID string) (map[string]int64, error) {
+	var rows []struct {
+		Status string
+		Count  int64
+	}
+	if err := s.db.Model(&database.Task{}).
+		Select("status, COUNT(*) AS count").
+		Where("project_id = ?", projectID).
+		Group("status").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.Status] = row.Count
+	}
+	return counts, nil
+}
+
+func (s gormTasks) SubtaskCounts(parentIDs []string, final FinalStatuses) (map[string]SubtaskCount, error) {
+	open, args := openCondition(final)
+
+	var rows []struct {
+		ParentID  string
+		Total     int64
+		Completed int64
+	}
+	if err := s.db.Model(&database.Task{}).
+		Select("tasks.parent_id, COUNT(*) AS total, SUM(CASE WHEN "+open+" THEN 0 ELSE 1 END) AS completed", args...).
+		Where("tasks.parent_id IN ?", parentIDs).
+		Group("tasks.parent_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]SubtaskCount, len(rows))
+	for _, row := range rows {
+		counts[row.ParentID] = SubtaskCount{Total: row.Total, Completed: row.Completed}
+	}
+	return counts, nil
+}
+
+func (s gormTasks) CommentCounts(taskIDs []string) (map[string]int64, error) {
+	var rows []struct {
+		TaskID string
+		Count  int64
+	}
+	if err := s.db.Model(&database.Comment{}).
+		Select("task_id, COUNT(*) AS count").
+		Where("task_id IN ?", taskIDs).
+		Group("task_id").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TaskID] = row.Count
+	}
+	return counts, nil
+}
+
+type gormDependencies struct {
+	db *gorm.DB
+}
+
+func (s gormDependencies) Tasks(taskID string) ([]database.Task, error) {
+	var tasks []database.Task
+	err := s.db.Preload("TaskTags.Tag").Preload("Agent").
+		Joins("JOIN task_dependencies ON task_dependencies.depends_on_id = tasks.id").
+		Where("task_dependencies.task_id = ?", taskID).
+		Order("task_dependencies.created_at").
+		Find(&tasks).Error
+	return tasks, err
+}
+
+func (s gormDependencies) DependsOn(taskIDs []string) ([]string, error) {
+	var ids []string
+	err := s.db.Model(&database.TaskDependency{}).Where("task_id IN ?", taskIDs).Pluck("depends_on_id", &ids).Error
+	return ids, err
+}
+
+func (s gormDependencies) Dependents(taskIDs []string) ([]string, error) {
+	var ids []string
+	err := s.db.Model(&database.TaskDependency{}).
+		Where("depends_on_id IN ? AND task_id NOT IN ?", taskIDs, taskIDs).
+		Distinct().Pluck("task_id", &ids).Error
+	return ids, err
+}
+
+func (s gormDependencies) Blocking(taskIDs []string, final FinalStatuses) (map[string][]string, error) {
+	open, args := openCondition(final)
+
+	var rows []database.TaskDependency
+	if err := s.db.Model(&database.TaskDependency{}).
+		Select("task_dependencies.task_id, task_dependencies.depends_on_id").
+		Joins("JOIN tasks ON tasks.id = task_dependencies.depends_on_id AND tasks.deleted_at IS NULL").
+		Where("task_dependencies.task_id IN ?", taskIDs).
+		Where(open, args...).
+		Order("task_dependencies.created_at").
+		Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	blocking := make(map[string][]string)
+	for _, row := range rows {
+		blocking[row.TaskID] = append(blocking[row.TaskID], row.DependsOnID)
+	}
+	return blocking, nil
+}
+
+func (s gormDependencies) Add(dependency *database.TaskDependency) (bool, error) {
+	result := s.db.Clauses(clause.OnConflict{DoNothing: true}).Create(dependency)
+	return result.RowsAffected > 0, result.Error
+}
+
+func (s gormDependencies) Remove(taskID, dependsOnID string) (bool, error) {
+	result := s.db.Delete(&database.TaskDependency{}, "task_id = ? AND depends_on_id = ?", taskID, dependsOnID)
+	return result.RowsAffected > 0, result.Error
+}
+
+type gormTags struct {
+	db *gorm.DB
+}
+
+func (s gormTags) Get(id string) (*database.Tag, error) {
+	var tag database.Tag
+	if err := s.db.First(&tag, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &tag, nil
+}
+
+func (s gormTags) List() ([]database.Tag, error) {
+	var tags []database.Tag
+	err := s.db.Order("name").Find(&tags).Error
+	return tags, err
+}
+
+func (s gormTags) NameTaken(name, exceptID string) (bool, error) {
+	var count int64
+	err := s.db.Model(&database.Tag{}).Where("name = ? AND id <> ?", name, exceptID).Count(&count).Error
+	return count > 0, err
+}
+
+func (s gormTags) CountTasks(tagIDs ...string) (map[string]int64, error) {
+	db := s.db.Table("task_tags").
+		Select("task_tags.tag_id, COUNT(*) AS count").
+		Joins("JOIN tasks ON tasks.id = task_tags.task_id AND tasks.deleted_at IS NULL").
+		Group("task_tags.tag_id")
+	if len(tagIDs) > 0 {
+		db = db.Where("task_tags.tag_id IN ?", tagIDs)
+	}
+
+	var rows []struct {
+		TagID string
+		Count int64
+	}
+	if err := db.Scan(&rows).Error; err != nil {
+		return nil, err
+	}
+
+	counts := make(map[string]int64, len(rows))
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
+
+func (s gormTags) TaskIDs(tagID string) ([]string, error) {
+	var taskIDs []string
+	err := s.db.Model(&database.TaskTag{}).Where("tag_id = ?", tagID).Pluck("task_id", &taskIDs).Error
+	return taskIDs, err
+}
+
+func (s gormTags) Create(tag *database.Tag) error {
+	return s.db.Create(tag).Error
+}
+
+func (s gormTags) Update(tag *database.Tag) error {
+	return s.db.Save(tag).Error
+}
+
+func (s gormTags) Delete(id string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&database.TaskTag{}, "tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		result := tx.Delete(&database.Tag{}, "id = ?", id)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return ErrNotFound
+		}
+		return nil
+	})
+}
+
+func (s gormTags) Merge(id, targetID string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		var count int64
+		if err := tx.Model(&database.Tag{}).Where("id IN ?", []string{id, targetID}).Count(&count).Error; err != nil {
+			return err
+		}
+		if count != 2 {
+			return ErrNotFound
+		}
+
+		var taskIDs []string
+		if err := tx.Model(&database.TaskTag{}).Where("tag_id = ?", id).Pluck("task_id", &taskIDs).Error; err != nil {
+			return err
+		}
+		// Tasks already carrying the target keep a single assignment
+		for _, taskID := range taskIDs {
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).
+				Create(&database.TaskTag{TaskID: taskID, TagID: targetID}).Error; err != nil {
+				return err
+			}
+		}
+		if err := tx.Delete(&database.TaskTag{}, "tag_id = ?", id).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&database.Tag{}, "id = ?", id).Error
+	})
+}
+
+func (s gormTags) DeleteUnused() ([]database.Tag, error) {
+	var unused []database.Tag
+	err := s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("task_id NOT IN (?)", tx.Unscoped().Model(&database.Task{}).Select("id")).
+			Delete(&database.TaskTag{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("id NOT IN (?)", tx.Model(&database.TaskTag{}).Select("tag_id")).
+			Find(&unused).Error; err != nil {
+			return err
+		}
+		if len(unused) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(unused))
+		for i := range unused {
+			ids[i] = unused[i].ID
+		}
+		return tx.Delete(&database.Tag{}, "id IN ?", ids).Error
+	})
+	return unused, err
+}
+
+type gormRuns struct {
+	db *gorm.DB
+}
+
+func (s gormRuns) Create(run *database.Run) error {
+	return s.db.Create(run).Error
+}
+
+func (s gormRuns) Active(taskID string) (bool, error) {
+	var active int64
+	err := s.db.Model(&database.Run{}).
+		Where("task_id = ? AND status = ?", taskID, model.RunStatusRunning).
+		Count(&active).Error
+	return active > 0, err
+}
+
+type gormTrash struct {
+	db *gorm.DB
+}
+
+func (s gormTrash) Tasks() ([]database.Task, error) {
+	var tasks []database.Task
+	err := s.db.Unscoped().Where("deleted_at IS NOT NULL").Order("created_at, id").Find(&tasks).Error
+	return tasks, err
+}
+
+func (s gormTrash) Projects() ([]database.Project, error) {
+	var projects []database.Project
+	err := s.db.Unscoped().Where("deleted_at IS NOT NULL").Order("created_at, id").Find(&projects).Error
+	return projects, err
+}
+
+func (s gormTrash) Task(id string) (*database.Task, error) {
+	var task database.Task
+	if err := s.db.Unscoped().Where("deleted_at IS NOT NULL").First(&task, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+func (s gormTrash) Project(id string) (*database.Project, error) {
+	var project database.Project
+	if err := s.db.Unscoped().Where("deleted_at IS NOT NULL").First(&project, "id = ?", id).Error; err != nil {
+		return nil, err
+	}
+	return &project, nil
+}
+
+func (s gormTrash) RestoreTask(task *database.Task) error {
+	return s.db.Unscoped().Model(&database.Task{}).Where("id = ?", task.ID).Updates(map[string]interface{}{
+		"deleted_at":  nil,
+		"trash_batch": "",
+		"status":      task.Status,
+		"position":    task.Position,
+		"version":     gorm.Expr("version + 1"),
+		"updated_at":  task.UpdatedAt,
+	}).Error
+}
+
+func (s gormTrash) RestoreProject(id string, restoredAt time.Time) error {
+	return s.db.Unscoped().Model(&database.Project{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"deleted_at":  nil,
+		"trash_batch": "",
+		"version":     gorm.Expr("version + 1"),
+		"updated_at":  restoredAt,
+	}).Error
+}
+
+func (s gormTrash) Purge(taskIDs, projectIDs []string) error {
+	return s.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&database.TaskDependency{}, "task_id IN ? OR depends_on_id IN ?", taskIDs, taskIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&database.TaskTag{}, "task_id IN ?", taskIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Where("comment_id IN (?)", tx.Model(&database.Comment{}).Select("id").Where("task_id IN ?", taskIDs)).
+			Delete(&database.CommentRevision{}).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&database.Comment{}, "task_id IN ?", taskIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Delete(&database.Run{}, "task_id IN ?", taskIDs).Error; err != nil {
+			return err
+		}
+		if err := tx.Unscoped().Delete(&database.Task{}, "id IN ?", taskIDs).Error; err != nil {
+			return err
+		}
+		return tx.Unscoped().Delete(&database.Project{}, "id IN ? AND deleted_at IS NOT NULL", projectIDs).Error
+	})
+}
@@ -0,0 +1,1025 @@
+package store
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+// MemoryStore keeps entities in memory, for tests of the services. It
+// checks versions and unique names like the database does, but enforces no
+// references between entities. It keeps no comments, so it counts none,
+// and runs only as far as RunStore goes.
+type MemoryStore struct {
+	mu   *sync.Mutex // Guards data for a single call
+	txMu *sync.Mutex // Runs one transaction at a time
+	data *memoryData
+}
+
+type memoryData struct {
+	agents       map[string]database.Agent
+	projects     map[string]database.Project
+	tasks        map[string]database.Task
+	tags         map[string]database.Tag
+	taskTags     map[string][]string // Tag IDs by task ID, in the order assigned
+	dependencies []database.TaskDependency
+	runs         []database.Run
+	activities   []database.Activity
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		mu:   &sync.Mutex{},
+		txMu: &sync.Mutex{},
+		data: &memoryData{
+			agents:   map[string]database.Agent{},
+			projects: map[string]database.Project{},
+			tasks:    map[string]database.Task{},
+			tags:     map[string]database.Tag{},
+			taskTags: map[string][]string{},
+		},
+	}
+}
+
+func (s *MemoryStore) Agents() AgentStore     { return memoryAgents{s} }
+func (s *MemoryStore) Projects() ProjectStore { return memoryProjects{s} }
+func (s *MemoryStore) Tasks() TaskStore       { return memoryTasks{s} }
+func (s *MemoryStore) Tags() TagStore         { return memoryTags{s} }
+func (s *MemoryStore) Runs() RunStore         { return memoryRuns{s} }
+func (s *MemoryStore) Trash() TrashStore      { return memoryTrash{s} }
+
+func (s *MemoryStore) Dependencies() DependencyStore { return memoryDependencies{s} }
+
+func (s *MemoryStore) RecordActivity(activity *database.Activity) error {
+	defer s.lock()()
+	activity.ID = int64(len(s.data.activities) + 1)
+	s.data.activities = append(s.data.activities, *activity)
+	return nil
+}
+
+// Activities returns the recorded activity log, oldest first.
+func (s *MemoryStore) Activities() []database.Activity {
+	defer s.lock()()
+	return append([]database.Activity(nil), s.data.activities...)
+}
+
+// Transaction runs fn on a copy of the data, which replaces the data when fn
+// succeeds. Calls outside the transaction see the data as it was until then.
+func (s *MemoryStore) Transaction(fn func(tx Store) error) error {
+	s.txMu.Lock()
+	defer s.txMu.Unlock()
+
+	s.mu.Lock()
+	work := s.data.clone()
+	s.mu.Unlock()
+
+	if err := fn(&MemoryStore{mu: &sync.Mutex{}, txMu: &sync.Mutex{}, data: work}); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	*s.data = *work
+	s.mu.Unlock()
+	return nil
+}
+
+// lock takes the store for a call and returns the function releasing it.
+func (s *MemoryStore) lock() func() {
+	s.mu.Lock()
+	return s.mu.Unlock
+}
+
+func (d *memoryData) clone() *memoryData {
+	c := &memoryData{
+		agents:       make(map[string]database.Agent, len(d.agents)),
+		projects:     make(map[string]database.Project, len(d.projects)),
+		tasks:        make(map[string]database.Task, len(d.tasks)),
+		tags:         make(map[string]database.Tag, len(d.tags)),
+		taskTags:     make(map[string][]string, len(d.taskTags)),
+		dependencies: append([]database.TaskDependency(nil), d.dependencies...),
+		runs:         append([]database.Run(nil), d.runs...),
+		activities:   append([]database.Activity(nil), d.activities...),
+	}
+	for id, agent := range d.agents {
+		c.agents[id] = agent
+	}
+	for id, project := range d.projects {
+		c.projects[id] = project
+	}
+	for id, task := range d.tasks {
+		c.tasks[id] = task
+	}
+	for id, tag := range d.tags {
+		c.tags[id] = tag
+	}
+	for id, tagIDs := range d.taskTags {
+		c.taskTags[id] = append([]string(nil), tagIDs...)
+	}
+	return c
+}
+
+// agent returns a copy of an agent for a reference, nil for none.
+func (d *memoryData) agent(id *string) *database.Agent {
+	if id == nil {
+		return nil
+	}
+	agent, ok := d.agents[*id]
+	if !ok {
+		return nil
+	}
+	return &agent
+}
+
+// withRelations returns a task with its agent and tags, as the database
+// loads it.
+func (d *memoryData) withRelations(task database.Task) database.Task {
+	task.Agent = d.agent(task.AgentID)
+	task.TaskTags = nil
+	for _, tagID := range d.taskTags[task.ID] {
+		task.TaskTags = append(task.TaskTags, database.TaskTag{
+			TaskID: task.ID,
+			TagID:  tagID,
+			Tag:    d.tags[tagID],
+		})
+	}
+	return task
+}
+
+// liveTasks returns the tasks not in the trash, oldest first.
+func (d *memoryData) liveTasks() []database.Task {
+	tasks := []database.Task{}
+	for _, task := range d.tasks {
+		if !task.DeletedAt.Valid {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, byCreation(
+		func(i int) time.Time { return tasks[i].CreatedAt },
+		func(i int) string { return tasks[i].ID }))
+	return tasks
+}
+
+// byCreation orders entities oldest first, like the database lists them.
+func byCreation(createdAt func(i int) time.Time, id func(i int) string) func(i, j int) bool {
+	return func(i, j int) bool {
+		if !createdAt(i).Equal(createdAt(j)) {
+			return createdAt(i).Before(createdAt(j))
+		}
+		return id(i) < id(j)
+	}
+}
+
+type memoryAgents struct {
+	s *MemoryStore
+}
+
+func (m memoryAgents) Get(id string) (*database.Agent, error) {
+	defer m.s.lock()()
+	agent, ok := m.s.data.agents[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &agent, nil
+}
+
+func (m memoryAgents) List() ([]database.Agent, error) {
+	defer m.s.lock()()
+	agents := make([]database.Agent, 0, len(m.s.data.agents))
+	for _, agent := range m.s.data.agents {
+		agents = append(agents, agent)
+	}
+	sort.Slice(agents, byCreation(
+		func(i int) time.Time { return agents[i].CreatedAt },
+		func(i int) string { return agents[i].ID }))
+	return agents, nil
+}
+
+func (m memoryAgents) Create(agent *database.Agent) error {
+	defer m.s.lock()()
+	if err := m.checkName(agent); err != nil {
+		return err
+	}
+	m.s.data.agents[agent.ID] = *agent
+	return nil
+}
+
+func (m memoryAgents) Update(agent *database.Agent) error {
+	defer m.s.lock()()
+	stored, ok := m.s.data.agents[agent.ID]
+	if !ok || stored.Version != agent.Version {
+		return ErrVersionMismatch
+	}
+	if err := m.checkName(agent); err != nil {
+		return err
+	}
+	agent.Version++
+	m.s.data.agents[agent.ID] = *agent
+	return nil
+}
+
+func (m memoryAgents) Delete(id string) error {
+	defer m.s.lock()()
+	if _, ok := m.s.data.agents[id]; !ok {
+		return ErrNotFound
+	}
+	for taskID, task := range m.s.data.tasks {
+		if task.AgentID != nil && *task.AgentID == id {
+			task.AgentID = nil
+			m.s.data.tasks[taskID] = task
+		}
+	}
+	for projectID, project := range m.s.data.projects {
+		if project.AgentID != nil && *project.AgentID == id {
+			project.AgentID = nil
+			m.s.data.projects[projectID] = project
+		}
+	}
+	delete(m.s.data.agents, id)
+	return nil
+}
+
+func (m memoryAgents) checkName(agent *database.Agent) error {
+	for _, other := range m.s.data.agents {
+		if other.ID != agent.ID && other.Name == agent.Name {
+			return ErrDuplicate
+		}
+	}
+	return nil
+}
+
+type memoryProjects struct {
+	s *MemoryStore
+}
+
+func (m memoryProjects) Get(id string) (*database.Project, error) {
+	defer m.s.lock()()
+	project, ok := m.s.data.projects[id]
+	if !ok || project.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	project.Agent = m.s.data.agent(project.AgentID)
+	return &project, nil
+}
+
+func (m memoryProjects) List(includeArchived bool) ([]database.Project, error) {
+	defer m.s.lock()()
+	projects := []database.Project{}
+	for _, project := range m.s.data.projects {
+		if project.DeletedAt.Valid || project.ArchivedAt != nil && !includeArchived {
+			continue
+		}
+		project.Agent = m.s.data.agent(project.AgentID)
+		projects = append(projects, project)
+	}
+	sort.Slice(projects, byCreation(
+		func(i int) time.Time { return projects[i].CreatedAt },
+		func(i int) string { return projects[i].ID }))
+	return projects, nil
+}
+
+func (m memoryProjects) Create(project *database.Project) error {
+	defer m.s.lock()()
+	stored := *project
+	stored.Agent = nil
+	m.s.data.projects[project.ID] = stored
+	return nil
+}
+
+func (m memoryProjects) Update(project *database.Project) error {
+	defer m.s.lock()()
+	stored, ok := m.s.data.projects[project.ID]
+	if !ok || stored.DeletedAt.Valid || stored.Version != project.Version {
+		return ErrVersionMismatch
+	}
+	project.Version++
+	stored = *project
+	stored.Agent = nil
+	m.s.data.projects[project.ID] = stored
+	return nil
+}
+
+func (m memoryProjects) BumpVersions(ids []string) error {
+	defer m.s.lock()()
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if project, ok := m.s.data.projects[id]; ok && !project.DeletedAt.Valid && !seen[id] {
+			seen[id] = true
+			project.Version++
+			m.s.data.projects[id] = project
+		}
+	}
+	return nil
+}
+
+func (m memoryProjects) Trash(id string, deletedAt time.Time, batch string) error {
+	defer m.s.lock()()
+	if project, ok := m.s.data.projects[id]; ok {
+		project.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+		project.TrashBatch = batch
+		m.s.data.projects[id] = project
+	}
+	return nil
+}
+
+type memoryTasks struct {
+	s *MemoryStore
+}
+
+func (m memoryTasks) Get(id string) (*database.Task, error) {
+	defer m.s.lock()()
+	task, ok := m.s.data.tasks[id]
+	if !ok || task.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+
+	task = m.s.data.withRelations(task)
+	return &task, nil
+}
+
+func (m memoryTasks) Find(filter TaskFilter, page TaskPage) ([]database.Task, error) {
+	defer m.s.lock()()
+	key := page.Sort
+	if key == "" {
+		key = "created_at"
+	}
+	field, ok := TaskSortFields[key]
+	if !ok {
+		return nil, fmt.Errorf("cannot sort tasks by %q", page.Sort)
+	}
+
+	// before tells whether task a sorts before task b in the page order
+	before := func(a interface{}, aID string, b interface{}, bID string) bool {
+		order := compareSortValues(a, b)
+		if order == 0 {
+			order = strings.Compare(aID, bID)
+		}
+		if page.Desc {
+			return order > 0
+		}
+		return order < 0
+	}
+
+	tasks := []database.Task{}
+	for _, task := range m.matching(filter) {
+		if page.After != nil && !before(page.After.Value, page.After.ID, field.Value(&task), task.ID) {
+			continue
+		}
+		tasks = append(tasks, m.s.data.withRelations(task))
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		return before(field.Value(&tasks[i]), tasks[i].ID, field.Value(&tasks[j]), tasks[j].ID)
+	})
+	if page.Limit > 0 && len(tasks) > page.Limit {
+		tasks = tasks[:page.Limit]
+	}
+	return tasks, nil
+}
+
+func (m memoryTasks) Count(filter TaskFilter) (int64, error) {
+	defer m.s.lock()()
+	return int64(len(m.matching(filter))), nil
+}
+
+// matching returns the live tasks matching a filter, oldest first.
+func (m memoryTasks) matching(filter TaskFilter) []database.Task {
+	now := time.Now()
+	text := strings.ToLower(filter.Text)
+
+	tasks := []database.Task{}
+	for _, task := range m.s.data.liveTasks() {
+		finished := filter.Final.IsFinal(task.ProjectID, task.Status)
+		completedAt := task.UpdatedAt
+		if task.CompletedAt != nil {
+			completedAt = *task.CompletedAt
+		}
+		overdue := !finished && task.DueDate != nil && task.DueDate.Before(now)
+		switch {
+		case task.ArchivedAt != nil && !filter.IncludeArchived,
+			filter.ProjectID != "" && task.ProjectID != filter.ProjectID,
+			filter.ParentID != "" && (task.ParentID == nil || *task.ParentID != filter.ParentID),
+			len(filter.Statuses) > 0 && !contains(filter.Statuses, task.Status),
+			filter.AgentID != "" && (task.AgentID == nil || *task.AgentID != filter.AgentID),
+			filter.Assignee != "" && task.Assignee != filter.Assignee,
+			text != "" && !strings.Contains(strings.ToLower(task.Title), text) && !strings.Contains(strings.ToLower(task.Description), text),
+			filter.Finished != nil && finished != *filter.Finished,
+			filter.Overdue != nil && overdue != *filter.Overdue,
+			filter.CreatedAfter != nil && task.CreatedAt.Before(*filter.CreatedAfter),
+			filter.CreatedBefore != nil && !task.CreatedAt.Before(*filter.CreatedBefore),
+			filter.CompletedBefore != nil && !completedAt.Before(*filter.CompletedBefore):
+			continue
+		}
+
+		names := []string{}
+		for _, tagID := range m.s.data.taskTags[task.ID] {
+			names = append(names, m.s.data.tags[tagID].Name)
+		}
+		tagged := true
+		for _, tag := range filter.Tags {
+			tagged = tagged && contains(names, tag)
+		}
+		if tagged {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks
+}
+
+// compareSortValues compares two values of a TaskSortField.
+func compareSortValues(a, b interface{}) int {
+	switch a := a.(type) {
+	case time.Time:
+		b, _ := b.(time.Time)
+		return a.Compare(b)
+	case int:
+		b, _ := b.(int)
+		return a - b
+	case string:
+		b, _ := b.(string)
+		return strings.Compare(a, b)
+	}
+	return 0
+}
+
+func (m memoryTasks) Subtasks(parentIDs []string) ([]database.Task, error) {
+	defer m.s.lock()()
+	tasks := []database.Task{}
+	for _, task := range m.s.data.liveTasks() {
+		if task.ParentID != nil && contains(parentIDs, *task.ParentID) {
+			tasks = append(tasks, task)
+		}
+	}
+	return tasks, nil
+}
+
+func (m memoryTasks) Create(task *database.Task) error {
+	defer m.s.lock()()
+	m.s.data.tasks[task.ID] = stripped(*task)
+	return nil
+}
+
+func (m memoryTasks) Update(task *database.Task) error {
+	defer m.s.lock()()
+	stored, ok := m.s.data.tasks[task.ID]
+	if !ok || stored.DeletedAt.Valid || stored.Version != task.Version {
+		return ErrVersionMismatch
+	}
+	task.Version++
+	m.s.data.tasks[task.ID] = stripped(*task)
+	return nil
+}
+
+// stripped drops the relations of a task, which are stored on their own.
+func stripped(task database.Task) database.Task {
+	task.Agent = nil
+	task.Parent = nil
+	task.TaskTags = nil
+	return task
+}
+
+func (m memoryTasks) BumpVersions(ids []string) error {
+	defer m.s.lock()()
+	seen := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		if task, ok := m.s.data.tasks[id]; ok && !task.DeletedAt.Valid && !seen[id] {
+			seen[id] = true
+			task.Version++
+			m.s.data.tasks[id] = task
+		}
+	}
+	return nil
+}
+
+func (m memoryTasks) Trash(ids []string, deletedAt time.Time, batch string) error {
+	defer m.s.lock()()
+	for _, id := range ids {
+		if task, ok := m.s.data.tasks[id]; ok {
+			task.DeletedAt = gorm.DeletedAt{Time: deletedAt, Valid: true}
+			task.TrashBatch = batch
+			m.s.data.tasks[id] = task
+		}
+	}
+	return nil
+}
+
+func (m memoryTasks) SetBranch(id, branch, baseBranch, worktreePath string) error {
+	defer m.s.lock()()
+	if task, ok := m.s.data.tasks[id]; ok && !task.DeletedAt.Valid {
+		task.Branch = branch
+		task.BaseBranch = baseBranch
+		task.WorktreePath = worktreePath
+		task.Version++
+		task.UpdatedAt = time.Now()
+		m.s.data.tasks[id] = task
+	}
+	return nil
+}
+
+func (m memoryTasks) SetTags(taskID string, names []string) error {
+	defer m.s.lock()()
+	tagIDs := []string{}
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if name == "" || seen[name] {
+			continue
+		}
+		seen[name] = true
+
+		tag, ok := memoryTags{m.s}.byName(name)
+		if !ok {
+			now := time.Now()
+			tag = database.Tag{ID: uuid.New().String(), Name: name, CreatedAt: now, UpdatedAt: now}
+			m.s.data.tags[tag.ID] = tag
+		}
+		tagIDs = append(tagIDs, tag.ID)
+	}
+
+	if len(tagIDs) == 0 {
+		delete(m.s.data.taskTags, taskID)
+	} else {
+		m.s.data.taskTags[taskID] = tagIDs
+	}
+	return nil
+}
+
+func (m memoryTasks) TagNames(taskIDs []string) (map[string][]string, error) {
+	defer m.s.lock()()
+	tags := make(map[string][]string, len(taskIDs))
+	for _, taskID := range taskIDs {
+		for _, tagID := range m.s.data.taskTags[taskID] {
+			tags[taskID] = append(tags[taskID], m.s.data.tags[tagID].Name)
+		}
+	}
+	return tags, nil
+}
+
+func (m memoryTasks) LastPosition(projectID, status string) (string, error) {
+	defer m.s.lock()()
+	last := ""
+	for _, task := range m.s.data.tasks {
+		if !task.DeletedAt.Valid && task.ProjectID == projectID && task.Status == status && task.Position > last {
+			last = task.Position
+		}
+	}
+	return last, nil
+}
+
+func (m memoryTasks) Column(projectID, status string) ([]database.Task, error) {
+	defer m.s.lock()()
+	tasks := []database.Task{}
+	for _, task := range m.s.data.liveTasks() {
+		if task.ProjectID == projectID && task.Status == status && task.Position != "" {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, func(i, j int) bool {
+		if tasks[i].Position != tasks[j].Position {
+			return tasks[i].Position < tasks[j].Position
+		}
+		return tasks[i].ID < tasks[j].ID
+	})
+	return tasks, nil
+}
+
+func (m memoryTasks) Unpositioned() ([]database.Task, error) {
+	defer m.s.lock()()
+	tasks := []database.Task{}
+	for _, task := range m.s.data.liveTasks() {
+		if task.Position == "" {
+			tasks = append(tasks, task)
+		}
+	}
+	// Stable, so tasks stay oldest first within a column
+	sort.SliceStable(tasks, func(i, j int) bool {
+		if tasks[i].ProjectID != tasks[j].ProjectID {
+			return tasks[i].ProjectID < tasks[j].ProjectID
+		}
+		return tasks[i].Status < tasks[j].Status
+	})
+	return tasks, nil
+}
+
+func (m memoryTasks) SetPosition(id, position string) error {
+	defer m.s.lock()()
+	if task, ok := m.s.data.tasks[id]; ok {
+		task.Position = position
+		m.s.data.tasks[id] = task
+	}
+	return nil
+}
+
+func (m memoryTasks) StatusCounts(projectID string) (map[string]int64, error) {
+	defer m.s.lock()()
+	counts := map[string]int64{}
+	for _, task := range m.s.data.liveTasks() {
+		if task.ProjectID == projectID {
+			counts[task.Status]++
+		}
+	}
+	return counts, nil
+}
+
+func (m memoryTasks) SubtaskCounts(parentIDs []string, final FinalStatuses) (map[string]SubtaskCount, error) {
+	defer m.s.lock()()
+	counts := map[string]SubtaskCount{}
+	for _, task := range m.s.data.liveTasks() {
+		if task.ParentID == nil || !contains(parentIDs, *task.ParentID) {
+			continue
+		}
+		count := counts[*task.ParentID]
+		count.Total++
+		if final.IsFinal(task.ProjectID, task.Status) {
+			count.Completed++
+		}
+		counts[*task.ParentID] = count
+	}
+	return counts, nil
+}
+
+func (m memoryTasks) CommentCounts(taskIDs []string) (map[string]int64, error) {
+	return map[string]int64{}, nil
+}
+
+type memoryDependencies struct {
+	s *MemoryStore
+}
+
+func (m memoryDependencies) Tasks(taskID string) ([]database.Task, error) {
+	defer m.s.lock()()
+	tasks := []database.Task{}
+	for _, dependency := range m.s.data.dependencies {
+		task, ok := m.s.data.tasks[dependency.DependsOnID]
+		if dependency.TaskID == taskID && ok && !task.DeletedAt.Valid {
+			tasks = append(tasks, m.s.data.withRelations(task))
+		}
+	}
+	return tasks, nil
+}
+
+func (m memoryDependencies) DependsOn(taskIDs []string) ([]string, error) {
+	defer m.s.lock()()
+	ids := []string{}
+	for _, dependency := range m.s.data.dependencies {
+		if contains(taskIDs, dependency.TaskID) {
+			ids = append(ids, dependency.DependsOnID)
+		}
+	}
+	return ids, nil
+}
+
+func (m memoryDependencies) Dependents(taskIDs []string) ([]string, error) {
+	defer m.s.lock()()
+	ids := []string{}
+	for _, dependency := range m.s.data.dependencies {
+		if contains(taskIDs, dependency.DependsOnID) && !contains(taskIDs, dependency.TaskID) && !contains(ids, dependency.TaskID) {
+			ids = append(ids, dependency.TaskID)
+		}
+	}
+	return ids, nil
+}
+
+func (m memoryDependencies) Blocking(taskIDs []string, final FinalStatuses) (map[string][]string, error) {
+	defer m.s.lock()()
+	blocking := map[string][]string{}
+	for _, dependency := range m.s.data.dependencies {
+		task, ok := m.s.data.tasks[dependency.DependsOnID]
+		if !contains(taskIDs, dependency.TaskID) || !ok || task.DeletedAt.Valid || final.IsFinal(task.ProjectID, task.Status) {
+			continue
+		}
+		blocking[dependency.TaskID] = append(blocking[dependency.TaskID], dependency.DependsOnID)
+	}
+	return blocking, nil
+}
+
+func (m memoryDependencies) Add(dependency *database.TaskDependency) (bool, error) {
+	defer m.s.lock()()
+	if m.find(dependency.TaskID, dependency.DependsOnID) >= 0 {
+		return false, nil
+	}
+	m.s.data.dependencies = append(m.s.data.dependencies, *dependency)
+	return true, nil
+}
+
+func (m memoryDependencies) Remove(taskID, dependsOnID string) (bool, error) {
+	defer m.s.lock()()
+	i := m.find(taskID, dependsOnID)
+	if i < 0 {
+		return false, nil
+	}
+	m.s.data.dependencies = append(m.s.data.dependencies[:i:i], m.s.data.dependencies[i+1:]...)
+	return true, nil
+}
+
+func (m memoryDependencies) find(taskID, dependsOnID string) int {
+	for i, dependency := range m.s.data.dependencies {
+		if dependency.TaskID == taskID && dependency.DependsOnID == dependsOnID {
+			return i
+		}
+	}
+	return -1
+}
+
+type memoryTags struct {
+	s *MemoryStore
+}
+
+func (m memoryTags) Get(id string) (*database.Tag, error) {
+	defer m.s.lock()()
+	tag, ok := m.s.data.tags[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return &tag, nil
+}
+
+func (m memoryTags) List() ([]database.Tag, error) {
+	defer m.s.lock()()
+	tags := make([]database.Tag, 0, len(m.s.data.tags))
+	for _, tag := range m.s.data.tags {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i].Name < tags[j].Name
+	})
+	return tags, nil
+}
+
+func (m memoryTags) NameTaken(name, exceptID string) (bool, error) {
+	defer m.s.lock()()
+	tag, ok := m.byName(name)
+	return ok && tag.ID != exceptID, nil
+}
+
+func (m memoryTags) CountTasks(tagIDs ...string) (map[string]int64, error) {
+	defer m.s.lock()()
+	wanted := map[string]bool{}
+	for _, id := range tagIDs {
+		wanted[id] = true
+	}
+
+	counts := map[string]int64{}
+	for taskID, assigned := range m.s.data.taskTags {
+		task, ok := m.s.data.tasks[taskID]
+		if !ok || task.DeletedAt.Valid {
+			continue
+		}
+		for _, tagID := range assigned {
+			if len(wanted) == 0 || wanted[tagID] {
+				counts[tagID]++
+			}
+		}
+	}
+	return counts, nil
+}
+
+func (m memoryTags) TaskIDs(tagID string) ([]string, error) {
+	defer m.s.lock()()
+	taskIDs := []string{}
+	for taskID, assigned := range m.s.data.taskTags {
+		if contains(assigned, tagID) {
+			taskIDs = append(taskIDs, taskID)
+		}
+	}
+	sort.Strings(taskIDs)
+	return taskIDs, nil
+}
+
+func (m memoryTags) Create(tag *database.Tag) error {
+	defer m.s.lock()()
+	if other, ok := m.byName(tag.Name); ok && other.ID != tag.ID {
+		return ErrDuplicate
+	}
+	m.s.data.tags[tag.ID] = *tag
+	return nil
+}
+
+func (m memoryTags) Update(tag *database.Tag) error {
+	defer m.s.lock()()
+	if other, ok := m.byName(tag.Name); ok && other.ID != tag.ID {
+		return ErrDuplicate
+	}
+	m.s.data.tags[tag.ID] = *tag
+	return nil
+}
+
+func (m memoryTags) Delete(id string) error {
+	defer m.s.lock()()
+	if _, ok := m.s.data.tags[id]; !ok {
+		return ErrNotFound
+	}
+	m.unassign(id)
+	delete(m.s.data.tags, id)
+	return nil
+}
+
+func (m memoryTags) Merge(id, targetID string) error {
+	defer m.s.lock()()
+	_, found := m.s.data.tags[id]
+	_, targetFound := m.s.data.tags[targetID]
+	if !found || !targetFound {
+		return ErrNotFound
+	}
+
+	for taskID, assigned := range m.s.data.taskTags {
+		if contains(assigned, id) && !contains(assigned, targetID) {
+			m.s.data.taskTags[taskID] = append(assigned, targetID)
+		}
+	}
+	m.unassign(id)
+	delete(m.s.data.tags, id)
+	return nil
+}
+
+func (m memoryTags) DeleteUnused() ([]database.Tag, error) {
+	defer m.s.lock()()
+	used := map[string]bool{}
+	for taskID, assigned := range m.s.data.taskTags {
+		if _, ok := m.s.data.tasks[taskID]; !ok {
+			delete(m.s.data.taskTags, taskID)
+			continue
+		}
+		for _, tagID := range assigned {
+			used[tagID] = true
+		}
+	}
+
+	unused := []database.Tag{}
+	for id, tag := range m.s.data.tags {
+		if !used[id] {
+			unused = append(unused, tag)
+			delete(m.s.data.tags, id)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].Name < unused[j].Name
+	})
+	return unused, nil
+}
+
+func (m memoryTags) byName(name string) (database.Tag, bool) {
+	for _, tag := range m.s.data.tags {
+		if tag.Name == name {
+			return tag, true
+		}
+	}
+	return database.Tag{}, false
+}
+
+// unassign removes a tag from every task carrying it.
+func (m memoryTags) unassign(tagID string) {
+	for taskID, assigned := range m.s.data.taskTags {
+		kept := []string{}
+		for _, id := range assigned {
+			if id != tagID {
+				kept = append(kept, id)
+			}
+		}
+		if len(kept) == 0 {
+			delete(m.s.data.taskTags, taskID)
+		} else {
+			m.s.data.taskTags[taskID] = kept
+		}
+	}
+}
+
+type memoryRuns struct {
+	s *MemoryStore
+}
+
+func (m memoryRuns) Create(run *database.Run) error {
+	defer m.s.lock()()
+	m.s.data.runs = append(m.s.data.runs, *run)
+	return nil
+}
+
+func (m memoryRuns) Active(taskID string) (bool, error) {
+	defer m.s.lock()()
+	for _, run := range m.s.data.runs {
+		if run.TaskID == taskID && run.Status == model.RunStatusRunning {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type memoryTrash struct {
+	s *MemoryStore
+}
+
+func (m memoryTrash) Tasks() ([]database.Task, error) {
+	defer m.s.lock()()
+	tasks := []database.Task{}
+	for _, task := range m.s.data.tasks {
+		if task.DeletedAt.Valid {
+			tasks = append(tasks, task)
+		}
+	}
+	sort.Slice(tasks, byCreation(
+		func(i int) time.Time { return tasks[i].CreatedAt },
+		func(i int) string { return tasks[i].ID }))
+	return tasks, nil
+}
+
+func (m memoryTrash) Projects() ([]database.Project, error) {
+	defer m.s.lock()()
+	projects := []database.Project{}
+	for _, project := range m.s.data.projects {
+		if project.DeletedAt.Valid {
+			projects = append(projects, project)
+		}
+	}
+	sort.Slice(projects, byCreation(
+		func(i int) time.Time { return projects[i].CreatedAt },
+		func(i int) string { return projects[i].ID }))
+	return projects, nil
+}
+
+func (m memoryTrash) Task(id string) (*database.Task, error) {
+	defer m.s.lock()()
+	task, ok := m.s.data.tasks[id]
+	if !ok || !task.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &task, nil
+}
+
+func (m memoryTrash) Project(id string) (*database.Project, error) {
+	defer m.s.lock()()
+	project, ok := m.s.data.projects[id]
+	if !ok || !project.DeletedAt.Valid {
+		return nil, ErrNotFound
+	}
+	return &project, nil
+}
+
+func (m memoryTrash) RestoreTask(task *database.Task) error {
+	defer m.s.lock()()
+	if stored, ok := m.s.data.tasks[task.ID]; ok {
+		stored.DeletedAt = gorm.DeletedAt{}
+		stored.TrashBatch = ""
+		stored.Status = task.Status
+		stored.Position = task.Position
+		stored.Version++
+		stored.UpdatedAt = task.UpdatedAt
+		m.s.data.tasks[task.ID] = stored
+	}
+	return nil
+}
+
+func (m memoryTrash) RestoreProject(id string, restoredAt time.Time) error {
+	defer m.s.lock()()
+	if project, ok := m.s.data.projects[id]; ok {
+		project.DeletedAt = gorm.DeletedAt{}
+		project.TrashBatch = ""
+		project.Version++
+		project.UpdatedAt = restoredAt
+		m.s.data.projects[id] = project
+	}
+	return nil
+}
+
+func (m memoryTrash) Purge(taskIDs, projectIDs []string) error {
+	defer m.s.lock()()
+	dependencies := []database.TaskDependency{}
+	for _, dependency := range m.s.data.dependencies {
+		if !contains(taskIDs, dependency.TaskID) && !contains(taskIDs, dependency.DependsOnID) {
+			dependencies = append(dependencies, dependency)
+		}
+	}
+	m.s.data.dependencies = dependencies
+
+	runs := []database.Run{}
+	for _, run := range m.s.data.runs {
+		if !contains(taskIDs, run.TaskID) {
+			runs = append(runs, run)
+		}
+	}
+	m.s.data.runs = runs
+
+	for _, id := range taskIDs {
+		delete(m.s.data.taskTags, id)
+		delete(m.s.data.tasks, id)
+	}
+	for _, id := range projectIDs {
+		if project, ok := m.s.data.projects[id]; ok && project.DeletedAt.Valid {
+			delete(m.s.data.projects, id)
+		}
+	}
+	return nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
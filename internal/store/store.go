@@ -0,0 +1,302 @@
+// Package store keeps services apart from how tasks, projects, agents and
+// tags are persisted. GormStore keeps them in the database; MemoryStore
+// keeps them in memory for tests.
+package store
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/amoylab/solo-api/internal/database"
+	"github.com/amoylab/solo-api/internal/model"
+)
+
+var (
+	// ErrNotFound is gorm.ErrRecordNotFound, which handlers already map to
+	// 404, whichever store reports it.
+	ErrNotFound = gorm.ErrRecordNotFound
+
+	// ErrVersionMismatch reports an update of an entity that was changed
+	// since it was read.
+	ErrVersionMismatch = errors.New("the entity was changed since the given version")
+
+	// ErrDuplicate reports a name already in use. MemoryStore returns it
+	// where the database has a unique constraint.
+	ErrDuplicate = errors.New("duplicate key")
+)
+
+// Store gives access to the entity stores. Trashed tasks and projects are
+// left out of everything but the tag task lists and the trash.
+type Store interface {
+	Agents() AgentStore
+	Projects() ProjectStore
+	Tasks() TaskStore
+	Dependencies() DependencyStore
+	Tags() TagStore
+	Runs() RunStore
+	Trash() TrashStore
+
+	// RecordActivity appends an entry to the activity log.
+	RecordActivity(activity *database.Activity) error
+
+	// Transaction runs fn with a store whose changes are kept when fn
+	// returns nil and discarded otherwise. Transactions nest.
+	Transaction(fn func(tx Store) error) error
+}
+
+type AgentStore interface {
+	Get(id string) (*database.Agent, error)
+	// List returns all agents, oldest first.
+	List() ([]database.Agent, error)
+	Create(agent *database.Agent) error
+	// Update saves an agent read at agent.Version and moves it on to the
+	// next version, or fails with ErrVersionMismatch when it has been
+	// changed since.
+	Update(agent *database.Agent) error
+	// Delete deletes an agent and clears it from trashed tasks and
+	// projects. Live ones are unassigned by the caller first.
+	Delete(id string) error
+}
+
+type ProjectStore interface {
+	// Get returns a project with its agent.
+	Get(id string) (*database.Project, error)
+	// List returns the projects with their agents, oldest first.
+	List(includeArchived bool) ([]database.Project, error)
+	Create(project *database.Project) error
+	// Update saves a project like AgentStore.Update saves an agent.
+	Update(project *database.Project) error
+	// BumpVersions moves the versions of projects on like
+	// TaskStore.BumpVersions does for tasks.
+	BumpVersions(ids []string) error
+	// Trash moves a project to the trash as part of a batch, like
+	// TaskStore.Trash.
+	Trash(id string, deletedAt time.Time, batch string) error
+}
+
+type TaskStore interface {
+	// Get returns a task with its tags and agent.
+	Get(id string) (*database.Task, error)
+	// Find returns the tasks matching a filter with their tags and agents,
+	// ordered and cut as the page asks.
+	Find(filter TaskFilter, page TaskPage) ([]database.Task, error)
+	// Count counts the tasks matching a filter.
+	Count(filter TaskFilter) (int64, error)
+	// Subtasks returns the direct subtasks of the given tasks.
+	Subtasks(parentIDs []string) ([]database.Task, error)
+	Create(task *database.Task) error
+	// Update saves a task like AgentStore.Update saves an agent. Tags are
+	// set with SetTags.
+	Update(task *database.Task) error
+	// BumpVersions moves the versions of tasks on without changing them,
+	// for changes to what their responses show from other rows, such as
+	// their comments, subtasks or dependencies. Each task moves on once;
+	// trashed tasks are skipped.
+	BumpVersions(ids []string) error
+	// Trash moves tasks to the trash. Tasks and projects trashed together
+	// share a batch, which lets a restore bring them back as a whole.
+	Trash(ids []string, deletedAt time.Time, batch string) error
+	// SetBranch records the branch, base branch and worktree of a task,
+	// moving its version on. Trashed tasks are skipped.
+	SetBranch(id, branch, baseBranch, worktreePath string) error
+	// SetTags replaces the tags of a task, creating tags for new names.
+	// Empty and repeated names are skipped.
+	SetTags(taskID string, names []string) error
+	// TagNames returns the tag names of each of the tasks.
+	TagNames(taskIDs []string) (map[string][]string, error)
+	// LastPosition returns the highest position in a board column, or an
+	// empty string when no task of the column has one yet.
+	LastPosition(projectID, status string) (string, error)
+	// Column returns the positioned tasks of a board column by position.
+	Column(projectID, status string) ([]database.Task, error)
+	// Unpositioned returns the tasks without a position, by column and
+	// oldest first within a column.
+	Unpositioned() ([]database.Task, error)
+	// SetPosition places a task without counting it as a change.
+	SetPosition(id, position string) error
+	// StatusCounts counts the tasks of a project by status.
+	StatusCounts(projectID string) (map[string]int64, error)
+	// SubtaskCounts counts the subtasks of each of the tasks, all of them
+	// and the finished ones. Tasks without subtasks are left out.
+	SubtaskCounts(parentIDs []string, final FinalStatuses) (map[string]SubtaskCount, error)
+	// CommentCounts counts the comments on each of the tasks. Tasks
+	// without comments are left out.
+	CommentCounts(taskIDs []string) (map[string]int64, error)
+}
+
+// DependencyStore keeps the dependencies between tasks.
+type DependencyStore interface {
+	// Tasks returns the tasks a task depends on with their tags and
+	// agents, in the order the dependencies were added.
+	Tasks(taskID string) ([]database.Task, error)
+	// DependsOn returns the IDs of the tasks the given tasks depend on.
+	DependsOn(taskIDs []string) ([]string, error)
+	// Dependents returns the IDs of the tasks, trashed ones included,
+	// that depend on any of the given tasks without being one of them.
+	Dependents(taskIDs []string) ([]string, error)
+	// Blocking maps each of the given tasks to the unfinished tasks it
+	// depends on, in the order the dependencies were added. Trashed tasks
+	// block nothing; tasks that are not blocked are left out.
+	Blocking(taskIDs []string, final FinalStatuses) (map[string][]string, error)
+	// Add adds a dependency. It reports false when it existed already.
+	Add(dependency *database.TaskDependency) (bool, error)
+	// Remove removes a dependency. It reports false when there was none.
+	Remove(taskID, dependsOnID string) (bool, error)
+}
+
+type TagStore interface {
+	Get(id string) (*database.Tag, error)
+	// List returns all tags by name.
+	List() ([]database.Tag, error)
+	// NameTaken tells whether a tag other than exceptID has the name.
+	NameTaken(name, exceptID string) (bool, error)
+	// CountTasks counts the tasks carrying each of the tags, or every tag
+	// when no IDs are given. Trashed tasks are not counted; tags without
+	// tasks are left out.
+	CountTasks(tagIDs ...string) (map[string]int64, error)
+	// TaskIDs returns the tasks carrying a tag, trashed ones included.
+	TaskIDs(tagID string) ([]string, error)
+	Create(tag *database.Tag) error
+	Update(tag *database.Tag) error
+	// Delete removes a tag from its tasks and deletes it.
+	Delete(id string) error
+	// Merge moves the tasks carrying a tag over to the target tag and
+	// deletes the merged tag. It fails with ErrNotFound unless both exist.
+	Merge(id, targetID string) error
+	// DeleteUnused deletes the tag assignments of purged tasks, then the
+	// tags no task carries, trashed ones included, and returns the deleted
+	// tags.
+	DeleteUnused() ([]database.Tag, error)
+}
+
+// RunStore keeps the agent runs of tasks.
+type RunStore interface {
+	Create(run *database.Run) error
+	// Active reports whether a task has a run in progress.
+	Active(taskID string) (bool, error)
+}
+
+// TrashStore keeps the trashed tasks and projects.
+type TrashStore interface {
+	// Tasks returns the trashed tasks, oldest first.
+	Tasks() ([]database.Task, error)
+	// Projects returns the trashed projects, oldest first.
+	Projects() ([]database.Project, error)
+	// Task returns a trashed task. Live tasks are not found.
+	Task(id string) (*database.Task, error)
+	// Project returns a trashed project. Live projects are not found.
+	Project(id string) (*database.Project, error)
+	// RestoreTask takes a task out of the trash with the status, position
+	// and update time it carries, moving its version on.
+	RestoreTask(task *database.Task) error
+	// RestoreProject takes a project out of the trash, moving its version
+	// on.
+	RestoreProject(id string, restoredAt time.Time) error
+	// Purge deletes trashed tasks and projects for good, together with the
+	// dependencies, tags, comments and runs of the tasks.
+	Purge(taskIDs, projectIDs []string) error
+}
+
+// FinalStatuses names the statuses that finish tasks: Custom for the
+// projects declaring a workflow of their own, Default for all others.
+type FinalStatuses struct {
+	Default []string
+	Custom  map[string][]string
+}
+
+// IsFinal reports whether a status finishes tasks of a project.
+func (f FinalStatuses) IsFinal(projectID, status string) bool {
+	final, ok := f.Custom[projectID]
+	if !ok {
+		final = f.Default
+	}
+	for _, s := range final {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
+
+// TaskFilter selects tasks. Zero fields match every task; trashed tasks
+// never match.
+type TaskFilter struct {
+	ProjectID       string
+	ParentID        string
+	AgentID         string
+	Assignee        string
+	Statuses        []string // Any of them
+	Tags            []string // Every one of them, by name
+	Text            string   // Contained in the title or description, ignoring case
+	IncludeArchived bool
+	Final           FinalStatuses // Decides Finished and Overdue
+	Finished        *bool         // In a final status or not
+	Overdue         *bool         // Unfinished and due before now, or not
+	CreatedAfter    *time.Time    // Inclusive
+	CreatedBefore   *time.Time
+	CompletedBefore *time.Time // Tasks never marked complete count from their last update
+}
+
+// TaskPage orders tasks and picks a page of them.
+type TaskPage struct {
+	Sort  string // A key of TaskSortFields, empty for creation order
+	Desc  bool
+	After *TaskCursor // The last task of the previous page, nil for the first
+	Limit int         // Zero for no limit
+}
+
+// TaskCursor marks a task in a sort order by its sort value and ID.
+type TaskCursor struct {
+	Value interface{}
+	ID    string
+}
+
+// TaskSortField is a value tasks can be sorted by. Ties are broken by ID.
+type TaskSortField struct {
+	Time  bool // Value is a time.Time
+	Value func(task *database.Task) interface{}
+}
+
+// TaskSortFields are the values tasks can be sorted by: times, strings or
+// ranks. Priorities rank from least to most pressing, tasks without a due
+// date sort after every dated task.
+var TaskSortFields = map[string]TaskSortField{
+	"created_at": {Time: true, Value: func(t *database.Task) interface{} { return t.CreatedAt }},
+	"updated_at": {Time: true, Value: func(t *database.Task) interface{} { return t.UpdatedAt }},
+	"title":      {Value: func(t *database.Task) interface{} { return t.Title }},
+	"status":     {Value: func(t *database.Task) interface{} { return t.Status }},
+	"assignee":   {Value: func(t *database.Task) interface{} { return t.Assignee }},
+	"priority":   {Value: func(t *database.Task) interface{} { return priorityRank(t.Priority) }},
+	"due_date":   {Time: true, Value: dueDate},
+	"position":   {Value: func(t *database.Task) interface{} { return t.Position }},
+}
+
+// noDueDate stands in for a missing due date when sorting.
+var noDueDate = time.Date(9999, 12, 31, 0, 0, 0, 0, time.UTC)
+
+func dueDate(task *database.Task) interface{} {
+	if task.DueDate == nil {
+		return noDueDate.Local()
+	}
+	return task.DueDate.Local()
+}
+
+func priorityRank(priority string) int {
+	switch priority {
+	case model.PriorityUrgent:
+		return 3
+	case model.PriorityHigh:
+		return 2
+	case model.PriorityMedium:
+		return 1
+	}
+	return 0
+}
+
+// SubtaskCount sums up the subtasks of a task.
+type SubtaskCount struct {
+	Total     int64
+	Completed int64
+}
@@ -15,7 +15,7 @@ import type { Project } from '@/types/project';
 
 function App() {
   const { projects, loading: projectsLoading, createProject, updateProject, deleteProject } = useProjects();
-  const { tasks, loading: tasksLoading, handleDragEnd, addTask, updateTask, deleteTask } = useTasks();
+  const { tasks, loading: tasksLoading, handleDragEnd, addTask, updateTask, deleteTask, removeProjectTasks } = useTasks();
   const [searchQuery, setSearchQuery] = useState('');
   const [showTaskModal, setShowTaskModal] = useState(false);
   const [editingTask, setEditingTask] = useState<Task | null>(null);
@@ -144,15 +144,10 @@ function App() {
   const handleConfirmDeleteProject = async () => {
     if (projectToDelete) {
       haptics.medium();
-      
-      // First delete all tasks belonging to this project
-      const projectTasks = tasks.filter(task => task.projectId === projectToDelete.id);
-      for (const task of projectTasks) {
-        await deleteTask(task.id);
-      }
-      
-      // Then delete the project
-      await deleteProject(projectToDelete.id);
+
+      // The server moves the project's tasks to the trash along with it
+      await deleteProject(projectToDelete.id, 'cascade');
+      removeProjectTasks(projectToDelete.id);
       setProjectToDelete(null);
     }
   };
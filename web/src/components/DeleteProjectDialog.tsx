@@ -45,11 +45,11 @@ export function DeleteProjectDialog({
             Are you sure you want to delete <strong>"{project.name}"</strong>?
             {taskCount > 0 ? (
               <>
-                This action cannot be undone and will permanently remove the project and <strong>{taskCount} task{taskCount !== 1 ? 's' : ''}</strong>.
+                The project and <strong>{taskCount} task{taskCount !== 1 ? 's' : ''}</strong> will be moved to the trash, where they can be restored until they are purged.
               </>
             ) : (
               <>
-                This action cannot be undone and will permanently remove the project.
+                The project will be moved to the trash, where it can be restored until it is purged.
               </>
             )}
           </AlertDialogDescription>
@@ -41,7 +41,7 @@ export function DeleteTaskDialog({
           </AlertDialogTitle>
           <AlertDialogDescription>
             Are you sure you want to delete <strong>"{task.title}"</strong>?
-            This action cannot be undone and will permanently remove the task from your kanban board.
+            The task and its subtasks will be moved to the trash, where they can be restored until they are purged.
           </AlertDialogDescription>
         </AlertDialogHeader>
         <AlertDialogFooter>
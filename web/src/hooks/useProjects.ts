@@ -1,6 +1,6 @@
 import { useState, useCallback, useEffect } from 'react';
 import type { Project, CreateProject, UpdateProject } from '@/types/project';
-import { projectApi } from '@/lib/api';
+import { projectApi, type ProjectDeleteMode } from '@/lib/api';
 
 export function useProjects() {
   const [projects, setProjects] = useState<Project[]>([]);
@@ -64,13 +64,13 @@ export function useProjects() {
     }
   }, []);
 
-  const deleteProject = useCallback(async (id: string) => {
+  const deleteProject = useCallback(async (id: string, onDelete?: ProjectDeleteMode) => {
     // Optimistically remove from UI
     const projectToDelete = projects.find(p => p.id === id);
     setProjects((prevProjects) => prevProjects.filter((project) => project.id !== id));
 
     try {
-      await projectApi.deleteProject(id);
+      await projectApi.deleteProject(id, onDelete);
     } catch (err) {
       // Revert on error
       if (projectToDelete) {
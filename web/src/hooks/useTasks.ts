@@ -49,81 +49,56 @@ export function useTasks() {
 
     const activeContainer = findContainer(activeId);
     const overContainer = findContainer(overId);
+    const activeTask = tasks.find(task => task.id === activeId);
+    if (!activeTask) return;
 
-    // If we're dropping on a container (status column), move the task there
-    if (['todo', 'inprogress', 'inreview', 'done', 'cancelled'].includes(overId)) {
-      const newStatus = overId as TaskStatus;
-      
-      // Optimistically update UI
-      setTasks((prevTasks) =>
-        prevTasks.map((task) =>
-          task.id === activeId
-            ? { ...task, status: newStatus, updatedAt: new Date() }
-            : task
-        )
-      );
-
-      // Update via API
-      try {
-        await taskApi.updateTask(activeId, { status: newStatus });
-      } catch (err) {
-        // Revert on error
-        setTasks((prevTasks) =>
-          prevTasks.map((task) =>
-            task.id === activeId
-              ? { ...task, status: activeContainer, updatedAt: new Date() }
-              : task
-          )
-        );
-        setError(err instanceof Error ? err.message : 'Failed to update task');
-      }
-      return;
-    }
-
-    // If we're dropping on a task, we need to handle reordering
+    // Work out the new order of the target column
+    const containerTasks = tasks.filter(task => task.status === overContainer);
+    let orderedTasks: Task[];
     if (activeContainer === overContainer) {
-      // Same container - just reorder (local only for now)
-      const containerTasks = tasks.filter(task => task.status === activeContainer);
       const activeIndex = containerTasks.findIndex(task => task.id === activeId);
       const overIndex = containerTasks.findIndex(task => task.id === overId);
-      
-      if (activeIndex !== overIndex) {
-        const reorderedTasks = arrayMove(containerTasks, activeIndex, overIndex);
-        
-        setTasks((prevTasks) => {
-          const otherTasks = prevTasks.filter(task => task.status !== activeContainer);
-          return [...otherTasks, ...reorderedTasks];
-        });
-      }
+      // Dropping on the column itself moves the task to its end
+      const targetIndex = overIndex === -1 ? containerTasks.length - 1 : overIndex;
+      if (activeIndex === targetIndex) return;
+      orderedTasks = arrayMove(containerTasks, activeIndex, targetIndex);
     } else {
-      // Different containers - move task to new container
-      const newStatus = overContainer;
-      
-      // Optimistically update UI
-      setTasks((prevTasks) =>
-        prevTasks.map((task) =>
-          task.id === activeId
-            ? { ...task, status: newStatus, updatedAt: new Date() }
-            : task
-        )
+      const movedTask = { ...activeTask, status: overContainer, updatedAt: new Date() };
+      const overIndex = containerTasks.findIndex(task => task.id === overId);
+      orderedTasks = overIndex === -1
+        ? [...containerTasks, movedTask]
+        : [...containerTasks.slice(0, overIndex), movedTask, ...containerTasks.slice(overIndex)];
+    }
+
+    // The server only needs the neighbors the task lands between
+    const index = orderedTasks.findIndex(task => task.id === activeId);
+    const afterId = orderedTasks[index - 1]?.id;
+    const beforeId = orderedTasks[index + 1]?.id;
+
+    // Optimistically update UI
+    setTasks((prevTasks) => {
+      const otherTasks = prevTasks.filter(
+        task => task.status !== overContainer && task.id !== activeId
       );
+      return [...otherTasks, ...orderedTasks];
+    });
 
-      // Update via API
-      try {
-        await taskApi.updateTask(activeId, { status: newStatus });
-      } catch (err) {
-        // Revert on error
-        setTasks((prevTasks) =>
-          prevTasks.map((task) =>
-            task.id === activeId
-              ? { ...task, status: activeContainer, updatedAt: new Date() }
-              : task
-          )
-        );
-        setError(err instanceof Error ? err.message : 'Failed to update task');
-      }
+    // Persist the move via API
+    try {
+      const movedTask = await taskApi.moveTask(activeId, {
+        status: activeContainer === overContainer ? undefined : overContainer,
+        afterId,
+        beforeId,
+      });
+      setTasks((prevTasks) =>
+        prevTasks.map((task) => (task.id === activeId ? movedTask : task))
+      );
+    } catch (err) {
+      // Revert to the server's order on error
+      await loadTasks();
+      setError(err instanceof Error ? err.message : 'Failed to move task');
     }
-  }, [tasks]);
+  }, [tasks, loadTasks]);
 
   const addTask = useCallback(async (newTask: Omit<Task, 'id' | 'createdAt' | 'updatedAt'>) => {
     try {
@@ -131,6 +106,8 @@ export function useTasks() {
         title: newTask.title,
         description: newTask.description,
         status: newTask.status,
+        priority: newTask.priority,
+        dueDate: newTask.dueDate,
         assignee: newTask.assignee,
         agentId: newTask.agentId,
         tags: newTask.tags,
@@ -157,6 +134,8 @@ export function useTasks() {
         title: updates.title,
         description: updates.description,
         status: updates.status,
+        priority: updates.priority,
+        dueDate: updates.dueDate,
         assignee: updates.assignee,
         agentId: updates.agentId,
         tags: updates.tags,
@@ -191,6 +170,11 @@ export function useTasks() {
     }
   }, [tasks]);
 
+  // Drops the tasks of a deleted project, which the server trashes with it
+  const removeProjectTasks = useCallback((projectId: string) => {
+    setTasks((prevTasks) => prevTasks.filter((task) => task.projectId !== projectId));
+  }, []);
+
   return {
     tasks,
     loading,
@@ -199,6 +183,7 @@ export function useTasks() {
     addTask,
     updateTask,
     deleteTask,
+    removeProjectTasks,
     loadTasks,
   };
 }
\ No newline at end of file
@@ -1,12 +1,16 @@
-import type { Task, TaskStatus } from '@/types/task';
+import type { Task, TaskStatus, TaskPriority } from '@/types/task';
 import type { Project, Agent } from '@/types/project';
 
 const API_BASE_URL = 'http://localhost:8080/api';
 
+export type ProjectDeleteMode = 'restrict' | 'cascade';
+
 export interface CreateTaskRequest {
   title: string;
   description?: string;
   status?: TaskStatus;
+  priority?: TaskPriority;
+  dueDate?: Date;
   assignee?: string;
   agentId?: string;
   tags?: string[];
@@ -17,6 +21,8 @@ export interface UpdateTaskRequest {
   title?: string;
   description?: string;
   status?: TaskStatus;
+  priority?: TaskPriority;
+  dueDate?: Date;
   assignee?: string;
   agentId?: string;
   tags?: string[];
@@ -28,6 +34,8 @@ export interface ApiTask {
   title: string;
   description: string;
   status: TaskStatus;
+  priority: TaskPriority;
+  due_date?: string;
   assignee: string;
   agent_id?: string;
   agent?: Agent;
@@ -35,6 +43,15 @@ export interface ApiTask {
   created_at: string;
   updated_at: string;
   project_id?: string;
+  position?: string;
+}
+
+// Places a task after afterId and/or before beforeId in the target column;
+// without neighbors it goes to the end of the column
+export interface MoveTaskRequest {
+  status?: TaskStatus;
+  afterId?: string;
+  beforeId?: string;
 }
 
 export interface TaskListResponse {
@@ -79,7 +96,7 @@ function transformApiTask(apiTask: ApiTask): Task {
     title: apiTask.title,
     description: apiTask.description || undefined,
     status: apiTask.status,
-    priority: 'medium', // Default priority since API doesn't have this field
+    priority: apiTask.priority || 'medium',
     assignee: apiTask.assignee || undefined,
     agentId: apiTask.agent_id,
     agent: apiTask.agent ? {
@@ -93,7 +110,9 @@ function transformApiTask(apiTask: ApiTask): Task {
     tags: apiTask.tags || [],
     createdAt: new Date(apiTask.created_at),
     updatedAt: new Date(apiTask.updated_at),
+    dueDate: apiTask.due_date ? new Date(apiTask.due_date) : undefined,
     projectId: apiTask.project_id,
+    position: apiTask.position,
   };
 }
 
@@ -155,6 +174,8 @@ export const taskApi = {
       title: task.title,
       description: task.description,
       status: task.status,
+      priority: task.priority,
+      due_date: task.dueDate?.toISOString(),
       assignee: task.assignee,
       agent_id: task.agentId, // Convert camelCase to snake_case
       tags: task.tags,
@@ -181,6 +202,8 @@ export const taskApi = {
       title: updates.title,
       description: updates.description,
       status: updates.status,
+      priority: updates.priority,
+      due_date: updates.dueDate?.toISOString(),
       assignee: updates.assignee,
       agent_id: updates.agentId, // Convert camelCase to snake_case
       tags: updates.tags,
@@ -201,6 +224,25 @@ export const taskApi = {
     return transformApiTask(data);
   },
 
+  async moveTask(id: string, move: MoveTaskRequest): Promise<Task> {
+    const response = await fetch(`${API_BASE_URL}/tasks/${id}/move`, {
+      method: 'POST',
+      headers: {
+        'Content-Type': 'application/json',
+      },
+      body: JSON.stringify({
+        status: move.status,
+        after_id: move.afterId,
+        before_id: move.beforeId,
+      }),
+    });
+    if (!response.ok) {
+      throw new Error('Failed to move task');
+    }
+    const data: ApiTask = await response.json();
+    return transformApiTask(data);
+  },
+
   async deleteTask(id: string): Promise<void> {
     const response = await fetch(`${API_BASE_URL}/tasks/${id}`, {
       method: 'DELETE',
@@ -291,12 +333,15 @@ export const projectApi = {
     return transformApiProject(data);
   },
 
-  async deleteProject(id: string): Promise<void> {
-    const response = await fetch(`${API_BASE_URL}/projects/${id}`, {
+  // onDelete picks what happens to the project's tasks; the server refuses
+  // to delete a project with tasks unless told to cascade or move them.
+  async deleteProject(id: string, onDelete: ProjectDeleteMode = 'restrict'): Promise<void> {
+    const response = await fetch(`${API_BASE_URL}/projects/${id}?on_delete=${onDelete}`, {
       method: 'DELETE',
     });
     if (!response.ok) {
-      throw new Error('Failed to delete project');
+      const body: { message?: string } | null = await response.json().catch(() => null);
+      throw new Error(body?.message ?? 'Failed to delete project');
     }
   },
 };
\ No newline at end of file
@@ -16,4 +16,5 @@ export interface Task {
   dueDate?: Date;
   tags?: string[];
   projectId?: string;
+  position?: string;
 }
\ No newline at end of file